@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"micgain-manager/internal/domain"
+)
+
+// assignConfig applies key=value pairs to config. Keys are the JSON keys
+// of the config edit document, dotted for nested ones (noise.minVolume),
+// and values use the same notation as there and as the config set flags.
+// The result goes through the same conversion as config edit.
+func assignConfig(config domain.Config, pairs []string) (domain.Config, error) {
+	data, err := marshalEditable(config)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return domain.Config{}, err
+	}
+
+	for _, pair := range pairs {
+		key, raw, ok := strings.Cut(pair, "=")
+		if !ok {
+			return domain.Config{}, fmt.Errorf("%q は key=value の形式で指定してください", pair)
+		}
+		path := strings.Split(key, ".")
+		field, ok := editableField(reflect.TypeOf(editableConfig{}), path)
+		if !ok {
+			return domain.Config{}, fmt.Errorf("不明なキー %q (指定できるキー: %s)", key, strings.Join(editableKeys(reflect.TypeOf(editableConfig{}), ""), ", "))
+		}
+		value, err := parseEditableValue(field, raw)
+		if err != nil {
+			return domain.Config{}, fmt.Errorf("%s: %w", key, err)
+		}
+
+		parent := doc
+		for _, name := range path[:len(path)-1] {
+			parent = parent[name].(map[string]any)
+		}
+		parent[path[len(path)-1]] = value
+	}
+
+	data, err = json.Marshal(doc)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	return unmarshalEditable(config, data)
+}
+
+// editableField finds the leaf field named by path through JSON tags.
+func editableField(t reflect.Type, path []string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if jsonName(f) != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return f.Type, f.Type.Kind() != reflect.Struct
+		}
+		if f.Type.Kind() != reflect.Struct {
+			return nil, false
+		}
+		return editableField(f.Type, path[1:])
+	}
+	return nil, false
+}
+
+// editableKeys lists the dotted keys of every leaf field.
+func editableKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			keys = append(keys, editableKeys(f.Type, prefix+jsonName(f)+".")...)
+			continue
+		}
+		keys = append(keys, prefix+jsonName(f))
+	}
+	return keys
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
+
+// parseEditableValue converts raw into the JSON value for a field of type t.
+// Lists take comma separated values; an empty value clears a list or an
+// optional number.
+func parseEditableValue(t reflect.Type, raw string) (any, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("true/false を指定してください: %q", raw)
+		}
+		return v, nil
+	case reflect.Int:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("整数を指定してください: %q", raw)
+		}
+		return v, nil
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("数値を指定してください: %q", raw)
+		}
+		return v, nil
+	case reflect.Pointer:
+		if raw == "" || raw == "null" {
+			return nil, nil
+		}
+		return parseEditableValue(t.Elem(), raw)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Int {
+			return parseVolumeList(raw)
+		}
+	}
+	return nil, fmt.Errorf("unsupported field type %s", t)
+}
@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/adapter/primary/web"
+	"micgain-manager/internal/adapter/secondary/repository"
+	"micgain-manager/internal/logging"
+)
+
+// bundleLogTailLines is how much of each log file a support bundle keeps.
+const bundleLogTailLines = 1000
+
+// secretKey matches the names of config fields and headers whose values
+// are secrets. The pre/post-apply commands are included because they often
+// carry tokens for the services they call.
+const secretKey = `[\w-]*(?:token|password|secret|apikey|api_key|authorization|preapplycmd|postapplycmd)[\w-]*`
+
+// secretPatterns match values that must not leave the machine.
+var secretPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// Credentials embedded in URLs
+	{regexp.MustCompile(`(\w+://)[^/\s:@"]+:[^/\s@"]+@`), "${1}***:***@"},
+	// JSON fields named like secrets
+	{regexp.MustCompile(`(?i)("` + secretKey + `"\s*:\s*)"(?:[^"\\]|\\.)*"`), `${1}"***"`},
+	// YAML "key: value" lines named like secrets
+	{regexp.MustCompile(`(?im)^([ \t]*(?:- )?` + secretKey + `:[ \t]+)\S.*$`), `${1}"***"`},
+	// TOML "key = value" lines named like secrets
+	{regexp.MustCompile(`(?im)^([ \t]*["']?` + secretKey + `["']?[ \t]*=[ \t]*)\S.*$`), `${1}"***"`},
+	// Query parameters named like secrets
+	{regexp.MustCompile(`(?i)([?&](?:token|access_token|key|api_key|secret|password)=)[^&\s"]+`), "${1}***"},
+}
+
+func newSupportBundleCmd() *cobra.Command {
+	var (
+		output    string
+		serverURL string
+		logFiles  []string
+	)
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "不具合報告用に設定・状態・履歴・ログ・環境情報をzipにまとめる (秘密情報は伏せ字)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output == "" {
+				output = "micgain-support-" + time.Now().Format("20060102-150405") + ".zip"
+			}
+
+			// Keep the loaders' own log output, at debug level, as part of
+			// the bundle instead of the terminal
+			var bundleLog bytes.Buffer
+			log.SetOutput(&bundleLog)
+			defer log.SetOutput(os.Stderr)
+			defer logging.SetVerbosity(logging.Verbosity())
+			logging.SetVerbosity(2)
+
+			b := &supportBundle{}
+			b.addJSON("info.json", bundleInfo())
+			b.addFile("config/user"+filepath.Ext(cfgPath), cfgPath)
+			if systemCfgPath != "" {
+				b.addFile("config/system"+filepath.Ext(systemCfgPath), systemCfgPath)
+			}
+			collectConfigLayers(b)
+			collectLocalState(b)
+			b.addJSON("capabilities.json", controllerCapabilities())
+			if effectLogPath != "" {
+				b.addTail("logs/effects.jsonl", effectLogPath)
+			}
+			for _, path := range logFiles {
+				b.addTail("logs/"+filepath.Base(path), path)
+			}
+			if serverURL != "" {
+				collectServerDebug(cmd.Context(), b, serverURL)
+			}
+			b.add("logs/support-bundle.log", bundleLog.Bytes())
+
+			if err := b.write(output); err != nil {
+				return err
+			}
+			fmt.Printf("サポートバンドルを作成しました: %s\n", output)
+			for _, name := range b.names() {
+				fmt.Printf("  %s\n", name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "出力するzipファイル (既定: micgain-support-<日時>.zip)")
+	cmd.Flags().StringVar(&serverURL, "server", "http://127.0.0.1:7070", "起動中のサーバーからGET /api/debugを取得するURL (空文字で取得しない)")
+	cmd.Flags().StringArrayVar(&logFiles, "log-file", nil, "同梱するログファイル (launchdのStandardErrorPathなど、末尾のみ、複数指定可)")
+	return cmd
+}
+
+// supportBundle accumulates redacted files in memory before zipping them.
+type supportBundle struct {
+	files []bundleFile
+}
+
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+func (b *supportBundle) add(name string, data []byte) {
+	b.files = append(b.files, bundleFile{name: name, data: redactSecrets(data)})
+}
+
+func (b *supportBundle) addJSON(name string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		data = []byte(err.Error())
+	}
+	b.add(name, append(data, '\n'))
+}
+
+// addFile includes a file, or a note on why it could not be read.
+func (b *supportBundle) addFile(name, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.add(name+".missing.txt", []byte(fmt.Sprintf("%s: %v\n", path, err)))
+		return
+	}
+	b.add(name, data)
+}
+
+// addTail includes the last lines of a possibly large log file.
+func (b *supportBundle) addTail(name, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		b.add(name+".missing.txt", []byte(fmt.Sprintf("%s: %v\n", path, err)))
+		return
+	}
+	defer f.Close()
+
+	lines := make([]string, 0, bundleLogTailLines)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(lines) == bundleLogTailLines {
+			lines = lines[1:]
+		}
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		lines = append(lines, fmt.Sprintf("(read error: %v)", err))
+	}
+	b.add(name, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+func (b *supportBundle) names() []string {
+	names := make([]string, 0, len(b.files))
+	for _, f := range b.files {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+func (b *supportBundle) write(path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(out)
+	for _, f := range b.files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			out.Close()
+			return err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			out.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// redactSecrets masks credentials and replaces the home directory, which
+// usually contains the user name, with "~".
+func redactSecrets(data []byte) []byte {
+	for _, p := range secretPatterns {
+		data = p.re.ReplaceAll(data, []byte(p.repl))
+	}
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		data = bytes.ReplaceAll(data, []byte(home), []byte("~"))
+	}
+	return data
+}
+
+func bundleInfo() map[string]any {
+	var commands []string
+	for _, newCmd := range optionalCommands {
+		commands = append(commands, newCmd().Name())
+	}
+	return map[string]any{
+		"version":          Version,
+		"goVersion":        runtime.Version(),
+		"platform":         runtime.GOOS + "/" + runtime.GOARCH,
+		"createdAt":        time.Now().Format(time.RFC3339),
+		"configPath":       cfgPath,
+		"systemConfigPath": systemCfgPath,
+		"effectLogPath":    effectLogPath,
+		"historyPath":      repository.HistoryPath(cfgPath),
+		"lockConfig":       lockConfig,
+		"strictVolume":     strictVolume,
+		"dryRun":           dryRun,
+		"watchConfig":      watchConfig,
+		"volumeTolerance":  volumeTol,
+		"controller":       controllerType,
+		"controllerCmd":    controllerCmd,
+		"noiseSensorCmd":   noiseSensorCmd,
+		"optionalCommands": commands,
+	}
+}
+
+// collectConfigLayers records which layer every setting came from.
+func collectConfigLayers(b *supportBundle) {
+	repo, err := newRepository()
+	if err == nil {
+		_, _, err = repo.Load()
+	}
+	if err != nil {
+		b.add("config/layers.error.txt", []byte(err.Error()+"\n"))
+		return
+	}
+	layered := repo.(*repository.FileRepository)
+	b.addJSON("config/layers.json", map[string]any{
+		"layers":  layered.Layers(),
+		"origins": layered.Origins(),
+	})
+}
+
+// collectLocalState loads the use case the way the daemon does and dumps
+// it without starting the scheduler.
+func collectLocalState(b *supportBundle) {
+	uc, err := newUseCase()
+	if err != nil {
+		b.add("state.error.txt", []byte(err.Error()+"\n"))
+		return
+	}
+	b.addJSON("state.json", web.DebugDump(uc, Version, time.Time{}))
+}
+
+// controllerCapabilities probes what the volume controller can do here.
+func controllerCapabilities() map[string]any {
+	caps := map[string]any{"controller": controllerType}
+	if path, err := exec.LookPath("osascript"); err != nil {
+		caps["osascript"] = err.Error()
+	} else {
+		caps["osascript"] = path
+	}
+	controller, err := newController()
+	if err != nil {
+		caps["controllerError"] = err.Error()
+		return caps
+	}
+	if current, err := controller.GetVolume(); err != nil {
+		caps["readBack"] = false
+		caps["readBackError"] = err.Error()
+	} else {
+		caps["readBack"] = true
+		caps["currentVolume"] = current
+	}
+	return caps
+}
+
+// collectServerDebug includes a running server's own view, if one answers.
+func collectServerDebug(ctx context.Context, b *supportBundle, serverURL string) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	url := strings.TrimSuffix(serverURL, "/") + "/api/debug"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		b.add("server-debug.error.txt", []byte(err.Error()+"\n"))
+		return
+	}
+	authorizeRequest(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		b.add("server-debug.error.txt", []byte(fmt.Sprintf("no server answered at %s: %v\n", url, err)))
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		b.add("server-debug.error.txt", []byte(fmt.Sprintf("GET %s: %s %v\n%s", url, resp.Status, err, body)))
+		return
+	}
+	b.add("server-debug.json", body)
+}
@@ -2,13 +2,19 @@ package cli
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,18 +23,51 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"micgain-manager/internal/adapter/primary/metrics"
 	"micgain-manager/internal/adapter/primary/web"
+	"micgain-manager/internal/adapter/secondary/command"
+	"micgain-manager/internal/adapter/secondary/mdns"
+	"micgain-manager/internal/adapter/secondary/noise"
+	"micgain-manager/internal/adapter/secondary/notify"
+	"micgain-manager/internal/adapter/secondary/power"
 	"micgain-manager/internal/adapter/secondary/repository"
 	"micgain-manager/internal/adapter/secondary/volume"
+	"micgain-manager/internal/domain"
 	"micgain-manager/internal/logging"
 	"micgain-manager/internal/usecase"
 )
 
 var (
-	cfgPath   string
-	verbosity int
+	cfgPath       string
+	systemCfgPath string
+	noFallback    bool
+	verbosity     int
+	effectLogPath string
+	lockConfig    bool
+	strictVolume  bool
+	dryRun        bool
+	watchConfig   bool
+	notifyStatus  bool
+	logFormat     string
+	volumeTol     int
+
+	controllerType    string
+	controllerCmd     string
+	controllerTimeout time.Duration
+	noiseSensorCmd    string
+	notifyDebounce    time.Duration
+
+	// embedOptions are passed to every use case the commands create.
+	embedOptions []usecase.Option
 )
 
+// AddUseCaseOptions lets a program embedding the CLI inject options such as
+// usecase.WithTickHook into the scheduler. Call it before executing the
+// root command.
+func AddUseCaseOptions(opts ...usecase.Option) {
+	embedOptions = append(embedOptions, opts...)
+}
+
 // NewRootCmd creates the root CLI command.
 // This is the primary adapter that translates CLI inputs to use case calls.
 func NewRootCmd() *cobra.Command {
@@ -40,34 +79,303 @@ func NewRootCmd() *cobra.Command {
 
 	defaultCfg := repository.DefaultPath()
 	cmd.PersistentFlags().StringVar(&cfgPath, "config", defaultCfg, "設定ファイルのパス")
+	cmd.PersistentFlags().BoolVar(&noFallback, "no-fallback", false, "設定ファイルが不正な場合に最後に正常だった設定 (config.json.good) で代替せずエラーにする")
+	cmd.PersistentFlags().StringVar(&systemCfgPath, "system-config", repository.DefaultSystemPath(), "ユーザー設定の下に重ねるシステム設定ファイルのパス (空文字で無効)")
+	cmd.PersistentFlags().StringVar(&effectLogPath, "effect-log", "", "実行した副作用(音量変更・設定保存など)をJSON Linesで記録するファイル")
+	cmd.PersistentFlags().BoolVar(&lockConfig, "lock-config", false, "設定の変更(config set、Webからの更新、音量指定の適用、lock/unlock)をすべて禁止")
+	cmd.PersistentFlags().BoolVar(&watchConfig, "watch-config", false, "daemon/serveの実行中に設定ファイルが外部で編集されたら再読み込みして反映する")
+	cmd.PersistentFlags().BoolVar(&notifyStatus, "notify", false, "適用の失敗が続いてエラーになったときと、その後回復したときにmacOSの通知を表示する")
+	cmd.PersistentFlags().DurationVar(&notifyDebounce, "notify-debounce", usecase.DefaultNotifyDebounce, "--notify の通知を出す最短の間隔 (失敗と回復を繰り返しても通知し続けないように)")
+	cmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "音量を実際には変えず、適用しようとした音量・トリガー・次回実行をログに出すだけにする (設定には保存しない)")
+	cmd.PersistentFlags().BoolVar(&strictVolume, "strict-volume", false, "適用後に音量を読み戻し、要求値と異なればエラーにする")
+	cmd.PersistentFlags().IntVar(&volumeTol, "volume-tolerance", 0, "--strict-volume / apply --verify で許容する要求値との差")
+	cmd.PersistentFlags().StringVar(&controllerType, "controller", "applescript", "音量の制御方式 applescript / exec (外部コマンド) / noop (何もしない)")
+	cmd.PersistentFlags().StringVar(&controllerCmd, "controller-cmd", "", "--controller exec で実行するコマンド (\"<cmd> set <音量>\" と \"<cmd> get\" で呼び出す)")
+	cmd.PersistentFlags().DurationVar(&controllerTimeout, "controller-timeout", volume.DefaultExecTimeout, "--controller exec のコマンド1回あたりの制限時間")
+	cmd.PersistentFlags().StringVar(&noiseSensorCmd, "noise-sensor-cmd", "", "騒音連動ターゲット (noise.enabled) の入力レベルを測るコマンド (dBFSを標準出力に表示する)")
+	cmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "ログの形式 text / json (1行1オブジェクト、ログ収集基盤向け)")
 	cmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "ロギングを詳細化 (-v, -vv, ... 最大4回)")
-	cmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		logging.SetVerbosity(verbosity)
+		if err := logging.SetFormat(logFormat); err != nil {
+			return fmt.Errorf("--log-format: %w", err)
+		}
+		return nil
 	}
 
 	cmd.AddCommand(
 		newDaemonCmd(),
+		newGuardCmd(),
 		newWebCmd(),
 		newServeCmd(),
 		newConfigCmd(),
 		newApplyCmd(),
 		newShellCmd(),
+		newHistoryCmd(),
+		newVerifyStateCmd(),
+		newExplainCmd(),
+		newStateCmd(),
+		newLockCmd(),
+		newUnlockCmd(),
+		newWatchVolumeCmd(),
+		newDevicesCmd(),
+		newStatusCmd(),
+		newSupportBundleCmd(),
+		newTelemetryCmd(),
+		newVersionCmd(),
 	)
+	for _, newCmd := range optionalCommands {
+		cmd.AddCommand(newCmd())
+	}
 
 	return cmd
 }
 
+// newRepository opens the layered config: system < user file < env.
+func newRepository() (domain.ConfigRepository, error) {
+	opts := []repository.FileOption{repository.WithEnvLayer(), repository.WithLastKnownGood(!noFallback)}
+	if systemCfgPath != "" {
+		opts = append(opts, repository.WithSystemLayer(systemCfgPath))
+	}
+	return repository.NewFileRepository(cfgPath, opts...)
+}
+
+// newUseCase wires the secondary adapters into the scheduler use case.
+func newUseCase() (usecase.SchedulerUseCase, error) {
+	controller, err := newController()
+	if err != nil {
+		return nil, err
+	}
+	return newUseCaseWith(controller)
+}
+
+// newSchedulerUseCase is newUseCase for daemon and serve, which run the
+// scheduler unattended: the controller is probed first, so that an
+// unusable one fails at startup instead of on the first apply. With
+// fallbackNoop it is replaced by the noop controller instead.
+func newSchedulerUseCase(fallbackNoop bool) (usecase.SchedulerUseCase, error) {
+	controller, err := newController()
+	if err != nil {
+		return nil, err
+	}
+	prober, ok := controller.(domain.VolumeProber)
+	if !ok {
+		return newUseCaseWith(controller)
+	}
+	if err := prober.Probe(); err != nil {
+		if !fallbackNoop {
+			return nil, fmt.Errorf("音量コントローラー %v を使用できません: %w\n"+
+				"macOS以外では --controller exec --controller-cmd <コマンド> または --controller noop を指定してください。"+
+				"--fallback-noop を付けると、このような場合は音量を変更しないnoopコントローラーで起動します", controller, err)
+		}
+		logging.Warnf("controller %v is unusable: %v; --fallback-noop: starting with the noop controller, volumes will not be changed", controller, err)
+		controller = volume.NewNoopController()
+	}
+	return newUseCaseWith(controller)
+}
+
+// newUseCaseWith wires controller and the other secondary adapters into
+// the scheduler use case.
+func newUseCaseWith(controller domain.VolumeController) (usecase.SchedulerUseCase, error) {
+	repo, err := newRepository()
+	if err != nil {
+		return nil, err
+	}
+	history, err := repository.NewFileHistoryRepository(repository.HistoryPath(cfgPath))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []usecase.Option{
+		usecase.WithHistory(history),
+		usecase.WithAppVolumes(volume.NewAppleScriptAppController()),
+		usecase.WithCommandRunner(command.NewShellRunner()),
+		usecase.WithPowerSource(power.NewPmsetSource()),
+		usecase.WithDryRunController(volume.NewNoopController()),
+	}
+	notifier := notify.NewNoopNotifier()
+	if notifyStatus {
+		notifier = notify.NewAppleScriptNotifier()
+	}
+	opts = append(opts, usecase.WithNotifier(notifier, notifyDebounce))
+	if effectLogPath != "" {
+		effects, err := repository.NewFileEffectLog(effectLogPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, usecase.WithEffectLog(effects))
+	}
+	if lockConfig {
+		opts = append(opts, usecase.WithConfigLocked())
+	}
+	if strictVolume {
+		opts = append(opts, usecase.WithStrictVolume(volumeTol))
+	}
+	if dryRun {
+		opts = append(opts, usecase.WithDryRun())
+	}
+	if watchConfig {
+		opts = append(opts, usecase.WithConfigReload())
+	}
+	if noiseSensorCmd != "" {
+		argv, err := shlex.Split(noiseSensorCmd)
+		if err != nil {
+			return nil, fmt.Errorf("--noise-sensor-cmd: %w", err)
+		}
+		sensor, err := noise.NewExecSensor(argv, noise.DefaultExecTimeout)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, usecase.WithNoiseSensor(sensor))
+	}
+	opts = append(opts, embedOptions...)
+	// The scheduler hands the config's ramp to the decorator before each set
+	return usecase.NewSchedulerUseCase(repo, volume.NewRampingController(controller), opts...)
+}
+
+// newController creates the volume controller selected by --controller.
+func newController() (domain.VolumeController, error) {
+	switch controllerType {
+	case "applescript":
+		return volume.NewAppleScriptController(), nil
+	case "exec":
+		if controllerCmd == "" {
+			return nil, errors.New("--controller exec には --controller-cmd が必要です")
+		}
+		argv, err := shlex.Split(controllerCmd)
+		if err != nil {
+			return nil, fmt.Errorf("--controller-cmd: %w", err)
+		}
+		return volume.NewExecController(argv, controllerTimeout)
+	case "noop":
+		return volume.NewNoopController(), nil
+	default:
+		return nil, fmt.Errorf("unknown controller %q (applescript, exec or noop)", controllerType)
+	}
+}
+
+// metricsPushFlags holds the Pushgateway options shared by daemon and serve.
+type metricsPushFlags struct {
+	url      string
+	interval time.Duration
+	instance string
+}
+
+func (f *metricsPushFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.url, "metrics-push-url", "", "メトリクスを定期的にpushするPushgatewayのURL 例:http://pushgateway:9091")
+	cmd.Flags().DurationVar(&f.interval, "metrics-push-interval", 30*time.Second, "メトリクスをpushする間隔")
+	cmd.Flags().StringVar(&f.instance, "metrics-instance", "", "Pushgatewayのinstanceラベル (既定はホスト名)")
+}
+
+// start launches the pusher in the background when a URL is configured.
+func (f *metricsPushFlags) start(ctx context.Context, uc usecase.SchedulerUseCase) error {
+	if f.url == "" {
+		return nil
+	}
+	var opts []metrics.PushOption
+	if f.instance != "" {
+		opts = append(opts, metrics.WithInstance(f.instance))
+	}
+	pusher, err := metrics.NewPusher(uc, f.url, f.interval, opts...)
+	if err != nil {
+		return err
+	}
+	logging.Infof("pushing metrics to %s every %s", f.url, f.interval)
+	go pusher.Run(ctx)
+	return nil
+}
+
+// tlsFlags holds the HTTPS options shared by web and serve.
+type tlsFlags struct {
+	certFile string
+	keyFile  string
+	auto     bool
+}
+
+func (f *tlsFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.certFile, "tls-cert", "", "HTTPSで使う証明書ファイル (PEM、--tls-keyと併用)")
+	cmd.Flags().StringVar(&f.keyFile, "tls-key", "", "HTTPSで使う秘密鍵ファイル (PEM、--tls-certと併用)")
+	cmd.Flags().BoolVar(&f.auto, "tls-auto", false, "自己署名証明書を生成・キャッシュしてHTTPSで起動し、フィンガープリントを表示")
+}
+
+// options resolves the certificate to use; plaintext when none is given.
+func (f *tlsFlags) options() ([]web.Option, error) {
+	if f.auto && (f.certFile != "" || f.keyFile != "") {
+		return nil, errors.New("--tls-auto と --tls-cert/--tls-key は同時に指定できません")
+	}
+	if (f.certFile == "") != (f.keyFile == "") {
+		return nil, errors.New("--tls-cert と --tls-key は両方指定してください")
+	}
+
+	certFile, keyFile := f.certFile, f.keyFile
+	if f.auto {
+		certFile, keyFile = web.AutoTLSPaths(cfgPath)
+		created, err := web.EnsureSelfSignedCert(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("self-signed certificate: %w", err)
+		}
+		fingerprint, err := web.CertFingerprint(certFile)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			fmt.Printf("自己署名証明書を生成しました: %s\n", certFile)
+		}
+		fmt.Printf("証明書のSHA-256フィンガープリント: %s\n", fingerprint)
+	}
+	if certFile == "" {
+		return nil, nil
+	}
+	return []web.Option{web.WithTLS(certFile, keyFile)}, nil
+}
+
+// applyRateFlags limits the applies requested through the web API.
+type applyRateFlags struct {
+	perSecond float64
+	burst     int
+}
+
+func (f *applyRateFlags) register(cmd *cobra.Command) {
+	cmd.Flags().Float64Var(&f.perSecond, "apply-rate", 1, "Web UI・APIからの適用を1秒あたりこの回数までに制限し、超えた要求には429を返す (0で無制限、スケジューラの定期適用は対象外)")
+	cmd.Flags().IntVar(&f.burst, "apply-burst", 3, "--apply-rate の制限の中で連続して受け付ける適用の回数")
+}
+
+func (f *applyRateFlags) option() web.Option {
+	return web.WithApplyRateLimit(f.perSecond, f.burst)
+}
+
+// envAuthToken supplies the API token when --auth-token is not given, so
+// that it need not appear in the process list. Clients of a running
+// server (explain --server, support-bundle) send it too.
+const envAuthToken = "MICGAIN_AUTH_TOKEN"
+
+// resolveAuthToken returns the --auth-token value, or the environment's.
+func resolveAuthToken(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	return os.Getenv(envAuthToken)
+}
+
+// authorizeRequest attaches the API token from the environment, if any,
+// to a request for a running server.
+func authorizeRequest(req *http.Request) {
+	if token := os.Getenv(envAuthToken); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
 func newDaemonCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		push         metricsPushFlags
+		ping         telemetryFlags
+		fallbackNoop bool
+	)
+	cmd := &cobra.Command{
 		Use:   "daemon",
 		Short: "スケジューラのみを起動（Webサーバーなし）",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := repository.NewFileRepository(cfgPath)
-			if err != nil {
-				return err
-			}
-			controller := volume.NewAppleScriptController()
-			uc, err := usecase.NewSchedulerUseCase(repo, controller)
+			uc, err := newSchedulerUseCase(fallbackNoop)
 			if err != nil {
 				return err
 			}
@@ -78,133 +386,596 @@ func newDaemonCmd() *cobra.Command {
 			fmt.Println("Mic Gain Manager daemon started")
 			logging.Infof("Scheduler daemon started")
 			uc.Start(ctx)
+			if err := push.start(ctx, uc); err != nil {
+				return err
+			}
+			ping.start(ctx)
 
 			<-ctx.Done()
 			fmt.Println("Daemon shutting down...")
 			return nil
 		},
 	}
+	push.register(cmd)
+	ping.register(cmd)
+	cmd.Flags().BoolVar(&fallbackNoop, "fallback-noop", false, "起動時の確認で音量コントローラーが使えない場合 (osascriptがないなど)、エラーにせず音量を変更しないnoopコントローラーで起動する")
+	return cmd
 }
 
 func newWebCmd() *cobra.Command {
-	var addr string
+	var (
+		addr, basePath, portFile string
+		advertise                bool
+		tlsOpts                  tlsFlags
+		authToken                string
+		applyRate                applyRateFlags
+	)
 	cmd := &cobra.Command{
 		Use:   "web",
 		Short: "Web UIとREST APIのみを起動（スケジューラなし）",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := repository.NewFileRepository(cfgPath)
+			uc, err := newUseCase()
 			if err != nil {
 				return err
 			}
-			controller := volume.NewAppleScriptController()
-			uc, err := usecase.NewSchedulerUseCase(repo, controller)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			opts, err := tlsOpts.options()
 			if err != nil {
 				return err
 			}
+			opts = append(opts, web.WithBasePath(basePath), web.WithVersion(Version), web.WithAuthToken(resolveAuthToken(authToken)))
+			opts = append(opts, applyRate.option())
 
-			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-			defer stop()
+			ln, err := listen(addr, portFile)
+			if err != nil {
+				return err
+			}
+			defer removePortFile(portFile)
 
-			srv := web.NewServer(uc, addr)
-			fmt.Printf("Mic Gain Manager Web UI running at http://%s\n", addr)
-			logging.Infof("Web UI: http://%s (scheduler disabled)", addr)
+			srv := web.NewServer(uc, addr, opts...)
+			fmt.Printf("Mic Gain Manager Web UI running at %s://%s%s\n", srv.Scheme(), ln.Addr(), basePath)
+			logging.Infof("Web UI: %s://%s (scheduler disabled)", srv.Scheme(), ln.Addr())
+
+			var adv *mdns.Advertiser
+			if advertise {
+				if adv, err = advertiseServer(ln, basePath, srv.Scheme()); err != nil {
+					return err
+				}
+			}
 
 			go func() {
 				<-ctx.Done()
 				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				defer cancel()
+				if adv != nil {
+					_ = adv.Shutdown(shutdownCtx)
+				}
 				_ = srv.Shutdown(shutdownCtx)
 			}()
 
-			return srv.Start()
+			return srv.Serve(ln)
 		},
 	}
 	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:7070", "HTTPサーバーのアドレス:ポート")
+	cmd.Flags().StringVar(&basePath, "base-path", "", "リバースプロキシ配下で公開する場合のパスプレフィックス 例:/micgain")
+	cmd.Flags().StringVar(&portFile, "port-file", "", "待ち受けを開始したポート番号を書き出すファイル (--addr :0 と併用、終了時に削除)")
+	cmd.Flags().BoolVar(&advertise, "advertise", false, "mDNS(Bonjour)で_micgain._tcpとしてLANに公開 (終了時に取り下げ)")
+	tlsOpts.register(cmd)
+	cmd.Flags().StringVar(&authToken, "auth-token", "", "/api/* に \"Authorization: Bearer <トークン>\" を必須にする (未指定時は環境変数 "+envAuthToken+")")
+	applyRate.register(cmd)
 	return cmd
 }
 
 func newServeCmd() *cobra.Command {
-	var addr string
+	var (
+		addr, basePath, portFile string
+		advertise                bool
+		tlsOpts                  tlsFlags
+		authToken                string
+		applyRate                applyRateFlags
+		push                     metricsPushFlags
+		ping                     telemetryFlags
+		fallbackNoop             bool
+	)
 	cmd := &cobra.Command{
 		Use:   "serve",
 		Short: "Web UIとスケジューラを両方起動",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := repository.NewFileRepository(cfgPath)
+			uc, err := newSchedulerUseCase(fallbackNoop)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			// Start scheduler
+			uc.Start(ctx)
+			if err := push.start(ctx, uc); err != nil {
+				return err
+			}
+			ping.start(ctx)
+
+			opts, err := tlsOpts.options()
+			if err != nil {
+				return err
+			}
+			opts = append(opts, web.WithBasePath(basePath), web.WithVersion(Version), web.WithAuthToken(resolveAuthToken(authToken)))
+			opts = append(opts, applyRate.option())
+
+			ln, err := listen(addr, portFile)
+			if err != nil {
+				return err
+			}
+			defer removePortFile(portFile)
+
+			srv := web.NewServer(uc, addr, opts...)
+			fmt.Printf("Mic Gain Manager UI running at %s://%s%s\n", srv.Scheme(), ln.Addr(), basePath)
+			logging.Infof("Mic Gain Manager UI: %s://%s", srv.Scheme(), ln.Addr())
+
+			var adv *mdns.Advertiser
+			if advertise {
+				if adv, err = advertiseServer(ln, basePath, srv.Scheme()); err != nil {
+					return err
+				}
+			}
+
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if adv != nil {
+					_ = adv.Shutdown(shutdownCtx)
+				}
+				_ = srv.Shutdown(shutdownCtx)
+			}()
+
+			return srv.Serve(ln)
+		},
+	}
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:7070", "HTTPサーバーのアドレス:ポート")
+	cmd.Flags().StringVar(&basePath, "base-path", "", "リバースプロキシ配下で公開する場合のパスプレフィックス 例:/micgain")
+	cmd.Flags().StringVar(&portFile, "port-file", "", "待ち受けを開始したポート番号を書き出すファイル (--addr :0 と併用、終了時に削除)")
+	cmd.Flags().BoolVar(&advertise, "advertise", false, "mDNS(Bonjour)で_micgain._tcpとしてLANに公開 (終了時に取り下げ)")
+	tlsOpts.register(cmd)
+	cmd.Flags().StringVar(&authToken, "auth-token", "", "/api/* に \"Authorization: Bearer <トークン>\" を必須にする (未指定時は環境変数 "+envAuthToken+")")
+	applyRate.register(cmd)
+	push.register(cmd)
+	ping.register(cmd)
+	cmd.Flags().BoolVar(&fallbackNoop, "fallback-noop", false, "起動時の確認で音量コントローラーが使えない場合 (osascriptがないなど)、エラーにせず音量を変更しないnoopコントローラーで起動する")
+	return cmd
+}
+
+// parseVolumeList parses a comma separated list of volumes; an empty
+// string yields an empty list.
+func parseVolumeList(s string) ([]int, error) {
+	var volumes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid volume %q", part)
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes, nil
+}
+
+// listen binds addr before the server starts, so that the actual port is
+// known even for ":0", and writes it to portFile when one is given.
+func listen(addr, portFile string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if portFile != "" {
+		port := ln.Addr().(*net.TCPAddr).Port
+		if err := os.WriteFile(portFile, []byte(strconv.Itoa(port)+"\n"), 0o644); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("write port file: %w", err)
+		}
+	}
+	return ln, nil
+}
+
+// advertiseServer announces the listener on the LAN via mDNS.
+func advertiseServer(ln net.Listener, basePath, scheme string) (*mdns.Advertiser, error) {
+	tcpAddr := ln.Addr().(*net.TCPAddr)
+	if tcpAddr.IP.IsLoopback() {
+		logging.Warnf("advertising a server bound to %s; other hosts cannot reach it (use e.g. --addr 0.0.0.0:7070)", tcpAddr)
+	}
+	return mdns.Advertise(mdns.Service{
+		Port: tcpAddr.Port,
+		Text: []string{"path=" + basePath + "/", "scheme=" + scheme, "version=" + Version},
+	})
+}
+
+func removePortFile(portFile string) {
+	if portFile == "" {
+		return
+	}
+	if err := os.Remove(portFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logging.Warnf("remove port file: %v", err)
+	}
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "設定の取得・更新を行うサブコマンド",
+	}
+	cmd.AddCommand(newConfigGetCmd(), newConfigSetCmd(), newConfigPathCmd(), newConfigProfileCmd(), newConfigEditCmd(), newConfigSchemaCmd(), newConfigValidateCmd())
+	return cmd
+}
+
+func newConfigGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get",
+		Short: "現在の設定(JSON)を表示",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := newRepository()
+			if err != nil {
+				return err
+			}
+			config, state, err := repo.Load()
+			if err != nil {
+				return err
+			}
+
+			display := configDisplay(config, state, time.Now())
+			out, _ := json.MarshalIndent(display, "", "  ")
+			fmt.Println(string(out))
+			return nil
+		},
+	}
+}
+
+// configDisplay is what config get shows for the saved config and state
+// at now.
+func configDisplay(config domain.Config, state domain.ScheduleState, now time.Time) map[string]any {
+	service := domain.NewSchedulerService()
+	display := map[string]interface{}{
+		"targetVolume":    config.TargetVolume,
+		"intervalSeconds": config.Interval.Seconds(),
+		"enabled":         config.Enabled,
+		"lastApplyStatus": service.ReportedStatus(state, config).String(),
+	}
+	if config.ScheduleMode != domain.ScheduleRelative {
+		display["scheduleMode"] = config.ScheduleMode.String()
+	}
+	if config.Schedule != "" {
+		display["schedule"] = config.Schedule
+	}
+	if config.Timezone != "" {
+		display["timezone"] = config.Timezone
+	}
+	if config.DeviceName != "" {
+		display["deviceName"] = config.DeviceName
+	}
+	if config.OnDeviceAbsent != domain.DeviceAbsentError {
+		display["onDeviceAbsent"] = config.OnDeviceAbsent.String()
+	}
+	if len(config.FormatVolumes) > 0 {
+		display["formatVolumes"] = domain.FormatFormatVolumes(config.FormatVolumes)
+	}
+	if config.AdaptiveInterval {
+		display["adaptiveInterval"] = true
+		display["maxIntervalSeconds"] = config.MaxInterval.Seconds()
+	}
+	if !config.RescheduleOnManualApply {
+		display["rescheduleOnManualApply"] = false
+	}
+	if config.Locked {
+		display["locked"] = true
+	}
+	if config.MinTargetVolume > 0 {
+		display["minTargetVolume"] = config.MinTargetVolume
+	}
+	if config.ErrorThreshold > 0 {
+		display["errorThreshold"] = config.ErrorThreshold
+	}
+	if config.DriftAlertThreshold > 0 {
+		display["driftAlertThreshold"] = config.DriftAlertThreshold
+	}
+	if config.RedactErrors {
+		display["redactErrors"] = true
+	}
+	if config.MaxRetries > 0 {
+		display["maxRetries"] = config.MaxRetries
+		display["retryBackoffSeconds"] = config.RetryDelay(1).Seconds()
+	}
+	if ramp := config.Ramp(); ramp.Duration > 0 {
+		display["rampDurationMs"] = ramp.Duration.Milliseconds()
+		display["rampSteps"] = ramp.Steps
+	}
+	if config.StaggerBetweenDevices > 0 {
+		display["staggerBetweenDevicesMs"] = config.StaggerBetweenDevices.Milliseconds()
+	}
+	if config.ReapplyOnPowerChange {
+		display["reapplyOnPowerChange"] = true
+		display["powerPollSeconds"] = config.PowerPoll().Seconds()
+	}
+	if config.ParkVolume != nil {
+		display["parkVolume"] = *config.ParkVolume
+		display["fadeOnPark"] = config.FadeOnPark
+	}
+	if config.Output.Enabled {
+		display["output"] = map[string]any{
+			"enabled": true,
+			"volume":  config.Output.Volume,
+		}
+	}
+	if config.Noise.Enabled {
+		display["noise"] = map[string]any{
+			"enabled":       true,
+			"referenceDbfs": config.Noise.ReferenceDBFS,
+			"minVolume":     config.Noise.MinVolume,
+			"maxVolume":     config.Noise.MaxVolume,
+		}
+	}
+	if len(config.AllowedVolumes) > 0 {
+		display["allowedVolumes"] = config.AllowedVolumes
+	}
+	if !state.LastApplied.IsZero() {
+		display["lastApplied"] = state.LastApplied.Local().Format(time.RFC3339)
+		display["lastAppliedRelative"] = formatRelative(state.LastApplied, now)
+	}
+	if !state.FirstApplied.IsZero() {
+		display["firstApplied"] = state.FirstApplied.Local().Format(time.RFC3339)
+		display["firstAppliedRelative"] = formatRelative(state.FirstApplied, now)
+	}
+	if !state.LastWakeApply.IsZero() {
+		display["lastWakeApply"] = state.LastWakeApply.Local().Format(time.RFC3339)
+		display["lastWakeApplyRelative"] = formatRelative(state.LastWakeApply, now)
+	}
+	// Matches the API; an apply in flight is only seen by its own process
+	display["idle"] = !state.IsRunning
+	if nextRun := service.ProjectedNextRun(state, config); !nextRun.IsZero() && (config.Enabled || config.Output.Enabled) {
+		display["nextRun"] = nextRun.Local().Format(time.RFC3339)
+		display["nextRunRelative"] = formatRelative(nextRun, now)
+	}
+	if state.LastError != nil {
+		display["lastError"] = state.LastError.Error()
+	}
+	if state.LastWarning != "" {
+		display["lastWarning"] = state.LastWarning
+	}
+	if state.LastObservedVolume != nil {
+		display["lastObservedVolume"] = *state.LastObservedVolume
+	}
+	if config.DryRun {
+		display["dryRun"] = true
+	}
+	if state.LastApplyDryRun {
+		display["lastApplyDryRun"] = true
+	}
+	if len(config.Curve) > 0 {
+		display["curve"] = domain.FormatCurve(config.Curve)
+	}
+	if len(config.QuietHours) > 0 {
+		display["quietHours"] = domain.FormatQuietHours(config.QuietHours)
+		if until := config.QuietUntil(now); !until.IsZero() {
+			display["quietUntil"] = until.Local().Format(time.RFC3339)
+		}
+	}
+	if len(config.AppVolumes) > 0 {
+		display["appVolumes"] = domain.FormatAppVolumes(config.AppVolumes)
+	}
+	if config.ActiveProfile != "" {
+		display["activeProfile"] = config.ActiveProfile
+	}
+	if config.PreApplyCmd != "" {
+		display["preApplyCmd"] = config.PreApplyCmd
+		display["abortOnPreApplyFailure"] = config.AbortOnPreApplyFailure
+	}
+	if config.PostApplyCmd != "" {
+		display["postApplyCmd"] = config.PostApplyCmd
+	}
+	if state.Hold.Active {
+		display["lock"] = map[string]interface{}{
+			"volume":        state.Hold.Volume,
+			"since":         state.Hold.Since.Local().Format(time.RFC3339),
+			"sinceRelative": formatRelative(state.Hold.Since, now),
+		}
+	}
+	return display
+}
+
+func newConfigSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "設定ファイルのJSON Schemaを出力 (エディタの補完・検証用)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, err := json.MarshalIndent(repository.ConfigSchema(), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		},
+	}
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "設定ファイルを適用・変更せずに検証し、正規化した値を表示 (不正なら終了コード1)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				file = cfgPath
+			}
+			// A missing file would load as the defaults and pass
+			if _, err := os.Stat(file); err != nil {
+				return err
+			}
+			// The file alone, without the system and env layers, and
+			// without the upgrade of older layouts written back
+			repo, err := repository.NewFileRepository(file, repository.WithReadOnly())
+			if err != nil {
+				return err
+			}
+			config, _, err := repo.Load()
+			if err == nil {
+				err = config.CheckAllowed(config.TargetVolume)
+			}
+			if err == nil {
+				config, err = domain.NewSchedulerService().ValidateAndNormalize(config)
+			}
+			if err != nil {
+				return fmt.Errorf("%s は不正です: %w", file, err)
+			}
+
+			out, err := marshalEditable(config)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("valid: %s\n%s\n", file, out)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "", "検証する設定ファイル (省略時は --config のファイル)")
+	return cmd
+}
+
+func newConfigPathCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path",
+		Short: "設定レイヤー(system < user < env)と各項目の由来を表示",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := newRepository()
+			if err != nil {
+				return err
+			}
+			if _, _, err := repo.Load(); err != nil {
+				return err
+			}
+			layered := repo.(*repository.FileRepository)
+
+			fmt.Println("layers (低→高):")
+			for _, layer := range layered.Layers() {
+				status := "not found"
+				if layer.Found {
+					status = "loaded"
+				}
+				fmt.Printf("  %-7s %s (%s)\n", layer.Name, layer.Source, status)
+			}
+			fmt.Println("  flags   config set / apply のフラグ (最優先)")
+
+			origins := layered.Origins()
+			keys := make([]string, 0, len(origins))
+			for key := range origins {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			fmt.Println("fields:")
+			for _, key := range keys {
+				fmt.Printf("  %-16s %s\n", key, origins[key])
+			}
+			return nil
+		},
+	}
+}
+
+func newConfigProfileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "名前付きプロファイル(音量・間隔・スケジューラON/OFF)の管理",
+	}
+
+	save := &cobra.Command{
+		Use:   "save <name>",
+		Short: "現在の設定をプロファイルとして保存",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uc, err := newUseCase()
+			if err != nil {
+				return err
+			}
+			config := uc.GetSnapshot().Config
+			config = config.WithSavedProfile(domain.ProfileFromConfig(args[0], config))
+			if err := uc.UpdateConfig(config, false); err != nil {
+				return err
+			}
+			fmt.Printf("プロファイル %q を保存しました\n", args[0])
+			return nil
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "プロファイル一覧を表示",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := newRepository()
+			if err != nil {
+				return err
+			}
+			config, _, err := repo.Load()
+			if err != nil {
+				return err
+			}
+			for _, p := range config.Profiles {
+				marker := " "
+				if p.Name == config.ActiveProfile {
+					marker = "*"
+				}
+				fmt.Printf("%s %-12s volume=%d interval=%s enabled=%t\n",
+					marker, p.Name, p.TargetVolume, p.Interval, p.Enabled)
+			}
+			return nil
+		},
+	}
+
+	var applyNow bool
+	use := &cobra.Command{
+		Use:   "use <name>",
+		Short: "プロファイルに切り替え(スケジューラ設定も切り替わる)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uc, err := newUseCase()
 			if err != nil {
 				return err
 			}
-			controller := volume.NewAppleScriptController()
-			uc, err := usecase.NewSchedulerUseCase(repo, controller)
-			if err != nil {
+			if err := uc.UseProfile(args[0], applyNow); err != nil {
 				return err
 			}
-
-			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-			defer stop()
-
-			// Start scheduler
-			uc.Start(ctx)
-
-			srv := web.NewServer(uc, addr)
-			fmt.Printf("Mic Gain Manager UI running at http://%s\n", addr)
-			logging.Infof("Mic Gain Manager UI: http://%s", addr)
-
-			go func() {
-				<-ctx.Done()
-				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-				_ = srv.Shutdown(shutdownCtx)
-			}()
-
-			return srv.Start()
+			config := uc.GetSnapshot().Config
+			fmt.Printf("プロファイル %q に切り替えました: volume=%d interval=%s enabled=%t\n",
+				args[0], config.TargetVolume, config.Interval, config.Enabled)
+			return nil
 		},
 	}
-	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:7070", "HTTPサーバーのアドレス:ポート")
-	return cmd
-}
-
-func newConfigCmd() *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   "config",
-		Short: "設定の取得・更新を行うサブコマンド",
-	}
-	cmd.AddCommand(newConfigGetCmd(), newConfigSetCmd())
-	return cmd
-}
+	use.Flags().BoolVar(&applyNow, "apply-now", false, "切り替え後ただちに適用")
 
-func newConfigGetCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "get",
-		Short: "現在の設定(JSON)を表示",
+	del := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "プロファイルを削除",
+		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := repository.NewFileRepository(cfgPath)
+			uc, err := newUseCase()
 			if err != nil {
 				return err
 			}
-			config, state, err := repo.Load()
+			config, err := uc.GetSnapshot().Config.WithoutProfile(args[0])
 			if err != nil {
 				return err
 			}
-
-			// Convert to display format
-			display := map[string]interface{}{
-				"targetVolume":    config.TargetVolume,
-				"intervalSeconds": int(config.Interval.Seconds()),
-				"enabled":         config.Enabled,
-				"lastApplyStatus": state.LastApplyStatus.String(),
-			}
-			if !state.LastApplied.IsZero() {
-				display["lastApplied"] = state.LastApplied.Format(time.RFC3339)
-			}
-			if state.LastError != nil {
-				display["lastError"] = state.LastError.Error()
+			if err := uc.UpdateConfig(config, false); err != nil {
+				return err
 			}
-
-			out, _ := json.MarshalIndent(display, "", "  ")
-			fmt.Println(string(out))
+			fmt.Printf("プロファイル %q を削除しました\n", args[0])
 			return nil
 		},
 	}
+
+	cmd.AddCommand(save, list, use, del)
+	return cmd
 }
 
 func newConfigSetCmd() *cobra.Command {
@@ -212,18 +983,66 @@ func newConfigSetCmd() *cobra.Command {
 		volumeFlag   int
 		intervalFlag time.Duration
 		enabledFlag  string
+		curveFlag    string
+		quietFlag    string
+		adaptiveFlag bool
+		maxInterval  time.Duration
+		reschedule   bool
+		minVolume    int
+		errThreshold int
+		maxRetries   int
+		retryBackoff time.Duration
+		rampFlag     time.Duration
+		rampSteps    int
+		staggerFlag  time.Duration
+		driftAlert   int
+		redactErrors bool
+		parkVolume   int
+		fadeOnPark   bool
+		powerFlag    bool
+		powerPoll    time.Duration
+		outputFlag   bool
+		outputVolume int
+		noiseFlag    bool
+		noiseRef     float64
+		noiseMin     int
+		noiseMax     int
+		allowedFlag  string
+		appFlag      string
+		modeFlag     string
+		cronFlag     string
+		tzFlag       string
+		deviceFlag   string
+		absentFlag   string
+		formatFlag   string
 		applyNow     bool
+		simulate     bool
+		noWait       bool
 	)
 	cmd := &cobra.Command{
-		Use:   "set",
+		Use:   "set [key=value ...]",
 		Short: "設定を書き換え(必要なら即時適用)",
+		Long: `設定を書き換えます。フラグの代わりに key=value で任意の設定を指定できます。
+キーは config edit で開くJSONのキーで、入れ子のキーは noise.minVolume のようにドットでつなぎます。
+値の書式も config edit と同じです (interval=45s, curve=07:00=30,22:00=20, allowedVolumes=40,60)。
+
+  micgain-manager config set targetVolume=60 scheduleMode=fixed timezone=Asia/Tokyo`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := repository.NewFileRepository(cfgPath)
-			if err != nil {
-				return err
+			// Hold the file lock from load to save, so no other process
+			// writes in between
+			if !simulate {
+				wait := repository.DefaultLockTimeout
+				if noWait {
+					wait = 0
+				}
+				unlock, err := repository.LockFile(cfgPath, wait)
+				if err != nil {
+					return err
+				}
+				defer unlock()
 			}
-			controller := volume.NewAppleScriptController()
-			uc, err := usecase.NewSchedulerUseCase(repo, controller)
+
+			uc, err := newUseCase()
 			if err != nil {
 				return err
 			}
@@ -247,6 +1066,149 @@ func newConfigSetCmd() *cobra.Command {
 					return errors.New("--enabled には true/false を指定してください")
 				}
 			}
+			if cmd.Flags().Changed("schedule-mode") {
+				mode, err := domain.ParseScheduleMode(modeFlag)
+				if err != nil {
+					return err
+				}
+				config.ScheduleMode = mode
+			}
+			if cmd.Flags().Changed("schedule") {
+				config.Schedule = cronFlag
+			}
+			if cmd.Flags().Changed("timezone") {
+				config.Timezone = tzFlag
+			}
+			if cmd.Flags().Changed("device") {
+				config.DeviceName = deviceFlag
+			}
+			if cmd.Flags().Changed("on-device-absent") {
+				policy, err := domain.ParseDeviceAbsentPolicy(absentFlag)
+				if err != nil {
+					return err
+				}
+				config.OnDeviceAbsent = policy
+			}
+			if cmd.Flags().Changed("format-volume") {
+				rules, err := domain.ParseFormatVolumes(formatFlag)
+				if err != nil {
+					return err
+				}
+				config.FormatVolumes = rules
+			}
+			if cmd.Flags().Changed("adaptive-interval") {
+				config.AdaptiveInterval = adaptiveFlag
+			}
+			if cmd.Flags().Changed("max-interval") {
+				config.MaxInterval = maxInterval
+			}
+			if cmd.Flags().Changed("reschedule-on-manual-apply") {
+				config.RescheduleOnManualApply = reschedule
+			}
+			if cmd.Flags().Changed("min-volume") {
+				config.MinTargetVolume = minVolume
+			}
+			if cmd.Flags().Changed("error-threshold") {
+				config.ErrorThreshold = errThreshold
+			}
+			if cmd.Flags().Changed("max-retries") {
+				config.MaxRetries = maxRetries
+			}
+			if cmd.Flags().Changed("retry-backoff") {
+				config.RetryBackoff = retryBackoff
+			}
+			if cmd.Flags().Changed("ramp") {
+				config.RampDuration = rampFlag
+			}
+			if cmd.Flags().Changed("ramp-steps") {
+				config.RampSteps = rampSteps
+			}
+			if cmd.Flags().Changed("stagger") {
+				config.StaggerBetweenDevices = staggerFlag
+			}
+			if cmd.Flags().Changed("drift-alert-threshold") {
+				config.DriftAlertThreshold = driftAlert
+			}
+			if cmd.Flags().Changed("redact-errors") {
+				config.RedactErrors = redactErrors
+			}
+			if cmd.Flags().Changed("park-volume") {
+				config.ParkVolume = nil
+				if parkVolume >= 0 {
+					config.ParkVolume = &parkVolume
+				}
+			}
+			if cmd.Flags().Changed("fade-on-park") {
+				config.FadeOnPark = fadeOnPark
+			}
+			if cmd.Flags().Changed("reapply-on-power-change") {
+				config.ReapplyOnPowerChange = powerFlag
+			}
+			if cmd.Flags().Changed("power-poll") {
+				config.PowerPollInterval = powerPoll
+			}
+			if cmd.Flags().Changed("output-lock") {
+				config.Output.Enabled = outputFlag
+			}
+			if cmd.Flags().Changed("output-volume") {
+				config.Output.Volume = outputVolume
+			}
+			if cmd.Flags().Changed("noise-adaptive") {
+				config.Noise.Enabled = noiseFlag
+			}
+			if cmd.Flags().Changed("noise-reference") {
+				config.Noise.ReferenceDBFS = noiseRef
+			}
+			if cmd.Flags().Changed("noise-min-volume") {
+				config.Noise.MinVolume = noiseMin
+			}
+			if cmd.Flags().Changed("noise-max-volume") {
+				config.Noise.MaxVolume = noiseMax
+			}
+			if cmd.Flags().Changed("allowed-volumes") {
+				allowed, err := parseVolumeList(allowedFlag)
+				if err != nil {
+					return err
+				}
+				config.AllowedVolumes = allowed
+			}
+			if cmd.Flags().Changed("app-volume") {
+				rules, err := domain.ParseAppVolumes(appFlag)
+				if err != nil {
+					return err
+				}
+				config.AppVolumes = rules
+			}
+			if cmd.Flags().Changed("curve") {
+				curve, err := domain.ParseCurve(curveFlag)
+				if err != nil {
+					return err
+				}
+				config.Curve = curve
+			}
+			if cmd.Flags().Changed("quiet-hours") {
+				quiet, err := domain.ParseQuietHours(quietFlag)
+				if err != nil {
+					return err
+				}
+				config.QuietHours = quiet
+			}
+
+			if len(args) > 0 {
+				config, err = assignConfig(config, args)
+				if err != nil {
+					return err
+				}
+			}
+
+			if simulate {
+				sim, err := uc.SimulateConfig(config)
+				if err != nil {
+					return fmt.Errorf("この設定は保存できません: %w", err)
+				}
+				printSimulation(sim, time.Now())
+				return nil
+			}
 
 			if err := uc.UpdateConfig(config, applyNow); err != nil {
 				return err
@@ -263,22 +1225,86 @@ func newConfigSetCmd() *cobra.Command {
 	cmd.Flags().IntVar(&volumeFlag, "volume", 50, "入力音量(0-100)")
 	cmd.Flags().DurationVar(&intervalFlag, "interval", time.Minute, "再適用インターバル 例:45s,2m")
 	cmd.Flags().StringVar(&enabledFlag, "enabled", "", "true/false を指定するとスケジューラON/OFF")
+	cmd.Flags().StringVar(&modeFlag, "schedule-mode", "relative", "relative: 前回適用からインターバル後 / fixed: 0時起点のインターバル区切りの時刻(:00, :30など)")
+	cmd.Flags().StringVar(&cronFlag, "schedule", "", "インターバルの代わりに適用する時刻を決めるcron式 例:\"0 9-18 * * mon-fri\", @hourly (空文字で解除しインターバルに戻す)")
+	cmd.Flags().BoolVar(&adaptiveFlag, "adaptive-interval", false, "音量が安定している間はインターバルを段階的に延長")
+	cmd.Flags().DurationVar(&maxInterval, "max-interval", 15*time.Minute, "adaptive-interval 時のインターバル上限")
+	cmd.Flags().BoolVar(&reschedule, "reschedule-on-manual-apply", true, "手動の適用(apply、Webの適用)から次回実行時刻を数え直す (falseで手動の適用があっても次回実行時刻を変えない)")
+	cmd.Flags().IntVar(&minVolume, "min-volume", 0, "適用時に下回らない最低音量(0で無効)")
+	cmd.Flags().IntVar(&driftAlert, "drift-alert-threshold", 0, "定期適用時に目標からこの値を超えてずれていた音量を補正したら警告ログと履歴に記録 (0で無効)")
+	cmd.Flags().IntVar(&parkVolume, "park-volume", -1, "スケジューラを無効にしたときに戻す音量 (-1で解除し、音量をそのままにする)")
+	cmd.Flags().BoolVar(&fadeOnPark, "fade-on-park", false, "park-volume へ一度に変えず、2秒かけて段階的に変える")
+	cmd.Flags().BoolVar(&powerFlag, "reapply-on-power-change", false, "電源(AC/バッテリー)が切り替わったらすぐに音量を再適用 (macOSのpmsetで検出)")
+	cmd.Flags().DurationVar(&powerPoll, "power-poll", 0, "電源の状態を確認する間隔 (0で既定の10秒)")
+	cmd.Flags().BoolVar(&outputFlag, "output-lock", false, "入力とは別に、定期適用のたびに出力(スピーカー)音量も output-volume に固定 (スケジューラが無効でも出力だけ固定する)")
+	cmd.Flags().IntVar(&outputVolume, "output-volume", 0, "output-lock で固定する出力音量(0-100)")
+	cmd.Flags().BoolVar(&noiseFlag, "noise-adaptive", false, "--noise-sensor-cmd で測った入力レベルが基準に近づくよう、定期適用のたびにターゲットを調整")
+	cmd.Flags().Float64Var(&noiseRef, "noise-reference", -30, "騒音連動ターゲットで保つ入力レベル (dBFS)")
+	cmd.Flags().IntVar(&noiseMin, "noise-min-volume", 0, "騒音連動ターゲットの下限音量")
+	cmd.Flags().IntVar(&noiseMax, "noise-max-volume", 100, "騒音連動ターゲットの上限音量")
+	cmd.Flags().BoolVar(&redactErrors, "redact-errors", false, "適用失敗の詳細(osascriptの出力など)を設定ファイル・履歴・APIに残さず \"apply failed\" とだけ記録 (詳細はログのみ)")
+	cmd.Flags().IntVar(&errThreshold, "error-threshold", 0, "状態をerrorと表示するまでの連続失敗回数 (それ未満はdegraded、0/1で即error)")
+	cmd.Flags().IntVar(&maxRetries, "max-retries", 0, "音量の設定に失敗したとき、失敗として記録する前に再試行する回数 (0で再試行しない、最大10)")
+	cmd.Flags().DurationVar(&retryBackoff, "retry-backoff", 0, "最初の再試行までの待ち時間。再試行ごとに倍になる (0で既定の1秒、最大30秒)")
+	cmd.Flags().DurationVar(&rampFlag, "ramp", 0, "音量を一度に変えず、現在の音量から目標までこの時間をかけて段階的に変える 例:800ms (0で無効、最大10秒)")
+	cmd.Flags().IntVar(&rampSteps, "ramp-steps", 0, "ramp で音量を変える回数 (0で既定の10回、最大100回。音量の差より多くはならない)")
+	cmd.Flags().DurationVar(&staggerFlag, "stagger", 0, "1回の適用で入力・各アプリ・出力の音量を設定する間隔 例:200ms (0で続けて設定、最大10秒)")
+	cmd.Flags().StringVar(&allowedFlag, "allowed-volumes", "", "設定・適用できる音量の一覧 例:40,60,80 (空文字で制限なし)")
+	cmd.Flags().StringVar(&appFlag, "app-volume", "", "アプリごとの入力音量 例:zoom.us=70,Discord=60 (入力音量をスクリプトで操作できるアプリのみ、空文字で解除)")
+	cmd.Flags().StringVar(&curveFlag, "curve", "", "時刻ごとの音量カーブ 例:07:00=30,12:00=70,22:00=20 (空文字で解除)")
+	cmd.Flags().StringVar(&quietFlag, "quiet-hours", "", "定期適用を止める時間帯 例:22:00-07:00,sat+sun@09:00-12:00,mon-fri@12:00-13:00 (日付をまたいでよい、曜日は開始日、空文字で解除)")
 	cmd.Flags().BoolVar(&applyNow, "apply-now", false, "保存後ただちに適用")
+	cmd.Flags().StringVar(&tzFlag, "timezone", "", "カーブ・静音時間帯・cron式の時刻と fixed モードの区切りを解釈するタイムゾーン (IANA名 例:Asia/Tokyo、空文字でシステムのタイムゾーン)")
+	cmd.Flags().StringVar(&deviceFlag, "device", "", "音量を固定する入力デバイス名 例:\"MacBook Proのマイク\" (SwitchAudioSourceが必要、空文字でシステム既定の入力)")
+	cmd.Flags().StringVar(&absentFlag, "on-device-absent", "error", "--device のデバイスが接続されていないとき error: 適用を失敗させる / skip: 失敗にせず戻るまで待つ / fallback-default: システム既定の入力に適用")
+	cmd.Flags().StringVar(&formatFlag, "format-volume", "", "入力デバイスのサンプルレート(Hz)ごとの音量 例:44100=40,48000=55 (一致しないレートでは --volume、空文字で解除)")
+	cmd.Flags().BoolVar(&noWait, "no-wait", false, "他のプロセス(動作中のデーモンなど)が設定ファイルを書き込み中なら待たずにエラーにする (既定では最大5秒待つ)")
+	cmd.Flags().BoolVar(&simulate, "simulate", false, "保存も適用もせず、保存した場合の設定・次回実行・警告を表示")
 	return cmd
 }
 
+// printSimulation shows the projected result of config set --simulate.
+func printSimulation(sim domain.ConfigSimulation, now time.Time) {
+	config, state := sim.Snapshot.Config, sim.Snapshot.ScheduleState
+	fmt.Println("シミュレーション (保存していません):")
+	fmt.Printf("  volume=%d interval=%s enabled=%t\n", config.TargetVolume, config.Interval, config.Enabled)
+	fmt.Printf("  適用される音量: %d\n", sim.Target)
+	service := domain.NewSchedulerService()
+	fmt.Printf("  実効インターバル: %s\n", service.EffectiveInterval(state, config))
+	if service.Enforcing(state, config) {
+		fmt.Printf("  次回実行: %s (%s)\n", state.NextRun.Local().Format(time.RFC3339), formatRelative(state.NextRun, now))
+	} else {
+		fmt.Println("  次回実行: なし (スケジューラ無効)")
+	}
+	for _, w := range sim.Warnings {
+		fmt.Println("  警告: " + w)
+	}
+}
+
 func newApplyCmd() *cobra.Command {
-	var volumeFlag int
+	var (
+		volumeFlag     int
+		respectEnabled bool
+		verify         bool
+		plan           bool
+		watch          bool
+		watchInterval  time.Duration
+	)
 	cmd := &cobra.Command{
 		Use:   "apply",
 		Short: "現在の設定または指定音量で即時適用",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			repo, err := repository.NewFileRepository(cfgPath)
-			if err != nil {
-				return err
+			if watch && plan {
+				return errors.New("--watch と --plan は同時に指定できません")
+			}
+			if cmd.Flags().Changed("interval") && !watch {
+				return errors.New("--interval は --watch と一緒に指定してください")
 			}
-			controller := volume.NewAppleScriptController()
-			uc, err := usecase.NewSchedulerUseCase(repo, controller)
+			// --verify is strict volume mode for this one apply
+			if verify {
+				strictVolume = true
+			}
+			uc, err := newUseCase()
 			if err != nil {
 				return err
 			}
@@ -288,15 +1314,343 @@ func newApplyCmd() *cobra.Command {
 				volume = volumeFlag
 			}
 
+			if plan {
+				p, err := uc.PlanApply(volume)
+				if err != nil {
+					return err
+				}
+				printApplyPlan(p, respectEnabled)
+				return nil
+			}
+
+			apply := uc.ApplyNow
+			if respectEnabled {
+				apply = uc.ApplyIfEnabled
+			}
+			if watch {
+				if !cmd.Flags().Changed("interval") {
+					watchInterval = uc.GetSnapshot().Config.Interval
+				}
+				if watchInterval < domain.MinInterval {
+					return domain.ErrInvalidInterval
+				}
+				return watchApply(uc, apply, volume, watchInterval)
+			}
+
 			fmt.Printf("音量適用中...\n")
-			if err := uc.ApplyNow(volume); err != nil {
+			if err := apply(volume); err != nil {
 				return err
 			}
+			if verify {
+				fmt.Println("完了 (読み戻して確認済み)")
+				return nil
+			}
 			fmt.Println("完了")
 			return nil
 		},
 	}
 	cmd.Flags().IntVar(&volumeFlag, "volume", 0, "0-100を指定。未指定なら設定値を利用")
+	cmd.Flags().BoolVar(&respectEnabled, "respect-enabled", false, "スケジューラが無効なら適用せずエラー終了")
+	cmd.Flags().BoolVar(&verify, "verify", false, "適用後に音量を読み戻し、--volume-toleranceを超えてずれていればエラー終了")
+	cmd.Flags().BoolVar(&plan, "plan", false, "適用せず、適用する音量・コントローラ・読み戻し確認・前後のコマンドを表示")
+	cmd.Flags().BoolVar(&watch, "watch", false, "Ctrl-Cで止めるまでフォアグラウンドで繰り返し適用し、毎回の結果を表示 (スケジューラは起動しない)")
+	cmd.Flags().DurationVar(&watchInterval, "interval", 0, "--watch の適用間隔 例:30s (省略時は設定ファイルの値)")
+	return cmd
+}
+
+// watchApply applies volume with apply every interval, starting at once,
+// and prints each result until interrupted. Failures are printed and the
+// loop goes on, as the scheduler would.
+func watchApply(uc usecase.SchedulerUseCase, apply func(int) error, volume int, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("%s ごとに適用します (Ctrl-Cで終了)\n", interval)
+	applies, failures := 0, 0
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now().Format(time.RFC3339)
+		plan, err := uc.PlanApply(volume)
+		if err == nil {
+			err = apply(volume)
+		}
+		applies++
+		if err != nil {
+			failures++
+			fmt.Printf("%s  failed: %v\n", now, err)
+		} else {
+			fmt.Printf("%s  applied %d\n", now, plan.Volume)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("applies: %d, failures: %d\n", applies, failures)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// printApplyPlan shows what apply would do, as computed by PlanApply.
+func printApplyPlan(p domain.ApplyPlan, respectEnabled bool) {
+	volume := fmt.Sprintf("%d (%s)", p.Volume, p.Source)
+	if p.Raised {
+		volume += ", raised to minTargetVolume"
+	}
+	fmt.Printf("  %-12s %s\n", "volume", volume)
+	fmt.Printf("  %-12s %s\n", "controller", p.Controller)
+	if p.Device != "" {
+		fmt.Printf("  %-12s %s\n", "device", p.Device)
+	}
+	if p.Ramp.Duration > 0 {
+		fmt.Printf("  %-12s %s\n", "ramp", p.Ramp)
+	}
+	if p.Verify {
+		fmt.Printf("  %-12s read back, tolerance %d\n", "verify", p.Tolerance)
+	} else {
+		fmt.Printf("  %-12s off\n", "verify")
+	}
+	if p.PreApplyCmd != "" {
+		pre := p.PreApplyCmd
+		if p.AbortOnPreApplyFailure {
+			pre += " (aborts on failure)"
+		}
+		fmt.Printf("  %-12s %s\n", "pre-apply", pre)
+	}
+	if p.PostApplyCmd != "" {
+		fmt.Printf("  %-12s %s\n", "post-apply", p.PostApplyCmd)
+	}
+	fmt.Println()
+	if respectEnabled && !p.Enabled {
+		fmt.Println("計画: スケジューラが無効のため適用しません")
+		return
+	}
+	fmt.Printf("計画: 音量 %d を適用します (まだ適用していません)\n", p.Volume)
+}
+
+func newHistoryCmd() *cobra.Command {
+	var (
+		sinceFlag   string
+		limitFlag   int
+		offsetFlag  int
+		statusFlag  string
+		triggerFlag string
+		formatFlag  string
+	)
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "適用履歴を新しい順に表示",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			history, err := repository.NewFileHistoryRepository(repository.HistoryPath(cfgPath))
+			if err != nil {
+				return err
+			}
+
+			q := domain.HistoryQuery{Limit: limitFlag, Offset: offsetFlag}
+			if sinceFlag != "" {
+				since, err := time.Parse(time.RFC3339, sinceFlag)
+				if err != nil {
+					return fmt.Errorf("--since にはRFC3339形式の日時を指定してください: %w", err)
+				}
+				q.Since = since
+			}
+			if statusFlag != "" {
+				status, err := domain.ParseApplyStatus(statusFlag)
+				if err != nil {
+					return err
+				}
+				q.Status = &status
+			}
+			if triggerFlag != "" {
+				trigger, err := domain.ParseApplyTrigger(triggerFlag)
+				if err != nil {
+					return err
+				}
+				q.Trigger = &trigger
+			}
+
+			records, total, err := history.Query(q)
+			if err != nil {
+				return err
+			}
+
+			switch formatFlag {
+			case "text":
+			case "csv":
+				return writeHistoryCSV(os.Stdout, records)
+			default:
+				return fmt.Errorf("--format には text または csv を指定してください")
+			}
+
+			for _, r := range records {
+				line := fmt.Sprintf("%s  volume=%-3d %-5s %-9s", r.Timestamp.Local().Format(time.RFC3339), r.Volume, r.Status, r.Trigger)
+				if r.Observed != nil && *r.Observed != r.Volume {
+					line += fmt.Sprintf("  observed=%d", *r.Observed)
+				}
+				if r.DryRun {
+					line += "  (dry run)"
+				}
+				if r.Error != "" {
+					line += "  " + r.Error
+				}
+				if r.Warning != "" {
+					line += "  warning: " + r.Warning
+				}
+				fmt.Println(line)
+			}
+			fmt.Printf("%d/%d 件を表示 (offset=%d)\n", len(records), total, offsetFlag)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&sinceFlag, "since", "", "この日時(RFC3339)以降の履歴のみ表示")
+	cmd.Flags().IntVar(&limitFlag, "limit", 50, "表示する最大件数")
+	cmd.Flags().IntVar(&offsetFlag, "offset", 0, "先頭から読み飛ばす件数")
+	cmd.Flags().StringVar(&statusFlag, "status", "", "ok/error で絞り込み")
+	cmd.Flags().StringVar(&triggerFlag, "trigger", "", "適用のきっかけで絞り込み (scheduled/manual/config/lock/unlock/power/wake)")
+	cmd.Flags().StringVar(&formatFlag, "format", "text", "出力形式 text / csv (表計算ソフト向け、ヘッダー付き)")
+	return cmd
+}
+
+// writeHistoryCSV writes records with a header row.
+func writeHistoryCSV(out io.Writer, records []domain.ApplyRecord) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(domain.HistoryCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := w.Write(domain.HistoryCSVRow(r)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func newVerifyStateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-state",
+		Short: "適用履歴から再構築した状態と保存済みの状態を比較",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repo, err := newRepository()
+			if err != nil {
+				return err
+			}
+			config, state, err := repo.Load()
+			if err != nil {
+				return err
+			}
+			history, err := repository.NewFileHistoryRepository(repository.HistoryPath(cfgPath))
+			if err != nil {
+				return err
+			}
+
+			// Count first, then read everything; Query returns newest first
+			_, total, err := history.Query(domain.HistoryQuery{})
+			if err != nil {
+				return err
+			}
+			records, _, err := history.Query(domain.HistoryQuery{Limit: total})
+			if err != nil {
+				return err
+			}
+			slices.Reverse(records)
+
+			service := domain.NewSchedulerService()
+			expected := service.ReplayHistory(config, records)
+			fmt.Printf("履歴 %d 件: 最終結果=%s 連続失敗=%d\n", total, expected.LastApplyStatus, expected.ConsecutiveFailures)
+
+			diffs := service.StateDivergence(expected, state)
+			if len(diffs) == 0 {
+				fmt.Println("保存済みの状態は履歴と一致しています")
+				return nil
+			}
+			for _, d := range diffs {
+				fmt.Println("  " + d)
+			}
+			return fmt.Errorf("保存済みの状態が履歴と %d 項目で食い違っています", len(diffs))
+		},
+	}
+}
+
+func newStateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "state",
+		Short: "保存済みの実行状態を操作",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "最後の適用結果・エラー・連続失敗回数(バックオフ)だけを未実行の状態に戻す (設定はそのまま)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uc, err := newUseCase()
+			if err != nil {
+				return err
+			}
+			if err := uc.ResetState(); err != nil {
+				return err
+			}
+			fmt.Println("適用結果とエラーの表示をリセットしました")
+			return nil
+		},
+	})
+	return cmd
+}
+
+func newWatchVolumeCmd() *cobra.Command {
+	var (
+		pollFlag      time.Duration
+		frontmostFlag bool
+	)
+	cmd := &cobra.Command{
+		Use:   "watch-volume",
+		Short: "入力音量を高頻度で読み取り、外部からの変更を記録する診断コマンド",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if pollFlag <= 0 {
+				return errors.New("--poll には正の時間を指定してください")
+			}
+			controller, err := newController()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			last, err := controller.GetVolume()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s  volume=%d (監視開始、Ctrl-Cで終了)\n", time.Now().Format(time.RFC3339), last)
+
+			ticker := time.NewTicker(pollFlag)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+					current, err := controller.GetVolume()
+					if err != nil {
+						logging.Warnf("read volume: %v", err)
+						continue
+					}
+					if current == last {
+						continue
+					}
+					line := fmt.Sprintf("%s  volume=%d (%+d)", time.Now().Format(time.RFC3339), current, current-last)
+					if frontmostFlag {
+						if app, err := volume.FrontmostApp(); err == nil {
+							line += "  frontmost=" + app
+						}
+					}
+					fmt.Println(line)
+					last = current
+				}
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&pollFlag, "poll", 500*time.Millisecond, "読み取り間隔")
+	cmd.Flags().BoolVar(&frontmostFlag, "frontmost", false, "変更検知時に最前面のアプリ名も表示")
 	return cmd
 }
 
@@ -327,6 +1681,7 @@ func runInteractiveShell(prompt string) error {
 	defer rl.Close()
 
 	sessionVerbosity := verbosity
+	sessionConfig := cfgPath
 	fmt.Println("対話型シェルを開始します。'help' で使い方、'exit' で終了。")
 
 	for {
@@ -365,28 +1720,67 @@ func runInteractiveShell(prompt string) error {
 			}
 			continue
 		}
+		if tokens[0] == "use-config" {
+			if err := handleShellUseConfig(tokens[1:], &sessionConfig); err != nil {
+				fmt.Printf("use-config: %v\n", err)
+				continue
+			}
+			if sessionConfig != cfgPath {
+				rl.SetPrompt(fmt.Sprintf("[%s] %s", filepath.Base(sessionConfig), prompt))
+			} else {
+				rl.SetPrompt(prompt)
+			}
+			continue
+		}
 		if tokens[0] == "shell" {
 			fmt.Println("すでにシェル内です。他のコマンドを入力するか 'exit' で終了してください。")
 			continue
 		}
 
 		verbosity = sessionVerbosity
-		if err := executeArgs(tokens); err != nil {
+		if err := executeArgs(tokens, sessionConfig); err != nil {
 			fmt.Printf("command error: %v\n", err)
 		}
 		sessionVerbosity = verbosity
 	}
 }
 
-func executeArgs(args []string) error {
+// executeArgs runs one shell command with the session's config path, which
+// an explicit --config in args still overrides.
+func executeArgs(args []string, configPath string) error {
 	if len(args) == 0 {
 		return nil
 	}
 	root := NewRootCmd()
+	if err := root.PersistentFlags().Set("config", configPath); err != nil {
+		return err
+	}
 	root.SetArgs(args)
 	return root.Execute()
 }
 
+// handleShellUseConfig switches the config file for the rest of the session.
+func handleShellUseConfig(args []string, sessionConfig *string) error {
+	switch len(args) {
+	case 0:
+		fmt.Printf("config: %s\n", *sessionConfig)
+		return nil
+	case 1:
+	default:
+		return errors.New("usage: use-config [<path>]")
+	}
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("%s はまだ存在しません。最初の保存で作成されます\n", path)
+	}
+	*sessionConfig = path
+	fmt.Printf("config: %s\n", path)
+	return nil
+}
+
 func handleShellLog(args []string, sessionVerbosity *int) error {
 	fs := pflag.NewFlagSet("log", pflag.ContinueOnError)
 	fs.SetOutput(io.Discard)
@@ -430,8 +1824,13 @@ func printShellHelp() {
   serve --addr 0.0.0.0:8080   # Web UI + スケジューラを起動
   config get                  # 設定を確認
   config set --volume 70      # 設定を更新
+  config profile use calls    # プロファイルを切り替え
   apply --volume 45           # 即時適用のみ実施
+  history --status error      # 適用履歴を確認
+  lock --volume 60 / unlock   # 音量を固定 / 解除
   log -vv                     # ログ出力を詳細化
   log --show                  # 現在のログレベルを確認
+  use-config ./work.json      # 以降のコマンドで使う設定ファイルを切り替え
+  use-config                  # 現在の設定ファイルを表示
   exit / quit                 # シェル終了`)
 }
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+func TestConfigDisplayNextRun(t *testing.T) {
+	t.Setenv("LC_ALL", "C")
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	applied := now.Add(-30 * time.Second)
+	tests := []struct {
+		name     string
+		enabled  bool
+		state    domain.ScheduleState
+		wantNext time.Time
+		wantRel  string
+	}{
+		{"saved next run", true, domain.ScheduleState{LastApplied: applied, NextRun: now.Add(5 * time.Minute)}, now.Add(5 * time.Minute), "in 5 minutes"},
+		{"from last applied", true, domain.ScheduleState{LastApplied: applied}, applied.Add(90 * time.Second), "in 1 minute"},
+		{"never applied", true, domain.ScheduleState{}, time.Time{}, ""},
+		{"disabled", false, domain.ScheduleState{LastApplied: applied}, time.Time{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := domain.DefaultConfig()
+			config.Enabled = tt.enabled
+			display := configDisplay(config, tt.state, now)
+
+			if display["idle"] != true {
+				t.Errorf("idle = %v, want true", display["idle"])
+			}
+			next, ok := display["nextRun"]
+			if tt.wantNext.IsZero() {
+				if ok {
+					t.Errorf("nextRun = %v, want none", next)
+				}
+				return
+			}
+			if want := tt.wantNext.Local().Format(time.RFC3339); next != want {
+				t.Errorf("nextRun = %v, want %s", next, want)
+			}
+			if got := display["nextRunRelative"]; got != tt.wantRel {
+				t.Errorf("nextRunRelative = %v, want %s", got, tt.wantRel)
+			}
+		})
+	}
+}
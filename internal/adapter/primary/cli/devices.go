@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func newDevicesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "devices",
+		Short: "入力デバイスの確認",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "入力デバイスの一覧を表示 (deviceName に指定する名前の確認用)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uc, err := newUseCase()
+			if err != nil {
+				return err
+			}
+			devices, err := uc.ListInputDevices()
+			if err != nil {
+				return err
+			}
+			if len(devices) == 0 {
+				fmt.Println("入力デバイスが見つかりません")
+				return nil
+			}
+
+			pinned := uc.GetSnapshot().Config.DeviceName
+			fmt.Printf("%-3s %-8s %-9s %-6s %s\n", "ID", "DEFAULT", "SELECTED", "RATE", "NAME")
+			for _, d := range devices {
+				isDefault, selected := "", ""
+				if d.Default {
+					isDefault = "*"
+				}
+				if d.Name == pinned {
+					selected = "*"
+				}
+				rate := "-"
+				if d.SampleRate > 0 {
+					rate = strconv.Itoa(d.SampleRate)
+				}
+				fmt.Printf("%-3d %-8s %-9s %-6s %s\n", d.ID, isDefault, selected, rate, d.Name)
+			}
+			return nil
+		},
+	})
+	return cmd
+}
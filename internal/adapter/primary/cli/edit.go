@@ -0,0 +1,325 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/usecase"
+)
+
+// editableConfig is the document opened in the editor by config edit.
+// Durations, the curve and quiet hours use the same notation as the config
+// set flags.
+type editableConfig struct {
+	TargetVolume     int            `json:"targetVolume"`
+	Interval         string         `json:"interval"`
+	Enabled          bool           `json:"enabled"`
+	ScheduleMode     string         `json:"scheduleMode"`
+	Schedule         string         `json:"schedule"`
+	Timezone         string         `json:"timezone"`
+	AdaptiveInterval bool           `json:"adaptiveInterval"`
+	MaxInterval      string         `json:"maxInterval"`
+	Reschedule       bool           `json:"rescheduleOnManualApply"`
+	MinTargetVolume  int            `json:"minTargetVolume"`
+	ErrorThreshold   int            `json:"errorThreshold"`
+	MaxRetries       int            `json:"maxRetries"`
+	RetryBackoff     string         `json:"retryBackoff"`
+	RampDuration     string         `json:"rampDuration"`
+	RampSteps        int            `json:"rampSteps"`
+	Stagger          string         `json:"staggerBetweenDevices"`
+	DriftAlert       int            `json:"driftAlertThreshold"`
+	RedactErrors     bool           `json:"redactErrors"`
+	DeviceName       string         `json:"deviceName"`
+	OnDeviceAbsent   string         `json:"onDeviceAbsent"`
+	FormatVolumes    string         `json:"formatVolumes"`
+	ParkVolume       *int           `json:"parkVolume"`
+	FadeOnPark       bool           `json:"fadeOnPark"`
+	ReapplyOnPower   bool           `json:"reapplyOnPowerChange"`
+	PowerPoll        string         `json:"powerPoll"`
+	AllowedVolumes   []int          `json:"allowedVolumes"`
+	AppVolumes       string         `json:"appVolumes"`
+	Output           editableOutput `json:"output"`
+	DryRun           bool           `json:"dryRun"`
+	Noise            editableNoise  `json:"noise"`
+	Curve            string         `json:"curve"`
+	QuietHours       string         `json:"quietHours"`
+}
+
+type editableOutput struct {
+	Enabled bool `json:"enabled"`
+	Volume  int  `json:"volume"`
+}
+
+type editableNoise struct {
+	Enabled       bool    `json:"enabled"`
+	ReferenceDBFS float64 `json:"referenceDbfs"`
+	MinVolume     int     `json:"minVolume"`
+	MaxVolume     int     `json:"maxVolume"`
+}
+
+func newConfigEditCmd() *cobra.Command {
+	var applyNow bool
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "現在の設定を$EDITORで開いて編集 (保存時に検証)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uc, err := newUseCase()
+			if err != nil {
+				return err
+			}
+			base := uc.GetSnapshot().Config
+
+			original, err := marshalEditable(base)
+			if err != nil {
+				return err
+			}
+			stdin := bufio.NewReader(os.Stdin)
+			content := original
+			for {
+				edited, err := runEditor(content)
+				if err != nil {
+					return err
+				}
+				if bytes.Equal(bytes.TrimSpace(edited), bytes.TrimSpace(original)) {
+					fmt.Println("変更がないため保存しません")
+					return nil
+				}
+
+				config, err := unmarshalEditable(base, edited)
+				if err == nil {
+					err = config.Validate()
+				}
+				if err == nil {
+					return saveEdited(uc, config, applyNow)
+				}
+
+				fmt.Fprintf(os.Stderr, "設定が不正です: %v\n", err)
+				if !confirm(stdin, "再編集しますか? [Y/n] ") {
+					return errors.New("編集を中止しました")
+				}
+				content = edited
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&applyNow, "apply-now", false, "保存後ただちに適用")
+	return cmd
+}
+
+func saveEdited(uc usecase.SchedulerUseCase, config domain.Config, applyNow bool) error {
+	if err := uc.UpdateConfig(config, applyNow); err != nil {
+		return err
+	}
+	fmt.Printf("保存しました: volume=%d interval=%s enabled=%t\n",
+		config.TargetVolume, config.Interval, config.Enabled)
+	if applyNow {
+		fmt.Println("適用完了")
+	}
+	return nil
+}
+
+func marshalEditable(config domain.Config) ([]byte, error) {
+	data, err := json.MarshalIndent(editableConfig{
+		TargetVolume:     config.TargetVolume,
+		Interval:         config.Interval.String(),
+		Enabled:          config.Enabled,
+		ScheduleMode:     config.ScheduleMode.String(),
+		Schedule:         config.Schedule,
+		Timezone:         config.Timezone,
+		AdaptiveInterval: config.AdaptiveInterval,
+		Reschedule:       config.RescheduleOnManualApply,
+		MaxInterval:      config.MaxInterval.String(),
+		MinTargetVolume:  config.MinTargetVolume,
+		ErrorThreshold:   config.ErrorThreshold,
+		MaxRetries:       config.MaxRetries,
+		RetryBackoff:     config.RetryBackoff.String(),
+		RampDuration:     config.RampDuration.String(),
+		RampSteps:        config.RampSteps,
+		Stagger:          config.StaggerBetweenDevices.String(),
+		DriftAlert:       config.DriftAlertThreshold,
+		RedactErrors:     config.RedactErrors,
+		DeviceName:       config.DeviceName,
+		OnDeviceAbsent:   config.OnDeviceAbsent.String(),
+		FormatVolumes:    domain.FormatFormatVolumes(config.FormatVolumes),
+		ParkVolume:       config.ParkVolume,
+		FadeOnPark:       config.FadeOnPark,
+		ReapplyOnPower:   config.ReapplyOnPowerChange,
+		PowerPoll:        config.PowerPollInterval.String(),
+		AllowedVolumes:   config.AllowedVolumes,
+		AppVolumes:       domain.FormatAppVolumes(config.AppVolumes),
+		Output:           editableOutput(config.Output),
+		DryRun:           config.DryRun,
+		Noise:            editableNoise(config.Noise),
+		Curve:            domain.FormatCurve(config.Curve),
+		QuietHours:       domain.FormatQuietHours(config.QuietHours),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// unmarshalEditable applies an edited document on top of base, so that
+// settings not shown in the editor (such as profiles) are kept.
+func unmarshalEditable(base domain.Config, data []byte) (domain.Config, error) {
+	var edited editableConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&edited); err != nil {
+		return domain.Config{}, fmt.Errorf("parse: %w", err)
+	}
+
+	interval, err := time.ParseDuration(edited.Interval)
+	if err != nil {
+		return domain.Config{}, fmt.Errorf("interval: %w", err)
+	}
+	maxInterval, err := time.ParseDuration(edited.MaxInterval)
+	if err != nil {
+		return domain.Config{}, fmt.Errorf("maxInterval: %w", err)
+	}
+	curve, err := domain.ParseCurve(edited.Curve)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	quiet, err := domain.ParseQuietHours(edited.QuietHours)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	appVolumes, err := domain.ParseAppVolumes(edited.AppVolumes)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	mode, err := domain.ParseScheduleMode(edited.ScheduleMode)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	absent, err := domain.ParseDeviceAbsentPolicy(edited.OnDeviceAbsent)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	formatVolumes, err := domain.ParseFormatVolumes(edited.FormatVolumes)
+	if err != nil {
+		return domain.Config{}, err
+	}
+	powerPoll, err := time.ParseDuration(edited.PowerPoll)
+	if err != nil {
+		return domain.Config{}, fmt.Errorf("powerPoll: %w", err)
+	}
+	retryBackoff, err := time.ParseDuration(edited.RetryBackoff)
+	if err != nil {
+		return domain.Config{}, fmt.Errorf("retryBackoff: %w", err)
+	}
+	rampDuration, err := time.ParseDuration(edited.RampDuration)
+	if err != nil {
+		return domain.Config{}, fmt.Errorf("rampDuration: %w", err)
+	}
+	stagger, err := time.ParseDuration(edited.Stagger)
+	if err != nil {
+		return domain.Config{}, fmt.Errorf("staggerBetweenDevices: %w", err)
+	}
+
+	config := base
+	config.TargetVolume = edited.TargetVolume
+	config.Interval = interval
+	config.Enabled = edited.Enabled
+	config.ScheduleMode = mode
+	config.Schedule = edited.Schedule
+	config.Timezone = edited.Timezone
+	config.AdaptiveInterval = edited.AdaptiveInterval
+	config.MaxInterval = maxInterval
+	config.RescheduleOnManualApply = edited.Reschedule
+	config.MinTargetVolume = edited.MinTargetVolume
+	config.ErrorThreshold = edited.ErrorThreshold
+	config.MaxRetries = edited.MaxRetries
+	config.RetryBackoff = retryBackoff
+	config.RampDuration = rampDuration
+	config.RampSteps = edited.RampSteps
+	config.StaggerBetweenDevices = stagger
+	config.DriftAlertThreshold = edited.DriftAlert
+	config.RedactErrors = edited.RedactErrors
+	config.DeviceName = edited.DeviceName
+	config.OnDeviceAbsent = absent
+	config.FormatVolumes = formatVolumes
+	config.ParkVolume = edited.ParkVolume
+	config.FadeOnPark = edited.FadeOnPark
+	config.ReapplyOnPowerChange = edited.ReapplyOnPower
+	config.PowerPollInterval = powerPoll
+	config.AllowedVolumes = edited.AllowedVolumes
+	config.AppVolumes = appVolumes
+	config.Output = domain.OutputLock(edited.Output)
+	config.DryRun = edited.DryRun
+	config.Noise = domain.NoiseControl(edited.Noise)
+	config.Curve = curve
+	config.QuietHours = quiet
+	return config, nil
+}
+
+// runEditor opens content in $VISUAL or $EDITOR (vi by default) and returns
+// the saved result.
+func runEditor(content []byte) ([]byte, error) {
+	file, err := os.CreateTemp("", "micgain-config-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("create temp file: %w", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("write temp file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("write temp file: %w", err)
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	argv, err := shlex.Split(editor)
+	if err != nil || len(argv) == 0 {
+		return nil, fmt.Errorf("invalid editor %q", editor)
+	}
+
+	cmd := exec.Command(argv[0], append(argv[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run editor: %w", err)
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read temp file: %w", err)
+	}
+	return edited, nil
+}
+
+// confirm asks a yes/no question, defaulting to yes. EOF counts as no.
+func confirm(r *bufio.Reader, prompt string) bool {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, err := r.ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
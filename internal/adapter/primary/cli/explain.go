@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/domain"
+)
+
+func newExplainCmd() *cobra.Command {
+	var serverURL string
+	cmd := &cobra.Command{
+		Use:   "explain",
+		Short: "スケジューラが今適用するかどうかの判定を要因ごとに表示",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				decision domain.ApplyDecision
+				err      error
+			)
+			if serverURL != "" {
+				decision, err = fetchDecision(cmd.Context(), serverURL)
+			} else {
+				decision, err = loadDecision()
+			}
+			if err != nil {
+				return err
+			}
+			printDecision(decision)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&serverURL, "server", "", "起動中のサーバーのURL 例:http://127.0.0.1:7070 (省略時は設定ファイルの状態から判定)")
+	return cmd
+}
+
+// loadDecision explains the decision for the state saved on disk, which a
+// running daemon updates after every apply.
+func loadDecision() (domain.ApplyDecision, error) {
+	repo, err := newRepository()
+	if err != nil {
+		return domain.ApplyDecision{}, err
+	}
+	config, state, err := repo.Load()
+	if err != nil {
+		return domain.ApplyDecision{}, err
+	}
+	return domain.NewSchedulerService().ExplainApply(state, config, time.Now()), nil
+}
+
+// fetchDecision asks a running server for its live decision.
+func fetchDecision(ctx context.Context, serverURL string) (domain.ApplyDecision, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	url := strings.TrimSuffix(serverURL, "/") + "/api/explain"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return domain.ApplyDecision{}, err
+	}
+	authorizeRequest(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return domain.ApplyDecision{}, fmt.Errorf("サーバーに接続できません: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return domain.ApplyDecision{}, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	var view struct {
+		Apply   bool `json:"apply"`
+		Factors []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			Gated bool   `json:"gated"`
+		} `json:"factors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&view); err != nil {
+		return domain.ApplyDecision{}, fmt.Errorf("GET %s: %w", url, err)
+	}
+	decision := domain.ApplyDecision{Apply: view.Apply}
+	for _, f := range view.Factors {
+		decision.Factors = append(decision.Factors, domain.DecisionFactor{Name: f.Name, Value: f.Value, Gated: f.Gated})
+	}
+	return decision, nil
+}
+
+// printDecision lists each factor, marking the ones that block an apply.
+func printDecision(d domain.ApplyDecision) {
+	for _, f := range d.Factors {
+		mark := " "
+		if f.Gated {
+			mark = "✗"
+		}
+		fmt.Printf("%s %-12s %s\n", mark, f.Name, f.Value)
+	}
+	fmt.Println()
+	if d.Apply {
+		fmt.Println("判定: 次のtickで適用します")
+		return
+	}
+	fmt.Printf("判定: 適用しません (要因: %s)\n", strings.Join(d.Blocking(), ", "))
+}
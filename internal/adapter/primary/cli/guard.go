@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/adapter/secondary/repository"
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/usecase"
+)
+
+func newGuardCmd() *cobra.Command {
+	var (
+		interval time.Duration
+		duration time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "guard",
+		Short: "指定した時間だけフォアグラウンドで音量を維持して終了 (デーモン不要)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if duration <= 0 {
+				return fmt.Errorf("--duration には正の時間を指定してください")
+			}
+			// Overrides go through the env layer, so they last for this run
+			// only and are never written to the config file
+			if cmd.Flags().Changed("interval") {
+				if interval < domain.MinInterval {
+					return domain.ErrInvalidInterval
+				}
+				os.Setenv(repository.EnvInterval, interval.String())
+			}
+			os.Setenv(repository.EnvEnabled, "true")
+
+			uc, err := newUseCase()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			ctx, cancel := context.WithTimeout(ctx, duration)
+			defer cancel()
+
+			start := time.Now()
+			snap := uc.GetSnapshot()
+			fmt.Printf("guard started: every %s until %s (Ctrl-C to stop)\n",
+				domain.NewSchedulerService().EffectiveInterval(snap.ScheduleState, snap.Config),
+				start.Add(duration).Format("15:04:05"))
+
+			// Correct the volume at once rather than one interval from now
+			plan, err := uc.PlanApply(-1)
+			if err == nil {
+				err = uc.ApplyNow(-1)
+			}
+			if err != nil {
+				fmt.Printf("first apply failed: %v\n", err)
+			} else {
+				fmt.Printf("applied %d\n", plan.Volume)
+			}
+			uc.Start(ctx)
+
+			<-ctx.Done()
+			fmt.Printf("guard stopped after %s\n", time.Since(start).Round(time.Second))
+			return printGuardSummary(uc, start)
+		},
+	}
+	cmd.Flags().DurationVar(&interval, "interval", 0, "この実行の間だけ使う再適用インターバル (省略時は設定ファイルの値)")
+	cmd.Flags().DurationVar(&duration, "duration", time.Hour, "音量を維持する時間 例:30m,2h")
+	return cmd
+}
+
+// printGuardSummary reports the applies recorded in the history since start.
+func printGuardSummary(uc usecase.SchedulerUseCase, start time.Time) error {
+	// History timestamps are kept to the second
+	q := domain.HistoryQuery{Since: start.Truncate(time.Second)}
+	_, total, err := uc.QueryHistory(q)
+	if err != nil {
+		return err
+	}
+	q.Limit = total
+	records, _, err := uc.QueryHistory(q)
+	if err != nil {
+		return err
+	}
+
+	failures, drift := 0, 0
+	for _, r := range records {
+		if r.Status == domain.StatusError {
+			failures++
+		}
+		if r.SignificantDrift {
+			drift++
+		}
+	}
+	fmt.Printf("applies: %d\n", len(records))
+	fmt.Printf("failures: %d\n", failures)
+	if uc.GetSnapshot().Config.DriftAlertThreshold > 0 {
+		fmt.Printf("drift corrected: %d\n", drift)
+	} else {
+		fmt.Println("drift corrected: - (driftAlertThreshold が0のため未計測)")
+	}
+	return nil
+}
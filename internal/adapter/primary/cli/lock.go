@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/usecase"
+)
+
+// errLoopRunning refuses a hold set from this process while another one
+// runs the scheduler loop: the loop keeps its own state and would never
+// see the hold, and overwrite it on its next save.
+var errLoopRunning = errors.New("スケジューラが動作中のため、このプロセスからの固定は反映されません。--server で動作中のサーバーに送るか、daemon を停止してから実行してください")
+
+func newLockCmd() *cobra.Command {
+	var (
+		volumeFlag int
+		serverURL  string
+		force      bool
+	)
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "指定音量を適用し、unlockするまで固定（設定変更も保留）",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("volume") {
+				return errors.New("--volume を指定してください")
+			}
+			if serverURL != "" {
+				body, _ := json.Marshal(map[string]int{"volume": volumeFlag})
+				if err := sendLock(cmd.Context(), serverURL, http.MethodPost, body); err != nil {
+					return err
+				}
+			} else {
+				uc, err := newLockUseCase(force)
+				if err != nil {
+					return err
+				}
+				if err := uc.Hold(volumeFlag); err != nil {
+					return err
+				}
+			}
+			fmt.Printf("音量を %d で固定しました。解除は unlock を実行してください\n", volumeFlag)
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&volumeFlag, "volume", 0, "固定する音量(0-100)")
+	registerLockFlags(cmd, &serverURL, &force)
+	return cmd
+}
+
+func newUnlockCmd() *cobra.Command {
+	var (
+		serverURL string
+		force     bool
+	)
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "lockによる音量固定を解除",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serverURL != "" {
+				if err := sendLock(cmd.Context(), serverURL, http.MethodDelete, nil); err != nil {
+					return err
+				}
+			} else {
+				uc, err := newLockUseCase(force)
+				if err != nil {
+					return err
+				}
+				if err := uc.Release(); err != nil {
+					return err
+				}
+			}
+			fmt.Println("固定を解除しました")
+			return nil
+		},
+	}
+	registerLockFlags(cmd, &serverURL, &force)
+	return cmd
+}
+
+func registerLockFlags(cmd *cobra.Command, serverURL *string, force *bool) {
+	cmd.Flags().StringVar(serverURL, "server", "", "起動中のサーバーのURL 例:http://127.0.0.1:7070 (web/serveの動作中はこちらに送る)")
+	cmd.Flags().BoolVar(force, "force", false, "スケジューラの動作中と記録されていても実行 (異常終了で記録が残った場合用)")
+}
+
+// newLockUseCase returns the use case for a hold set from this process,
+// refusing while the saved state shows a scheduler loop running elsewhere
+// unless forced.
+func newLockUseCase(force bool) (usecase.SchedulerUseCase, error) {
+	uc, err := newUseCase()
+	if err != nil {
+		return nil, err
+	}
+	if !force && uc.GetSnapshot().ScheduleState.Running != nil {
+		return nil, errLoopRunning
+	}
+	return uc, nil
+}
+
+// sendLock sets (POST) or clears (DELETE) the hold of a running server.
+func sendLock(ctx context.Context, serverURL, method string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	url := strings.TrimSuffix(serverURL, "/") + "/api/lock"
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	authorizeRequest(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("サーバーに接続できません: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// justNow is how close to now a time is shown as "just now".
+const justNow = 5 * time.Second
+
+// relativeUnits are tried from largest to smallest.
+var relativeUnits = []struct {
+	size     time.Duration
+	en, ja   string
+	enPlural string
+}{
+	{24 * time.Hour, "day", "日", "days"},
+	{time.Hour, "hour", "時間", "hours"},
+	{time.Minute, "minute", "分", "minutes"},
+	{time.Second, "second", "秒", "seconds"},
+}
+
+// formatRelative renders t relative to now, e.g. "2 minutes ago" or
+// "in 40 seconds", in Japanese when the locale asks for it.
+func formatRelative(t, now time.Time) string {
+	return formatRelativeLang(t, now, localeIsJapanese())
+}
+
+func formatRelativeLang(t, now time.Time, japanese bool) string {
+	d := t.Sub(now)
+	future := d > 0
+	if !future {
+		d = -d
+	}
+	if d < justNow {
+		if japanese {
+			return "たった今"
+		}
+		return "just now"
+	}
+
+	for _, u := range relativeUnits {
+		if d < u.size {
+			continue
+		}
+		n := int(d / u.size)
+		if japanese {
+			if future {
+				return fmt.Sprintf("%d%s後", n, u.ja)
+			}
+			return fmt.Sprintf("%d%s前", n, u.ja)
+		}
+		unit := u.en
+		if n != 1 {
+			unit = u.enPlural
+		}
+		if future {
+			return fmt.Sprintf("in %d %s", n, unit)
+		}
+		return fmt.Sprintf("%d %s ago", n, unit)
+	}
+	return "just now"
+}
+
+// localeIsJapanese follows the POSIX precedence of the locale variables.
+func localeIsJapanese() bool {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(key); v != "" {
+			return strings.HasPrefix(v, "ja")
+		}
+	}
+	return false
+}
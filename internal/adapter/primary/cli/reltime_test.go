@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatRelative(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		offset time.Duration
+		en, ja string
+	}{
+		{"now", 0, "just now", "たった今"},
+		{"just past", -4 * time.Second, "just now", "たった今"},
+		{"just ahead", 4 * time.Second, "just now", "たった今"},
+		{"past the just now window", -5 * time.Second, "5 seconds ago", "5秒前"},
+		{"future seconds", 40 * time.Second, "in 40 seconds", "40秒後"},
+		{"one minute", -time.Minute, "1 minute ago", "1分前"},
+		{"rounds down", -119 * time.Second, "1 minute ago", "1分前"},
+		{"future hours", 2*time.Hour + 59*time.Minute, "in 2 hours", "2時間後"},
+		{"long ago", -400 * 24 * time.Hour, "400 days ago", "400日前"},
+		{"far future", 3 * 24 * time.Hour, "in 3 days", "3日後"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := now.Add(tt.offset)
+			if got := formatRelativeLang(at, now, false); got != tt.en {
+				t.Errorf("English = %q, want %q", got, tt.en)
+			}
+			if got := formatRelativeLang(at, now, true); got != tt.ja {
+				t.Errorf("Japanese = %q, want %q", got, tt.ja)
+			}
+		})
+	}
+}
+
+func TestLocaleIsJapanese(t *testing.T) {
+	tests := []struct {
+		all, messages, lang string
+		want                bool
+	}{
+		{"", "", "ja_JP.UTF-8", true},
+		{"", "", "en_US.UTF-8", false},
+		{"C", "", "ja_JP.UTF-8", false},
+		{"", "ja_JP.UTF-8", "en_US.UTF-8", true},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.all)
+		t.Setenv("LC_MESSAGES", tt.messages)
+		t.Setenv("LANG", tt.lang)
+		if got := localeIsJapanese(); got != tt.want {
+			t.Errorf("LC_ALL=%q LC_MESSAGES=%q LANG=%q: japanese = %v, want %v", tt.all, tt.messages, tt.lang, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/domain"
+)
+
+const defaultStatusTemplate = "mic:{{.Volume}} {{.Glyph}} {{.NextIn}}"
+
+// statusGlyphs are the markers used for the apply status in short output.
+type statusGlyphs struct {
+	OK, Error, Degraded, Never string
+}
+
+var (
+	unicodeGlyphs = statusGlyphs{OK: "✓", Error: "✗", Degraded: "!", Never: "·"}
+	asciiGlyphs   = statusGlyphs{OK: "OK", Error: "ERR", Degraded: "WARN", Never: "-"}
+)
+
+// statusLine is the data exposed to --template.
+type statusLine struct {
+	Volume  int
+	Target  int
+	Enabled bool
+	Locked  bool
+	Profile string
+	Status  string
+	Glyph   string
+	NextIn  string
+	// Next and Last are relative times, e.g. "in 40 seconds".
+	Next  string
+	Last  string
+	Error string
+	// Failures is the number of consecutive failed applies.
+	Failures int
+	// Restart lists saved settings the running loop has not picked up.
+	Restart string
+	// SuccessRate is the recent apply success rate, e.g. "98% over last 50".
+	SuccessRate string
+	// Observed is the volume read back before the last scheduled apply,
+	// or "-" when it has not been read.
+	Observed string
+	// Startup is the volume read back when the running loop started, or
+	// empty when no loop runs.
+	Startup string
+	// Quiet is when the active quiet hours end, e.g. "until 07:00", or
+	// empty outside quiet hours.
+	Quiet string
+	// Output is the locked output volume, or "-" when it is not locked.
+	Output string
+	// DryRun reports whether applies are only simulated.
+	DryRun bool
+	// Running reports whether a daemon or server runs the scheduler loop.
+	Running bool
+	// NextRun is when the next scheduled apply is due, zero when none is.
+	NextRun time.Time
+}
+
+func newStatusCmd() *cobra.Command {
+	var (
+		short    bool
+		ascii    bool
+		tmplText string
+		output   string
+	)
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "現在の状態を表示 (--shortでステータスバー向けの1行出力)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uc, err := newUseCase()
+			if err != nil {
+				return err
+			}
+			snap := uc.GetSnapshot()
+
+			glyphs := unicodeGlyphs
+			if ascii {
+				glyphs = asciiGlyphs
+			}
+			now := time.Now()
+			line := buildStatusLine(snap, glyphs, now)
+			restart, err := uc.RestartRequired()
+			if err == nil {
+				line.Restart = strings.Join(restart, ", ")
+			}
+
+			switch output {
+			case "table":
+			case "json":
+				if short || cmd.Flags().Changed("template") {
+					return fmt.Errorf("--output json は --short / --template と併用できません")
+				}
+				return writeStatusJSON(snap, line, restart, now)
+			default:
+				return fmt.Errorf("--output には table または json を指定してください")
+			}
+
+			if short || cmd.Flags().Changed("template") {
+				tmpl, err := template.New("status").Parse(tmplText)
+				if err != nil {
+					return fmt.Errorf("--template: %w", err)
+				}
+				if err := tmpl.Execute(os.Stdout, line); err != nil {
+					return err
+				}
+				fmt.Println()
+				return nil
+			}
+
+			fmt.Printf("volume:  %d (target %d)\n", line.Volume, line.Target)
+			if line.Observed != "-" {
+				fmt.Printf("observed: %s before the last scheduled apply\n", line.Observed)
+			}
+			fmt.Printf("enabled: %t\n", line.Enabled)
+			fmt.Printf("running: %t\n", line.Running)
+			if line.DryRun {
+				fmt.Println("dry run: true (applies are logged, not made)")
+			}
+			if line.Output != "-" {
+				fmt.Printf("output:  %s (locked)\n", line.Output)
+			}
+			statusText := fmt.Sprintf("status:  %s %s", line.Glyph, line.Status)
+			if line.Status == domain.StatusError.String() && colorOutput() {
+				statusText = ansiRed + statusText + ansiReset
+			}
+			fmt.Println(statusText)
+			fmt.Printf("last:    %s\n", line.Last)
+			fmt.Printf("next:    %s\n", line.Next)
+			if line.Quiet != "" {
+				fmt.Printf("quiet:   %s (no scheduled applies)\n", line.Quiet)
+			}
+			fmt.Printf("success: %s\n", line.SuccessRate)
+			if line.Startup != "" {
+				fmt.Printf("startup: %s\n", line.Startup)
+			}
+			if line.Profile != "" {
+				fmt.Printf("profile: %s\n", line.Profile)
+			}
+			if line.Locked {
+				fmt.Println("locked:  true")
+			}
+			if line.Error != "" {
+				fmt.Printf("error:   %s\n", line.Error)
+			}
+			if line.Failures > 1 {
+				fmt.Printf("failures: %d in a row (backing off)\n", line.Failures)
+			}
+			if line.Restart != "" {
+				fmt.Printf("restart required to apply: %s\n", line.Restart)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&output, "output", "table", "出力形式 table (人間向け) / json (スクリプト向け)")
+	cmd.Flags().BoolVar(&short, "short", false, "ステータスバー向けの1行で出力 例: mic:60 ✓ 34s")
+	cmd.Flags().BoolVar(&ascii, "ascii", false, "記号の代わりにASCII文字(OK/ERR/-)を使用")
+	cmd.Flags().StringVar(&tmplText, "template", defaultStatusTemplate,
+		"1行出力のGoテンプレート ({{.Volume}} {{.Target}} {{.Glyph}} {{.Status}} {{.NextIn}} {{.Next}} {{.Last}} {{.Profile}} {{.Locked}} {{.Error}} {{.Restart}} {{.SuccessRate}} {{.Observed}} {{.Startup}} {{.Quiet}} {{.Output}} {{.DryRun}} {{.Running}} {{.NextRun}})")
+	return cmd
+}
+
+// buildStatusLine flattens a snapshot into the fields shown by status.
+func buildStatusLine(snap domain.Snapshot, glyphs statusGlyphs, now time.Time) statusLine {
+	service := domain.NewSchedulerService()
+	state := snap.ScheduleState
+	status := service.ReportedStatus(state, snap.Config)
+	target, _ := service.ApplyFloor(snap.Config, service.ResolveTarget(state, snap.Config, now))
+
+	line := statusLine{
+		Volume:      target,
+		Target:      snap.Config.TargetVolume,
+		Enabled:     snap.Config.Enabled,
+		Locked:      state.Hold.Active,
+		Profile:     snap.Config.ActiveProfile,
+		Status:      status.String(),
+		NextIn:      "-",
+		Observed:    "-",
+		Output:      "-",
+		DryRun:      snap.DryRun,
+		Running:     state.Running != nil,
+		Next:        "-",
+		Last:        "-",
+		Failures:    state.ConsecutiveFailures,
+		SuccessRate: service.SuccessRate(state).String(),
+	}
+	if state.LastError != nil {
+		line.Error = state.LastError.Error()
+	}
+	if state.LastObservedVolume != nil {
+		line.Observed = strconv.Itoa(*state.LastObservedVolume)
+	}
+	if snap.Config.Output.Enabled {
+		line.Output = strconv.Itoa(snap.Config.Output.Volume)
+	}
+	if state.StartupCheck != nil {
+		line.Startup = state.StartupCheck.String()
+	}
+	if until := snap.Config.QuietUntil(now); !until.IsZero() {
+		line.Quiet = "until " + until.Local().Format("15:04")
+	}
+	if !state.LastApplied.IsZero() {
+		line.Last = formatRelative(state.LastApplied, now)
+	}
+
+	switch status {
+	case domain.StatusSuccess:
+		line.Glyph = glyphs.OK
+	case domain.StatusError:
+		line.Glyph = glyphs.Error
+	case domain.StatusDegraded:
+		line.Glyph = glyphs.Degraded
+	default:
+		line.Glyph = glyphs.Never
+	}
+
+	if service.Enforcing(state, snap.Config) {
+		nextRun := state.NextRun
+		if nextRun.IsZero() && !state.LastApplied.IsZero() {
+			// A snapshot loaded from disk has no NextRun; derive it
+			nextRun = service.CalculateNextRun(snap.Config, state.LastApplied, service.EffectiveInterval(state, snap.Config))
+		}
+		if !nextRun.IsZero() {
+			line.NextRun = nextRun
+			line.NextIn = formatCountdown(nextRun.Sub(now))
+			line.Next = formatRelative(nextRun, now)
+		}
+	}
+	return line
+}
+
+// formatCountdown renders a duration compactly, e.g. "34s" or "2m5s".
+func formatCountdown(d time.Duration) string {
+	if d <= 0 {
+		return "now"
+	}
+	return d.Round(time.Second).String()
+}
+
+// writeStatusJSON prints the status for scripts. Times are RFC 3339, with
+// the relative forms shown by the table alongside.
+func writeStatusJSON(snap domain.Snapshot, line statusLine, restart []string, now time.Time) error {
+	state := snap.ScheduleState
+	if restart == nil {
+		restart = []string{}
+	}
+	view := map[string]any{
+		"volume":              line.Volume,
+		"targetVolume":        line.Target,
+		"enabled":             line.Enabled,
+		"running":             line.Running,
+		"lastApplyStatus":     line.Status,
+		"locked":              line.Locked,
+		"dryRun":              line.DryRun,
+		"consecutiveFailures": line.Failures,
+		"successRate":         line.SuccessRate,
+		"restartRequired":     restart,
+	}
+	if !state.LastApplied.IsZero() {
+		view["lastApplied"] = state.LastApplied.Format(time.RFC3339)
+		view["lastAppliedRelative"] = line.Last
+	}
+	if !line.NextRun.IsZero() {
+		view["nextRun"] = line.NextRun.Format(time.RFC3339)
+		view["nextRunRelative"] = line.Next
+		view["nextRunInSeconds"] = max(0, int(line.NextRun.Sub(now).Seconds()))
+	}
+	if line.Error != "" {
+		view["lastError"] = line.Error
+	}
+	if line.Profile != "" {
+		view["activeProfile"] = line.Profile
+	}
+	if line.Quiet != "" {
+		view["quiet"] = line.Quiet
+	}
+	if state.LastObservedVolume != nil {
+		view["lastObservedVolume"] = *state.LastObservedVolume
+	}
+	if snap.Config.Output.Enabled {
+		view["outputVolume"] = snap.Config.Output.Volume
+	}
+
+	out, err := json.MarshalIndent(view, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+const (
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// colorOutput reports whether stdout is a terminal that should get
+// colors; NO_COLOR turns them off.
+func colorOutput() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
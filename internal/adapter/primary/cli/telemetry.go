@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/adapter/secondary/telemetry"
+	"micgain-manager/internal/logging"
+)
+
+// telemetryNotice is shown once, the first time a ping is enabled.
+const telemetryNotice = `匿名の利用状況通知が有効です。起動時に一度だけ、次の項目のみを送信します:
+  version    (バージョン)
+  os / arch  (OSとCPUアーキテクチャ)
+  controller (音量制御の方式)
+ホスト名・ユーザー名・IPアドレス・設定値・識別子は送信しません。無効にするには telemetry off を実行してください。`
+
+// telemetryFlags holds the opt-in startup ping options shared by daemon and serve.
+type telemetryFlags struct {
+	enabled bool
+	url     string
+}
+
+func (f *telemetryFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.enabled, "telemetry", false, "この起動に限り匿名の起動通知を送信 (既定は無効、内容は telemetry status で確認)")
+	cmd.Flags().StringVar(&f.url, "telemetry-url", "", "起動通知の送信先URL (telemetry on --url で保存した値より優先)")
+}
+
+// start sends the startup ping in the background when the user opted in.
+// It never fails the command: every problem is only logged at debug level.
+func (f *telemetryFlags) start(ctx context.Context) {
+	path := telemetry.SettingsPath(cfgPath)
+	settings, err := telemetry.LoadSettings(path)
+	if err != nil {
+		logging.Debugf("telemetry: %v", err)
+		return
+	}
+	if !f.enabled && !settings.Enabled {
+		return
+	}
+	endpoint := cmp.Or(f.url, settings.Endpoint)
+	if endpoint == "" {
+		logging.Debugf("telemetry: enabled but no endpoint is configured; nothing sent")
+		return
+	}
+	if !settings.NoticeShown {
+		fmt.Fprintln(os.Stderr, telemetryNotice)
+		settings.NoticeShown = true
+		if err := telemetry.SaveSettings(path, settings); err != nil {
+			logging.Debugf("telemetry: %v", err)
+		}
+	}
+
+	go func() {
+		if err := telemetry.Send(ctx, endpoint, startupPing()); err != nil {
+			logging.Debugf("telemetry: %v", err)
+			return
+		}
+		logging.Debugf("telemetry: startup ping sent to %s", endpoint)
+	}()
+}
+
+func startupPing() telemetry.Ping {
+	return telemetry.Ping{
+		Version:    Version,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Controller: controllerType,
+	}
+}
+
+func newTelemetryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "匿名の起動通知(オプトイン、既定は無効)の設定",
+	}
+
+	var url string
+	on := &cobra.Command{
+		Use:   "on",
+		Short: "daemon/serve起動時の匿名の起動通知を有効化",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := telemetry.SettingsPath(cfgPath)
+			settings, err := telemetry.LoadSettings(path)
+			if err != nil {
+				return err
+			}
+			settings.Endpoint = cmp.Or(url, settings.Endpoint)
+			if settings.Endpoint == "" {
+				return errors.New("--url で送信先を指定してください")
+			}
+			settings.Enabled = true
+			settings.NoticeShown = true
+			if err := telemetry.SaveSettings(path, settings); err != nil {
+				return err
+			}
+			fmt.Println(telemetryNotice)
+			fmt.Printf("送信先: %s\n", settings.Endpoint)
+			return nil
+		},
+	}
+	on.Flags().StringVar(&url, "url", "", "起動通知の送信先URL")
+
+	off := &cobra.Command{
+		Use:   "off",
+		Short: "匿名の起動通知を無効化",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := telemetry.SettingsPath(cfgPath)
+			settings, err := telemetry.LoadSettings(path)
+			if err != nil {
+				return err
+			}
+			settings.Enabled = false
+			if err := telemetry.SaveSettings(path, settings); err != nil {
+				return err
+			}
+			fmt.Println("匿名の起動通知を無効にしました")
+			return nil
+		},
+	}
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "起動通知の有効/無効と、送信される内容をそのまま表示",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings, err := telemetry.LoadSettings(telemetry.SettingsPath(cfgPath))
+			if err != nil {
+				return err
+			}
+			out, _ := json.MarshalIndent(map[string]any{
+				"enabled":  settings.Enabled,
+				"endpoint": settings.Endpoint,
+				"payload":  startupPing(),
+			}, "", "  ")
+			fmt.Println(string(out))
+			return nil
+		},
+	}
+
+	cmd.AddCommand(on, off, status)
+	return cmd
+}
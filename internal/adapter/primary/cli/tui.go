@@ -0,0 +1,46 @@
+//go:build !notui
+
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/adapter/primary/tui"
+)
+
+// Build with -tags notui to leave the terminal dashboard out.
+func init() {
+	optionalCommands = append(optionalCommands, newTUICmd)
+}
+
+func newTUICmd() *cobra.Command {
+	var noScheduler bool
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "音量・目標・次回実行までの残り時間・最近の履歴を表示する全画面ダッシュボード (スケジューラも起動)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uc, err := newUseCase()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			if !noScheduler {
+				uc.Start(ctx)
+			}
+			controller, err := newController()
+			if err != nil {
+				return err
+			}
+			return tui.New(uc, tui.WithVolumeReader(controller.GetVolume)).Run(ctx)
+		},
+	}
+	cmd.Flags().BoolVar(&noScheduler, "no-scheduler", false, "スケジューラを起動せず表示と手動操作のみ行う (daemonを別に動かしている場合)")
+	return cmd
+}
@@ -0,0 +1,131 @@
+//go:build !noupdate
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"micgain-manager/internal/adapter/secondary/release"
+)
+
+// Build with -tags noupdate to leave the update command out.
+func init() {
+	optionalCommands = append(optionalCommands, newUpdateCmd)
+}
+
+func newUpdateCmd() *cobra.Command {
+	var (
+		check bool
+		apply bool
+		repo  string
+	)
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "GitHubのリリースを確認し、新しいバージョンがあれば通知 (--applyで置き換え)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if check == apply {
+				return errors.New("--check か --apply のどちらかを指定してください")
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+			defer cancel()
+			client := release.NewGitHubClient(repo)
+
+			latest, err := client.Latest(ctx)
+			if err != nil {
+				if check {
+					// Being offline is not an error worth a failing exit code
+					fmt.Fprintf(os.Stderr, "更新を確認できませんでした: %v\n", err)
+					return nil
+				}
+				return fmt.Errorf("更新を確認できませんでした: %w", err)
+			}
+
+			newer, err := release.Newer(latest.Tag, Version)
+			if err != nil {
+				fmt.Printf("現在のバージョン %s と最新リリース %s を比較できません\n", Version, latest.Tag)
+				if check {
+					return nil
+				}
+				return err
+			}
+			if !newer {
+				fmt.Printf("最新です (%s)\n", Version)
+				return nil
+			}
+			fmt.Printf("新しいバージョンがあります: %s → %s\n%s\n", Version, latest.Tag, latest.URL)
+			if check {
+				return nil
+			}
+
+			return applyUpdate(ctx, client, latest)
+		},
+	}
+	cmd.Flags().BoolVar(&check, "check", false, "新しいバージョンがあるか確認のみ行う")
+	cmd.Flags().BoolVar(&apply, "apply", false, "新しいバージョンをダウンロードし、チェックサムを検証して実行ファイルを置き換える")
+	cmd.Flags().StringVar(&repo, "repo", release.DefaultRepo, "リリースを確認するGitHubリポジトリ (owner/name)")
+	return cmd
+}
+
+// applyUpdate downloads the asset for this platform and atomically replaces
+// the running executable with it.
+func applyUpdate(ctx context.Context, client *release.GitHubClient, latest release.Release) error {
+	asset, ok := platformAsset(latest, runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return fmt.Errorf("%s/%s 向けのファイルがリリース %s にありません", runtime.GOOS, runtime.GOARCH, latest.Tag)
+	}
+
+	data, err := client.DownloadVerified(ctx, latest, asset)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	// Write next to the executable so the rename stays on one filesystem
+	tmp := exe + ".new"
+	if err := os.WriteFile(tmp, data, 0o755); err != nil {
+		return fmt.Errorf("write update: %w", err)
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replace executable: %w", err)
+	}
+
+	fmt.Printf("%s に更新しました (%s)\n", latest.Tag, exe)
+	return nil
+}
+
+// platformAsset picks the raw binary built for goos and goarch. The name
+// must carry them as whole "_<os>_<arch>" tokens, so arm never matches an
+// arm64 build and amd64 never matches amd64v3.
+func platformAsset(latest release.Release, goos, goarch string) (release.Asset, bool) {
+	for _, a := range latest.Assets {
+		name := strings.ToLower(a.Name)
+		if strings.HasSuffix(name, ".txt") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".zip") {
+			continue
+		}
+		tokens := strings.Split(strings.TrimSuffix(name, ".exe"), "_")
+		for i := 1; i+1 < len(tokens); i++ {
+			if tokens[i] == goos && tokens[i+1] == goarch {
+				return a, true
+			}
+		}
+	}
+	return release.Asset{}, false
+}
@@ -0,0 +1,44 @@
+//go:build !noupdate
+
+package cli
+
+import (
+	"testing"
+
+	"micgain-manager/internal/adapter/secondary/release"
+)
+
+func TestPlatformAsset(t *testing.T) {
+	assets := func(names ...string) release.Release {
+		var r release.Release
+		for _, n := range names {
+			r.Assets = append(r.Assets, release.Asset{Name: n})
+		}
+		return r
+	}
+	tests := []struct {
+		name         string
+		release      release.Release
+		goos, goarch string
+		want         string
+	}{
+		{"exact", assets("micgain-manager_linux_amd64"), "linux", "amd64", "micgain-manager_linux_amd64"},
+		{"arm skips arm64", assets("micgain-manager_linux_arm64", "micgain-manager_linux_arm"), "linux", "arm", "micgain-manager_linux_arm"},
+		{"arm64 skips arm", assets("micgain-manager_linux_arm", "micgain-manager_linux_arm64"), "linux", "arm64", "micgain-manager_linux_arm64"},
+		{"amd64 skips amd64v3", assets("micgain-manager_linux_amd64v3"), "linux", "amd64", ""},
+		{"windows exe", assets("micgain-manager_windows_amd64.exe"), "windows", "amd64", "micgain-manager_windows_amd64.exe"},
+		{"version before platform", assets("micgain-manager_1.2.0_darwin_arm64"), "darwin", "arm64", "micgain-manager_1.2.0_darwin_arm64"},
+		{"upper case", assets("MicGain-Manager_Darwin_ARM64"), "darwin", "arm64", "MicGain-Manager_Darwin_ARM64"},
+		{"archives skipped", assets("micgain-manager_linux_amd64.tar.gz", "micgain-manager_linux_amd64.zip", "checksums.txt"), "linux", "amd64", ""},
+		{"other os", assets("micgain-manager_darwin_amd64"), "linux", "amd64", ""},
+		{"os not in its own token", assets("micgain-manager-linux_amd64"), "linux", "amd64", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := platformAsset(tt.release, tt.goos, tt.goarch)
+			if ok != (tt.want != "") || got.Name != tt.want {
+				t.Errorf("platformAsset = %q, %v; want %q", got.Name, ok, tt.want)
+			}
+		})
+	}
+}
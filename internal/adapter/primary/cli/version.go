@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// Version is the release version, set at build time with
+// -ldflags "-X micgain-manager/internal/adapter/primary/cli.Version=v1.2.3".
+var Version = "dev"
+
+// optionalCommands are added by files that can be left out with build tags.
+var optionalCommands []func() *cobra.Command
+
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "バージョンを表示",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(Version)
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// SnapshotSource is the part of the scheduler use case the metrics read.
+type SnapshotSource interface {
+	GetSnapshot() domain.Snapshot
+}
+
+// metric is a single gauge, or counter, in the Prometheus text exposition
+// format.
+type metric struct {
+	name    string
+	help    string
+	counter bool
+	value   func(snap domain.Snapshot, now time.Time) float64
+}
+
+// gauges defines every exported metric. Both the text writer and the
+// pusher use these definitions.
+var gauges = []metric{
+	{
+		name: "micgain_target_volume",
+		help: "Resolved target input volume (0-100).",
+		value: func(snap domain.Snapshot, now time.Time) float64 {
+			target, _ := service.ApplyFloor(snap.Config, service.ResolveTarget(snap.ScheduleState, snap.Config, now))
+			return float64(target)
+		},
+	},
+	{
+		name: "micgain_scheduler_enabled",
+		help: "Whether the scheduler is enabled.",
+		value: func(snap domain.Snapshot, _ time.Time) float64 {
+			return boolValue(snap.Config.Enabled)
+		},
+	},
+	{
+		name: "micgain_volume_held",
+		help: "Whether the volume is locked by a hold.",
+		value: func(snap domain.Snapshot, _ time.Time) float64 {
+			return boolValue(snap.ScheduleState.Hold.Active)
+		},
+	},
+	{
+		name: "micgain_interval_seconds",
+		help: "Effective interval between scheduled applies.",
+		value: func(snap domain.Snapshot, _ time.Time) float64 {
+			return service.EffectiveInterval(snap.ScheduleState, snap.Config).Seconds()
+		},
+	},
+	{
+		name: "micgain_last_apply_success",
+		help: "Whether the last apply attempt succeeded.",
+		value: func(snap domain.Snapshot, _ time.Time) float64 {
+			return boolValue(snap.ScheduleState.LastApplyStatus == domain.StatusSuccess)
+		},
+	},
+	{
+		name: "micgain_last_apply_timestamp_seconds",
+		help: "Unix time of the last successful apply, 0 if never.",
+		value: func(snap domain.Snapshot, _ time.Time) float64 {
+			if snap.ScheduleState.LastApplied.IsZero() {
+				return 0
+			}
+			return float64(snap.ScheduleState.LastApplied.Unix())
+		},
+	},
+	{
+		name:    "micgain_dropped_events_total",
+		help:    "Status events replaced before a slow subscriber read them.",
+		counter: true,
+		value: func(snap domain.Snapshot, _ time.Time) float64 {
+			return float64(snap.DroppedEvents)
+		},
+	},
+}
+
+var service = domain.NewSchedulerService()
+
+// WriteText renders the snapshot as Prometheus text exposition.
+func WriteText(w io.Writer, snap domain.Snapshot, now time.Time) error {
+	for _, g := range gauges {
+		kind := "gauge"
+		if g.counter {
+			kind = "counter"
+		}
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n",
+			g.name, g.help, g.name, kind, g.name, g.value(snap, now))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
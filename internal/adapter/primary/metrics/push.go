@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"micgain-manager/internal/logging"
+)
+
+// DefaultJob is the Pushgateway job label used when none is given.
+const DefaultJob = "micgain-manager"
+
+// Pusher periodically pushes the metrics to a Prometheus Pushgateway, for
+// hosts that cannot be scraped.
+type Pusher struct {
+	source   SnapshotSource
+	endpoint string
+	interval time.Duration
+	client   *http.Client
+}
+
+// PushOption configures optional behavior of the Pusher.
+type PushOption func(*pushOptions)
+
+type pushOptions struct {
+	job      string
+	instance string
+}
+
+// WithJob overrides the job label.
+func WithJob(job string) PushOption {
+	return func(o *pushOptions) {
+		o.job = job
+	}
+}
+
+// WithInstance overrides the instance label, which defaults to the hostname.
+func WithInstance(instance string) PushOption {
+	return func(o *pushOptions) {
+		o.instance = instance
+	}
+}
+
+// NewPusher creates a pusher for the given Pushgateway base URL.
+func NewPusher(source SnapshotSource, gatewayURL string, interval time.Duration, opts ...PushOption) (*Pusher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("push interval must be positive")
+	}
+	base, err := url.Parse(gatewayURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid push URL %q", gatewayURL)
+	}
+
+	o := pushOptions{job: DefaultJob}
+	if host, err := os.Hostname(); err == nil {
+		o.instance = host
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	endpoint := strings.TrimSuffix(base.String(), "/") + "/metrics/job/" + url.PathEscape(o.job)
+	if o.instance != "" {
+		endpoint += "/instance/" + url.PathEscape(o.instance)
+	}
+	return &Pusher{
+		source:   source,
+		endpoint: endpoint,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Run pushes once immediately and then every interval until ctx is done.
+// Failures are logged and never stop the loop.
+func (p *Pusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.push(ctx); err != nil {
+			logging.Warnf("metrics push: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pusher) push(ctx context.Context) error {
+	var body bytes.Buffer
+	if err := WriteText(&body, p.source.GetSnapshot(), time.Now()); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: unexpected status %s", p.endpoint, resp.Status)
+	}
+	logging.Debugf("metrics pushed to %s", p.endpoint)
+	return nil
+}
@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"bufio"
+	"io"
+)
+
+// key is a dashboard action decoded from terminal input.
+type key int
+
+const (
+	keyNone key = iota
+	keyQuit
+	keyApply
+	keyPause
+	keyUp
+	keyDown
+	keyUpFast
+	keyDownFast
+)
+
+// step is how far a key moves the target.
+func (k key) step() int {
+	switch k {
+	case keyUp:
+		return 1
+	case keyDown:
+		return -1
+	case keyUpFast:
+		return 5
+	case keyDownFast:
+		return -5
+	}
+	return 0
+}
+
+// readKeys decodes raw terminal input until it ends. The reader cannot be
+// interrupted, so the goroutine is simply left behind on exit.
+func readKeys(r io.Reader, keys chan<- key) {
+	defer close(keys)
+	in := bufio.NewReader(r)
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			return
+		}
+		k := keyNone
+		switch b {
+		case 'q', 'Q', 0x03: // Ctrl-C arrives as a byte in raw mode
+			k = keyQuit
+		case 'a', 'A':
+			k = keyApply
+		case 'p', 'P', ' ':
+			k = keyPause
+		case '+', '=':
+			k = keyUp
+		case '-', '_':
+			k = keyDown
+		case 0x1b:
+			k = readEscape(in)
+		}
+		if k != keyNone {
+			keys <- k
+		}
+	}
+}
+
+// readEscape decodes the arrow keys, "ESC [ A" and "ESC [ B".
+func readEscape(in *bufio.Reader) key {
+	if in.Buffered() < 2 {
+		return keyNone
+	}
+	if b, _ := in.ReadByte(); b != '[' {
+		return keyNone
+	}
+	switch b, _ := in.ReadByte(); b {
+	case 'A':
+		return keyUpFast
+	case 'B':
+		return keyDownFast
+	}
+	return keyNone
+}
@@ -0,0 +1,243 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+
+	"micgain-manager/internal/domain"
+)
+
+// DefaultRefresh is how often the dashboard redraws.
+const DefaultRefresh = 500 * time.Millisecond
+
+// historyRows is how many recent applies the dashboard lists.
+const historyRows = 8
+
+// volumeReadEvery limits how often the hardware volume is read back, since
+// every read runs osascript.
+const volumeReadEvery = 2 * time.Second
+
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+// UseCase is the part of the scheduler use case the dashboard drives.
+type UseCase interface {
+	GetSnapshot() domain.Snapshot
+	ApplyNow(volume int) error
+	UpdateConfig(config domain.Config, applyNow bool) error
+	QueryHistory(q domain.HistoryQuery) ([]domain.ApplyRecord, int, error)
+}
+
+// Dashboard is a full-screen terminal view of the scheduler with keys to
+// apply, pause and adjust the target. This is a primary adapter.
+type Dashboard struct {
+	usecase    UseCase
+	readVolume func() (int, error)
+	refresh    time.Duration
+	service    *domain.SchedulerService
+
+	current    string
+	volumeRead time.Time
+	message    string
+}
+
+// Option configures a Dashboard.
+type Option func(*Dashboard)
+
+// WithVolumeReader shows the read-back hardware volume next to the target.
+func WithVolumeReader(read func() (int, error)) Option {
+	return func(d *Dashboard) {
+		d.readVolume = read
+	}
+}
+
+// WithRefresh sets how often the dashboard redraws.
+func WithRefresh(refresh time.Duration) Option {
+	return func(d *Dashboard) {
+		if refresh > 0 {
+			d.refresh = refresh
+		}
+	}
+}
+
+// New creates a dashboard for the given use case.
+func New(uc UseCase, opts ...Option) *Dashboard {
+	d := &Dashboard{
+		usecase: uc,
+		refresh: DefaultRefresh,
+		service: domain.NewSchedulerService(),
+		current: "-",
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run takes over the terminal until ctx is done or the user quits.
+func (d *Dashboard) Run(ctx context.Context) error {
+	fd := int(os.Stdin.Fd())
+	if !readline.IsTerminal(fd) {
+		return errors.New("tui needs an interactive terminal")
+	}
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer readline.Restore(fd, state)
+
+	out := os.Stdout
+	fmt.Fprint(out, enterScreen)
+	defer fmt.Fprint(out, leaveScreen)
+
+	keys := make(chan key, 8)
+	go readKeys(os.Stdin, keys)
+
+	ticker := time.NewTicker(d.refresh)
+	defer ticker.Stop()
+	for {
+		d.draw(out, time.Now())
+		select {
+		case <-ctx.Done():
+			return nil
+		case k, ok := <-keys:
+			if !ok || k == keyQuit {
+				return nil
+			}
+			d.handle(k)
+		case <-ticker.C:
+		}
+	}
+}
+
+// handle runs the action bound to a key and keeps its outcome for the
+// message line.
+func (d *Dashboard) handle(k key) {
+	var err error
+	switch k {
+	case keyApply:
+		if err = d.usecase.ApplyNow(-1); err == nil {
+			d.message = "applied"
+		}
+	case keyPause:
+		config := d.usecase.GetSnapshot().Config
+		config.Enabled = !config.Enabled
+		if err = d.usecase.UpdateConfig(config, false); err == nil {
+			d.message = "paused"
+			if config.Enabled {
+				d.message = "resumed"
+			}
+		}
+	case keyUp, keyUpFast, keyDown, keyDownFast:
+		err = d.adjust(k.step())
+	default:
+		return
+	}
+	if err != nil {
+		d.message = "error: " + err.Error()
+	}
+	// Show the effect right away rather than on the next read-back
+	d.volumeRead = time.Time{}
+}
+
+func (d *Dashboard) adjust(step int) error {
+	config := d.usecase.GetSnapshot().Config
+	if len(config.Curve) > 0 {
+		return errors.New("the target follows a curve; edit it with config set --curve")
+	}
+	config.TargetVolume = min(max(config.TargetVolume+step, 0), 100)
+	if err := d.usecase.UpdateConfig(config, false); err != nil {
+		return err
+	}
+	d.message = fmt.Sprintf("target set to %d", config.TargetVolume)
+	return nil
+}
+
+func (d *Dashboard) draw(w io.Writer, now time.Time) {
+	snap := d.usecase.GetSnapshot()
+	config, state := snap.Config, snap.ScheduleState
+
+	if d.readVolume != nil && now.Sub(d.volumeRead) >= volumeReadEvery {
+		d.volumeRead = now
+		if v, err := d.readVolume(); err != nil {
+			d.current = "?"
+		} else {
+			d.current = fmt.Sprint(v)
+		}
+	}
+
+	target, _ := d.service.ApplyFloor(config, d.service.ResolveTarget(state, config, now))
+	enabled := "enabled"
+	if !config.Enabled {
+		enabled = "paused"
+	}
+	lines := []string{
+		"Mic Gain Manager",
+		"",
+		fmt.Sprintf("  volume    %s", d.current),
+		fmt.Sprintf("  target    %d%s", target, targetNote(config, state)),
+		fmt.Sprintf("  scheduler %s, every %s (%s)", enabled, d.service.EffectiveInterval(state, config), config.ScheduleMode),
+		fmt.Sprintf("  status    %s", d.service.ReportedStatus(state, config)),
+		fmt.Sprintf("  next run  %s", countdown(state, config, now)),
+	}
+	if state.LastError != nil {
+		lines = append(lines, "  error     "+state.LastError.Error())
+	}
+	if state.LastWarning != "" {
+		lines = append(lines, "  warning   "+state.LastWarning)
+	}
+
+	lines = append(lines, "", "Recent applies")
+	records, _, err := d.usecase.QueryHistory(domain.HistoryQuery{Limit: historyRows})
+	switch {
+	case err != nil:
+		lines = append(lines, "  "+err.Error())
+	case len(records) == 0:
+		lines = append(lines, "  (none)")
+	}
+	for _, r := range records {
+		line := fmt.Sprintf("  %s  %3d  %-9s %s", r.Timestamp.Local().Format("15:04:05"), r.Volume, r.Status, r.Trigger)
+		if r.Error != "" {
+			line += " " + r.Error
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "", "  "+d.message, "", "a: 今すぐ適用  p: 一時停止/再開  +/-: 目標±1  ↑/↓: 目標±5  q: 終了")
+	// Raw mode does not turn "\n" into a carriage return
+	fmt.Fprint(w, clearScreen+strings.Join(lines, "\r\n"))
+}
+
+func targetNote(config domain.Config, state domain.ScheduleState) string {
+	switch {
+	case state.Hold.Active:
+		return " (held)"
+	case len(config.Curve) > 0:
+		return " (curve)"
+	}
+	return ""
+}
+
+func countdown(state domain.ScheduleState, config domain.Config, now time.Time) string {
+	switch {
+	case !config.Enabled:
+		return "-"
+	case state.NextRun.IsZero():
+		return "pending"
+	}
+	left := state.NextRun.Sub(now).Round(time.Second)
+	if left <= 0 {
+		return "due"
+	}
+	return fmt.Sprintf("%s (%s)", left, state.NextRun.Local().Format("15:04:05"))
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// WithAuthToken requires "Authorization: Bearer <token>" on every /api/
+// request. The UI itself and /healthz stay public; the UI asks for the
+// token and sends it along. An empty token leaves the API open.
+func WithAuthToken(token string) Option {
+	return func(s *Server) {
+		s.authToken = token
+	}
+}
+
+// requireToken answers API requests that lack the token with 401.
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	if s.authToken == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/")) && !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="micgain-manager"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized compares the request's token in constant time. The event
+// stream may pass it as ?access_token=, since EventSource cannot set
+// headers.
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && r.URL.Path == "/api/events" {
+		token = r.URL.Query().Get("access_token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) == 1
+}
+
+// handleHealthz reports that the server is up, without authentication, for
+// load balancers and service managers.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// debugHistoryLimit is how many recent applies a debug dump includes.
+const debugHistoryLimit = 50
+
+// WithVersion sets the release version reported by GET /api/debug.
+func WithVersion(version string) Option {
+	return func(s *Server) {
+		s.version = version
+	}
+}
+
+// DebugDump collects what support needs to know about a running use case:
+// build and platform, the snapshot as the API shows it, pending restarts
+// and recent history. GET /api/debug serves it, and the support bundle
+// writes the same for its own process.
+func DebugDump(uc UseCase, version string, startedAt time.Time) map[string]any {
+	dump := map[string]any{
+		"version":    version,
+		"goVersion":  runtime.Version(),
+		"platform":   runtime.GOOS + "/" + runtime.GOARCH,
+		"pid":        os.Getpid(),
+		"goroutines": runtime.NumGoroutine(),
+		"snapshot":   snapshotToView(uc.GetSnapshot()),
+	}
+	if !startedAt.IsZero() {
+		dump["startedAt"] = startedAt
+		dump["uptimeSeconds"] = time.Since(startedAt).Seconds()
+	}
+
+	if fields, err := uc.RestartRequired(); err != nil {
+		dump["restartRequiredError"] = err.Error()
+	} else {
+		dump["restartRequired"] = fields
+	}
+
+	records, total, err := uc.QueryHistory(domain.HistoryQuery{Limit: debugHistoryLimit})
+	if err != nil {
+		dump["historyError"] = err.Error()
+	} else {
+		views := make([]map[string]any, 0, len(records))
+		for _, record := range records {
+			views = append(views, recordToView(record))
+		}
+		dump["history"] = views
+		dump["historyTotal"] = total
+	}
+	return dump
+}
+
+func (s *Server) handleDebug(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	dump := DebugDump(s.usecase, s.version, s.startedAt)
+	dump["basePath"] = s.basePath
+	dump["tls"] = s.TLS()
+	respondJSON(w, http.StatusOK, dump)
+}
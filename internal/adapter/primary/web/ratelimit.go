@@ -0,0 +1,76 @@
+package web
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WithApplyRateLimit limits the applies requested through the API to
+// perSecond on average, with bursts of up to burst, so that a stuck button
+// or a script cannot queue up controller calls. Requests over the limit
+// get 429 with Retry-After. The scheduler's own applies are not limited.
+// A non-positive rate leaves applies unlimited.
+func WithApplyRateLimit(perSecond float64, burst int) Option {
+	return func(s *Server) {
+		if perSecond <= 0 {
+			s.applyLimit = nil
+			return
+		}
+		s.applyLimit = newTokenBucket(perSecond, max(burst, 1), time.Now)
+	}
+}
+
+// tokenBucket is a token bucket rate limiter. It starts full.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(perSecond float64, burst int, now func() time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now(),
+		now:    now,
+	}
+}
+
+// take removes a token if there is one. Otherwise it reports how long
+// until the next token.
+func (b *tokenBucket) take() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / b.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// allowApply takes a token for an apply and answers 429 when there is
+// none, in which case the caller must not apply.
+func (s *Server) allowApply(w http.ResponseWriter) bool {
+	if s.applyLimit == nil {
+		return true
+	}
+	ok, wait := s.applyLimit.take()
+	if ok {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "too many applies, retry later", http.StatusTooManyRequests)
+	return false
+}
@@ -3,10 +3,19 @@ package web
 import (
 	"context"
 	"embed"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"micgain-manager/internal/domain"
@@ -16,39 +25,186 @@ import (
 //go:embed static/*
 var staticFiles embed.FS
 
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// UseCase is the subset of usecase.SchedulerUseCase the HTTP handlers
+// depend on. Tests can pass a fake instead of a real scheduler.
+type UseCase interface {
+	GetSnapshot() domain.Snapshot
+	UpdateConfig(config domain.Config, applyNow bool) error
+	SimulateConfig(config domain.Config) (domain.ConfigSimulation, error)
+	UseProfile(name string, applyNow bool) error
+	ApplyNow(volume int) error
+	ApplyIfEnabled(volume int) error
+	PlanApply(volume int) (domain.ApplyPlan, error)
+	Hold(volume int) error
+	Release() error
+	ResetState() error
+	QueryHistory(q domain.HistoryQuery) ([]domain.ApplyRecord, int, error)
+	PreviewTargets(horizon, step time.Duration) []domain.TargetPoint
+	ExplainApply() domain.ApplyDecision
+	RestartRequired() ([]string, error)
+	ListInputDevices() ([]domain.AudioDevice, error)
+	Subscribe() (<-chan domain.Snapshot, func())
+}
+
+var _ UseCase = (usecase.SchedulerUseCase)(nil)
+
 // Server is a primary adapter that exposes HTTP API + UI.
 // It depends on the use case (primary port).
 type Server struct {
-	usecase usecase.SchedulerUseCase
-	server  *http.Server
+	usecase  UseCase
+	server   *http.Server
+	basePath string
+	certFile string
+	keyFile  string
+	// version and startedAt are reported by GET /api/debug.
+	version   string
+	startedAt time.Time
+	// authToken is required on API requests when set (WithAuthToken).
+	authToken string
+	// applyLimit limits API applies when set (WithApplyRateLimit).
+	applyLimit *tokenBucket
+	// done is closed on shutdown to end open event streams, which
+	// http.Server.Shutdown would otherwise wait for.
+	done chan struct{}
+}
+
+// Option configures optional behavior of the server.
+type Option func(*Server)
+
+// WithBasePath serves the UI and API under the given path prefix,
+// e.g. "/micgain" when running behind a reverse proxy.
+func WithBasePath(basePath string) Option {
+	return func(s *Server) {
+		s.basePath = normalizeBasePath(basePath)
+	}
 }
 
 // NewServer creates the HTTP server bound to addr.
-func NewServer(uc usecase.SchedulerUseCase, addr string) *Server {
+func NewServer(uc UseCase, addr string, opts ...Option) *Server {
 	mux := http.NewServeMux()
-	srv := &Server{usecase: uc}
+	srv := &Server{usecase: uc, startedAt: time.Now(), done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(srv)
+	}
 
 	// API endpoints
 	mux.HandleFunc("/api/config", srv.handleConfig)
+	mux.HandleFunc("/api/config/restart-required", srv.handleRestartRequired)
+	mux.HandleFunc("/api/config/simulate", srv.handleConfigSimulate)
 	mux.HandleFunc("/api/apply", srv.handleApply)
+	mux.HandleFunc("/api/apply/plan", srv.handleApplyPlan)
+	mux.HandleFunc("/api/history", srv.handleHistory)
+	mux.HandleFunc("/api/history.csv", srv.handleHistoryCSV)
+	mux.HandleFunc("/api/lock", srv.handleLock)
+	mux.HandleFunc("/api/profiles", srv.handleProfiles)
+	mux.HandleFunc("/api/profiles/", srv.handleProfileActivate)
+	mux.HandleFunc("/api/state/reset", srv.handleStateReset)
+	mux.HandleFunc("/api/curve/preview", srv.handleCurvePreview)
+	mux.HandleFunc("/api/explain", srv.handleExplain)
+	mux.HandleFunc("/api/debug", srv.handleDebug)
+	mux.HandleFunc("/api/devices", srv.handleDevices)
+	mux.HandleFunc("/api/events", srv.handleEvents)
+	mux.HandleFunc("/healthz", srv.handleHealthz)
 
 	// Static files
 	staticFS, err := fs.Sub(staticFiles, "static")
 	if err != nil {
 		panic(err)
 	}
-	mux.Handle("/", http.FileServer(http.FS(staticFS)))
+	index, err := renderIndex(staticFS, srv.basePath)
+	if err != nil {
+		panic(err)
+	}
+	files := http.FileServer(http.FS(staticFS))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" || r.URL.Path == "/index.html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(index)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+
+	handler := srv.requireToken(mux)
+	if srv.basePath != "" {
+		root := http.NewServeMux()
+		root.Handle(srv.basePath+"/", http.StripPrefix(srv.basePath, handler))
+		root.Handle(srv.basePath, http.RedirectHandler(srv.basePath+"/", http.StatusMovedPermanently))
+		handler = root
+	}
 
 	srv.server = &http.Server{
 		Addr:    addr,
-		Handler: loggingMiddleware(mux),
+		Handler: loggingMiddleware(handler),
 	}
+	srv.server.RegisterOnShutdown(func() { close(srv.done) })
 	return srv
 }
 
-// Start blocks and serves HTTP traffic.
+// renderIndex injects a <base> element so that the UI's relative asset and
+// API URLs resolve under the base path.
+func renderIndex(staticFS fs.FS, basePath string) ([]byte, error) {
+	data, err := fs.ReadFile(staticFS, "index.html")
+	if err != nil {
+		return nil, err
+	}
+	base := fmt.Sprintf("<head>\n    <base href=\"%s/\">", html.EscapeString(basePath))
+	return []byte(strings.Replace(string(data), "<head>", base, 1)), nil
+}
+
+// normalizeBasePath returns the prefix with a leading slash and no trailing
+// slash, or an empty string for the root.
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(basePath, "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// Handler returns the fully wired HTTP handler, so that tests can drive the
+// API through httptest without binding a port.
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// Start listens on the configured address, then blocks and serves HTTP traffic.
 func (s *Server) Start() error {
-	return s.server.ListenAndServe()
+	if s.TLS() {
+		return s.server.ListenAndServeTLS(s.certFile, s.keyFile)
+	}
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve blocks and serves HTTP traffic on an already bound listener, so
+// that callers can learn the actual address (e.g. for ":0") beforehand.
+func (s *Server) Serve(ln net.Listener) error {
+	if s.TLS() {
+		return s.server.ServeTLS(ln, s.certFile, s.keyFile)
+	}
+	return s.server.Serve(ln)
+}
+
+// TLS reports whether the server was configured with WithTLS.
+func (s *Server) TLS() bool {
+	return s.certFile != ""
+}
+
+// Scheme returns "https" or "http" for building URLs to the server.
+func (s *Server) Scheme() string {
+	if s.TLS() {
+		return "https"
+	}
+	return "http"
 }
 
 // Shutdown gracefully stops the server.
@@ -68,20 +224,28 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		snap := s.usecase.GetSnapshot()
-		config := snap.Config
-
-		if req.TargetVolume != nil {
-			config.TargetVolume = *req.TargetVolume
-		}
-		if req.IntervalSeconds != nil {
-			config.Interval = time.Duration(*req.IntervalSeconds) * time.Second
+		config, err := configFromPayload(s.usecase.GetSnapshot().Config, req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		if req.Enabled != nil {
-			config.Enabled = *req.Enabled
+		if req.ApplyNow && !s.allowApply(w) {
+			return
 		}
 
 		if err := s.usecase.UpdateConfig(config, req.ApplyNow); err != nil {
+			if errors.Is(err, domain.ErrConfigLocked) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			if errors.Is(err, domain.ErrConfigBusy) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if errors.Is(err, domain.ErrInvalidConfig) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -92,19 +256,709 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleConfigSimulate previews a PUT /api/config body without saving it.
+// An update that would be rejected is reported as invalid, not as an error.
+func (s *Server) handleConfigSimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req updatePayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	config, err := configFromPayload(s.usecase.GetSnapshot().Config, req)
+	var sim domain.ConfigSimulation
+	if err == nil {
+		sim, err = s.usecase.SimulateConfig(config)
+	}
+	if err != nil {
+		respondJSON(w, http.StatusOK, map[string]any{
+			"valid": false,
+			"error": err.Error(),
+		})
+		return
+	}
+	warnings := sim.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"valid":        true,
+		"snapshot":     snapshotToView(sim.Snapshot),
+		"targetVolume": sim.Target,
+		"warnings":     warnings,
+	})
+}
+
+// configFromPayload applies the fields set in req on top of config.
+func configFromPayload(config domain.Config, req updatePayload) (domain.Config, error) {
+	if req.TargetVolume != nil {
+		config.TargetVolume = *req.TargetVolume
+	}
+	if req.IntervalSeconds != nil {
+		interval, err := domain.IntervalFromSeconds("intervalSeconds", *req.IntervalSeconds)
+		if err != nil {
+			return domain.Config{}, err
+		}
+		config.Interval = interval
+	}
+	if req.Enabled != nil {
+		config.Enabled = *req.Enabled
+	}
+	if req.ScheduleMode != nil {
+		mode, err := domain.ParseScheduleMode(*req.ScheduleMode)
+		if err != nil {
+			return domain.Config{}, err
+		}
+		config.ScheduleMode = mode
+	}
+	if req.Schedule != nil {
+		config.Schedule = *req.Schedule
+	}
+	if req.Timezone != nil {
+		config.Timezone = *req.Timezone
+	}
+	if req.DeviceName != nil {
+		config.DeviceName = *req.DeviceName
+	}
+	if req.OnDeviceAbsent != nil {
+		policy, err := domain.ParseDeviceAbsentPolicy(*req.OnDeviceAbsent)
+		if err != nil {
+			return domain.Config{}, err
+		}
+		config.OnDeviceAbsent = policy
+	}
+	if req.FormatVolumes != nil {
+		config.FormatVolumes = nil
+		for _, p := range *req.FormatVolumes {
+			config.FormatVolumes = append(config.FormatVolumes, domain.FormatVolume{SampleRate: p.SampleRate, Volume: p.Volume})
+		}
+	}
+	if req.AdaptiveInterval != nil {
+		config.AdaptiveInterval = *req.AdaptiveInterval
+	}
+	if req.RescheduleOnManualApply != nil {
+		config.RescheduleOnManualApply = *req.RescheduleOnManualApply
+	}
+	if req.MinTargetVolume != nil {
+		config.MinTargetVolume = *req.MinTargetVolume
+	}
+	if req.AllowedVolumes != nil {
+		config.AllowedVolumes = *req.AllowedVolumes
+	}
+	if req.ErrorThreshold != nil {
+		config.ErrorThreshold = *req.ErrorThreshold
+	}
+	if req.MaxRetries != nil {
+		config.MaxRetries = *req.MaxRetries
+	}
+	if req.RetryBackoffSeconds != nil {
+		config.RetryBackoff = time.Duration(*req.RetryBackoffSeconds * float64(time.Second))
+	}
+	if req.RampDurationMs != nil {
+		config.RampDuration = time.Duration(*req.RampDurationMs) * time.Millisecond
+	}
+	if req.RampSteps != nil {
+		config.RampSteps = *req.RampSteps
+	}
+	if req.StaggerBetweenDevicesMs != nil {
+		config.StaggerBetweenDevices = time.Duration(*req.StaggerBetweenDevicesMs) * time.Millisecond
+	}
+	if req.DriftAlertThreshold != nil {
+		config.DriftAlertThreshold = *req.DriftAlertThreshold
+	}
+	if req.ParkVolume != nil {
+		config.ParkVolume = nil
+		if *req.ParkVolume != -1 {
+			config.ParkVolume = req.ParkVolume
+		}
+	}
+	if req.FadeOnPark != nil {
+		config.FadeOnPark = *req.FadeOnPark
+	}
+	if req.ReapplyOnPowerChange != nil {
+		config.ReapplyOnPowerChange = *req.ReapplyOnPowerChange
+	}
+	if req.PowerPollSeconds != nil {
+		config.PowerPollInterval = 0
+		if *req.PowerPollSeconds != 0 {
+			poll, err := domain.IntervalFromSeconds("powerPollSeconds", *req.PowerPollSeconds)
+			if err != nil {
+				return domain.Config{}, err
+			}
+			config.PowerPollInterval = poll
+		}
+	}
+	if req.AppVolumes != nil {
+		config.AppVolumes = nil
+		for _, p := range *req.AppVolumes {
+			config.AppVolumes = append(config.AppVolumes, domain.AppVolume{App: p.App, Volume: p.Volume})
+		}
+	}
+	if req.DryRun != nil {
+		config.DryRun = *req.DryRun
+	}
+	if o := req.Output; o != nil {
+		if o.Enabled != nil {
+			config.Output.Enabled = *o.Enabled
+		}
+		if o.Volume != nil {
+			config.Output.Volume = *o.Volume
+		}
+	}
+	if n := req.Noise; n != nil {
+		if n.Enabled != nil {
+			config.Noise.Enabled = *n.Enabled
+		}
+		if n.ReferenceDBFS != nil {
+			config.Noise.ReferenceDBFS = *n.ReferenceDBFS
+		}
+		if n.MinVolume != nil {
+			config.Noise.MinVolume = *n.MinVolume
+		}
+		if n.MaxVolume != nil {
+			config.Noise.MaxVolume = *n.MaxVolume
+		}
+	}
+	if req.MaxIntervalSeconds != nil {
+		maxInterval, err := domain.IntervalFromSeconds("maxIntervalSeconds", *req.MaxIntervalSeconds)
+		if err != nil {
+			return domain.Config{}, err
+		}
+		config.MaxInterval = maxInterval
+	}
+	if req.Curve != nil {
+		curve, err := curveFromPayload(*req.Curve)
+		if err != nil {
+			return domain.Config{}, err
+		}
+		config.Curve = curve
+	}
+	if req.QuietHours != nil {
+		quiet, err := quietHoursFromPayload(*req.QuietHours)
+		if err != nil {
+			return domain.Config{}, err
+		}
+		config.QuietHours = quiet
+	}
+	return config, nil
+}
+
 func (s *Server) handleApply(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	if err := s.usecase.ApplyNow(-1); err != nil {
+	if !s.allowApply(w) {
+		return
+	}
+	apply := s.usecase.ApplyNow
+	if r.URL.Query().Get("respectEnabled") == "true" {
+		apply = s.usecase.ApplyIfEnabled
+	}
+	if err := apply(-1); err != nil {
+		if errors.Is(err, domain.ErrNotEnabled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, snapshotToView(s.usecase.GetSnapshot()))
+}
+
+func (s *Server) handleApplyPlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	volume := -1
+	if v := r.URL.Query().Get("volume"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "volume must be an integer", http.StatusBadRequest)
+			return
+		}
+		volume = parsed
+	}
+
+	plan, err := s.usecase.PlanApply(volume)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidVolume) || errors.Is(err, domain.ErrVolumeNotAllowed):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrConfigLocked):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, domain.ErrVolumeHeld):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, planToView(plan))
+}
+
+func (s *Server) handleLock(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req lockPayload
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Volume == nil {
+			http.Error(w, "invalid JSON: volume is required", http.StatusBadRequest)
+			return
+		}
+		// Holding applies the volume at once, so it shares the apply limit
+		if !s.allowApply(w) {
+			return
+		}
+		if err := s.usecase.Hold(*req.Volume); err != nil {
+			if errors.Is(err, domain.ErrInvalidVolume) || errors.Is(err, domain.ErrVolumeNotAllowed) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, domain.ErrConfigLocked) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, http.StatusOK, snapshotToView(s.usecase.GetSnapshot()))
+	case http.MethodDelete:
+		if err := s.usecase.Release(); err != nil {
+			if errors.Is(err, domain.ErrNotHeld) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if errors.Is(err, domain.ErrConfigLocked) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, http.StatusOK, snapshotToView(s.usecase.GetSnapshot()))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	config := s.usecase.GetSnapshot().Config
+	views := make([]map[string]any, 0, len(config.Profiles))
+	for _, p := range config.Profiles {
+		views = append(views, profileToView(p, p.Name == config.ActiveProfile))
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"profiles":      views,
+		"activeProfile": config.ActiveProfile,
+	})
+}
+
+// handleProfileActivate serves POST /api/profiles/{name}/activate.
+func (s *Server) handleProfileActivate(w http.ResponseWriter, r *http.Request) {
+	// The name is one path segment, split off before unescaping, so that
+	// a name containing "/" can be sent as %2F
+	rest := strings.TrimPrefix(r.URL.EscapedPath(), "/api/profiles/")
+	segment, ok := strings.CutSuffix(rest, "/activate")
+	if !ok || segment == "" || strings.Contains(segment, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	name, err := url.PathUnescape(segment)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The body is optional: {"applyNow": true}
+	var req struct {
+		ApplyNow bool `json:"applyNow"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.ApplyNow && !s.allowApply(w) {
+		return
+	}
+
+	if err := s.usecase.UseProfile(name, req.ApplyNow); err != nil {
+		if errors.Is(err, domain.ErrProfileNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, domain.ErrConfigLocked) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, snapshotToView(s.usecase.GetSnapshot()))
+}
+
+func (s *Server) handleStateReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.usecase.ResetState(); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	respondJSON(w, http.StatusOK, snapshotToView(s.usecase.GetSnapshot()))
 }
 
+func (s *Server) handleCurvePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	step := 30 * time.Minute
+	if v := r.URL.Query().Get("step"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute {
+			http.Error(w, "step must be a duration of at least 1m", http.StatusBadRequest)
+			return
+		}
+		step = d
+	}
+
+	points := s.usecase.PreviewTargets(24*time.Hour, step)
+	views := make([]map[string]any, 0, len(points))
+	for _, p := range points {
+		views = append(views, map[string]any{
+			"at":     p.At,
+			"volume": p.Volume,
+		})
+	}
+	respondJSON(w, http.StatusOK, views)
+}
+
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	respondJSON(w, http.StatusOK, decisionToView(s.usecase.ExplainApply()))
+}
+
+func (s *Server) handleRestartRequired(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	fields, err := s.usecase.RestartRequired()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if fields == nil {
+		fields = []string{}
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"restartRequired": len(fields) > 0,
+		"fields":          fields,
+	})
+}
+
+// eventKeepAlive is how often an idle event stream gets a comment line, so
+// that proxies keep it open and dead clients are noticed.
+const eventKeepAlive = 30 * time.Second
+
+// handleEvents serves GET /api/events, a Server-Sent Events stream of the
+// same payload as GET /api/config: one "snapshot" event on connect and one
+// whenever the state is saved.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before the first snapshot so no change falls in between
+	events, unsubscribe := s.usecase.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	send := func(snap domain.Snapshot) error {
+		data, err := json.Marshal(snapshotToView(snap))
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if err := send(s.usecase.GetSnapshot()); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case snap := <-events:
+			if err := send(snap); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleDevices serves GET /api/devices, the input devices the controller
+// can see, for picking deviceName.
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	devices, err := s.usecase.ListInputDevices()
+	if errors.Is(err, domain.ErrNotSupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	views := make([]map[string]any, 0, len(devices))
+	for _, d := range devices {
+		views = append(views, map[string]any{"id": d.ID, "name": d.Name, "default": d.Default, "sampleRate": d.SampleRate})
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"devices":    views,
+		"deviceName": s.usecase.GetSnapshot().Config.DeviceName,
+	})
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q, err := parseHistoryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records, total, err := s.usecase.QueryHistory(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	views := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		views = append(views, recordToView(record))
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	respondJSON(w, http.StatusOK, views)
+}
+
+// handleHistoryCSV serves the same page as handleHistory as CSV.
+func (s *Server) handleHistoryCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q, err := parseHistoryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records, total, err := s.usecase.QueryHistory(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="history.csv"`)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	cw := csv.NewWriter(w)
+	_ = cw.Write(domain.HistoryCSVHeader)
+	for _, record := range records {
+		_ = cw.Write(domain.HistoryCSVRow(record))
+	}
+	cw.Flush()
+}
+
+func parseHistoryQuery(values url.Values) (domain.HistoryQuery, error) {
+	q := domain.HistoryQuery{Limit: defaultHistoryLimit}
+
+	if v := values.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return q, fmt.Errorf("invalid since: %w", err)
+		}
+		q.Since = since
+	}
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+		}
+		q.Limit = limit
+	}
+	if v := values.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return q, errors.New("offset must be a non-negative integer")
+		}
+		q.Offset = offset
+	}
+	if v := values.Get("status"); v != "" {
+		status, err := domain.ParseApplyStatus(v)
+		if err != nil {
+			return q, err
+		}
+		q.Status = &status
+	}
+	if v := values.Get("trigger"); v != "" {
+		trigger, err := domain.ParseApplyTrigger(v)
+		if err != nil {
+			return q, err
+		}
+		q.Trigger = &trigger
+	}
+	return q, nil
+}
+
+func recordToView(record domain.ApplyRecord) map[string]any {
+	view := map[string]any{
+		"timestamp": record.Timestamp,
+		"volume":    record.Volume,
+		"status":    record.Status.String(),
+		"trigger":   record.Trigger.String(),
+	}
+	if record.Error != "" {
+		view["error"] = record.Error
+	}
+	if record.Warning != "" {
+		view["warning"] = record.Warning
+	}
+	if record.Observed != nil {
+		view["observed"] = *record.Observed
+	}
+	if record.SignificantDrift {
+		view["significantDrift"] = true
+	}
+	if record.DryRun {
+		view["dryRun"] = true
+	}
+	if record.Duration > 0 {
+		view["durationMs"] = record.Duration.Milliseconds()
+	}
+	return view
+}
+
+func decisionToView(d domain.ApplyDecision) map[string]any {
+	factors := make([]map[string]any, 0, len(d.Factors))
+	for _, f := range d.Factors {
+		factors = append(factors, map[string]any{
+			"name":  f.Name,
+			"value": f.Value,
+			"gated": f.Gated,
+		})
+	}
+	return map[string]any{
+		"apply":   d.Apply,
+		"factors": factors,
+	}
+}
+
+func planToView(p domain.ApplyPlan) map[string]any {
+	view := map[string]any{
+		"source":     p.Source,
+		"volume":     p.Volume,
+		"raised":     p.Raised,
+		"controller": p.Controller,
+		"verify":     p.Verify,
+		"enabled":    p.Enabled,
+	}
+	if p.Requested >= 0 {
+		view["requested"] = p.Requested
+	}
+	if p.Verify {
+		view["tolerance"] = p.Tolerance
+	}
+	if p.Device != "" {
+		view["device"] = p.Device
+	}
+	if p.PreApplyCmd != "" {
+		view["preApplyCmd"] = p.PreApplyCmd
+		view["abortOnPreApplyFailure"] = p.AbortOnPreApplyFailure
+	}
+	if p.PostApplyCmd != "" {
+		view["postApplyCmd"] = p.PostApplyCmd
+	}
+	return view
+}
+
+func profileToView(p domain.Profile, active bool) map[string]any {
+	view := map[string]any{
+		"name":            p.Name,
+		"targetVolume":    p.TargetVolume,
+		"intervalSeconds": p.Interval.Seconds(),
+		"enabled":         p.Enabled,
+		"active":          active,
+	}
+	if len(p.Curve) > 0 {
+		view["curve"] = domain.FormatCurve(p.Curve)
+	}
+	return view
+}
+
+// allowedVolumesView returns an empty list rather than null, so that the UI
+// can test its length.
+func allowedVolumesView(volumes []int) []int {
+	if volumes == nil {
+		return []int{}
+	}
+	return volumes
+}
+
 func snapshotToView(snap domain.Snapshot) map[string]any {
+	service := domain.NewSchedulerService()
 	var nextRun *time.Time
 	if !snap.ScheduleState.NextRun.IsZero() {
 		nr := snap.ScheduleState.NextRun
@@ -112,24 +966,148 @@ func snapshotToView(snap domain.Snapshot) map[string]any {
 	}
 
 	cfg := map[string]any{
-		"targetVolume":    snap.Config.TargetVolume,
-		"intervalSeconds": snap.Config.Interval.Seconds(),
-		"enabled":         snap.Config.Enabled,
-		"lastApplyStatus": snap.ScheduleState.LastApplyStatus.String(),
+		"targetVolume":         snap.Config.TargetVolume,
+		"intervalSeconds":      snap.Config.Interval.Seconds(),
+		"enabled":              snap.Config.Enabled,
+		"lastApplyStatus":      service.ReportedStatus(snap.ScheduleState, snap.Config).String(),
+		"scheduleMode":         snap.Config.ScheduleMode.String(),
+		"schedule":             snap.Config.Schedule,
+		"timezone":             snap.Config.Timezone,
+		"deviceName":           snap.Config.DeviceName,
+		"onDeviceAbsent":       snap.Config.OnDeviceAbsent.String(),
+		"adaptiveInterval":     snap.Config.AdaptiveInterval,
+		"maxIntervalSeconds":   snap.Config.MaxInterval.Seconds(),
+		"minTargetVolume":      snap.Config.MinTargetVolume,
+		"errorThreshold":       snap.Config.ErrorThreshold,
+		"maxRetries":           snap.Config.MaxRetries,
+		"retryBackoffSeconds":  snap.Config.RetryDelay(1).Seconds(),
+		"rampDurationMs":       snap.Config.RampDuration.Milliseconds(),
+		"rampSteps":            snap.Config.Ramp().Steps,
+		"driftAlertThreshold":  snap.Config.DriftAlertThreshold,
+		"redactErrors":         snap.Config.RedactErrors,
+		"parkVolume":           snap.Config.ParkVolume,
+		"fadeOnPark":           snap.Config.FadeOnPark,
+		"reapplyOnPowerChange": snap.Config.ReapplyOnPowerChange,
+		"powerPollSeconds":     snap.Config.PowerPoll().Seconds(),
+		"configLocked":         snap.Config.Locked,
+		"dryRun":               snap.DryRun,
+		"allowedVolumes":       allowedVolumesView(snap.Config.AllowedVolumes),
+		"output": map[string]any{
+			"enabled": snap.Config.Output.Enabled,
+			"volume":  snap.Config.Output.Volume,
+		},
+		"noise": map[string]any{
+			"enabled":       snap.Config.Noise.Enabled,
+			"referenceDbfs": snap.Config.Noise.ReferenceDBFS,
+			"minVolume":     snap.Config.Noise.MinVolume,
+			"maxVolume":     snap.Config.Noise.MaxVolume,
+		},
+		"rescheduleOnManualApply":  snap.Config.RescheduleOnManualApply,
+		"staggerBetweenDevicesMs":  snap.Config.StaggerBetweenDevices.Milliseconds(),
+		"consecutiveFailures":      snap.ScheduleState.ConsecutiveFailures,
+		"effectiveIntervalSeconds": service.EffectiveInterval(snap.ScheduleState, snap.Config).Seconds(),
 	}
 
+	if len(snap.Config.AppVolumes) > 0 {
+		apps := make([]appVolumePayload, 0, len(snap.Config.AppVolumes))
+		for _, r := range snap.Config.AppVolumes {
+			apps = append(apps, appVolumePayload{App: r.App, Volume: r.Volume})
+		}
+		cfg["appVolumes"] = apps
+	}
+	if len(snap.Config.FormatVolumes) > 0 {
+		rules := make([]formatVolumePayload, 0, len(snap.Config.FormatVolumes))
+		for _, r := range snap.Config.FormatVolumes {
+			rules = append(rules, formatVolumePayload{SampleRate: r.SampleRate, Volume: r.Volume})
+		}
+		cfg["formatVolumes"] = rules
+	}
+	if len(snap.Config.Curve) > 0 {
+		curve := make([]curvePointPayload, 0, len(snap.Config.Curve))
+		for _, p := range snap.Config.Curve {
+			curve = append(curve, curvePointPayload{Time: domain.FormatClock(p.Minute), Volume: p.Volume})
+		}
+		cfg["curve"] = curve
+	}
+	if len(snap.Config.QuietHours) > 0 {
+		quiet := make([]timeWindowPayload, 0, len(snap.Config.QuietHours))
+		for _, w := range snap.Config.QuietHours {
+			p := timeWindowPayload{Start: domain.FormatClock(w.Start), End: domain.FormatClock(w.End)}
+			for _, day := range w.Weekdays {
+				p.Weekdays = append(p.Weekdays, domain.WeekdayLabel(day))
+			}
+			quiet = append(quiet, p)
+		}
+		cfg["quietHours"] = quiet
+		if until := snap.Config.QuietUntil(time.Now()); !until.IsZero() {
+			cfg["quietUntil"] = until
+		}
+	}
+	rate := service.SuccessRate(snap.ScheduleState)
+	cfg["successRate"] = map[string]any{
+		"percent":   rate.Percent(),
+		"successes": rate.Successes,
+		"attempts":  rate.Attempts,
+		"window":    domain.SuccessRateWindow,
+		"text":      rate.String(),
+	}
+	if check := snap.ScheduleState.StartupCheck; check != nil {
+		startup := map[string]any{
+			"at":     check.At,
+			"volume": check.Volume,
+			"target": check.Target,
+			"drift":  check.Drift(),
+			"text":   check.String(),
+		}
+		if check.Failed() {
+			startup = map[string]any{"at": check.At, "error": check.Error, "text": check.String()}
+		}
+		cfg["startupCheck"] = startup
+	}
+	if ambient := snap.ScheduleState.Ambient; snap.Config.Noise.Enabled && ambient.Active {
+		cfg["ambient"] = map[string]any{
+			"volume":    ambient.Volume,
+			"levelDbfs": ambient.Level,
+		}
+	}
 	if snap.ScheduleState.LastError != nil {
 		cfg["lastError"] = snap.ScheduleState.LastError.Error()
 	}
+	if snap.Config.ActiveProfile != "" {
+		cfg["activeProfile"] = snap.Config.ActiveProfile
+	}
+	if snap.ScheduleState.LastWarning != "" {
+		cfg["lastWarning"] = snap.ScheduleState.LastWarning
+	}
+	if observed := snap.ScheduleState.LastObservedVolume; observed != nil {
+		cfg["lastObservedVolume"] = *observed
+	}
+	if snap.ScheduleState.LastApplyDryRun {
+		cfg["lastApplyDryRun"] = true
+	}
 	if !snap.ScheduleState.LastApplied.IsZero() {
 		cfg["lastApplied"] = snap.ScheduleState.LastApplied
 	}
+	if !snap.ScheduleState.FirstApplied.IsZero() {
+		cfg["firstApplied"] = snap.ScheduleState.FirstApplied
+	}
+	if !snap.ScheduleState.LastWakeApply.IsZero() {
+		cfg["lastWakeApply"] = snap.ScheduleState.LastWakeApply
+	}
 
-	return map[string]any{
+	view := map[string]any{
 		"config":  cfg,
 		"nextRun": nextRun,
 		"idle":    !snap.ScheduleState.IsRunning,
+		"locked":  snap.ScheduleState.Hold.Active,
 	}
+	if snap.ScheduleState.Hold.Active {
+		view["lock"] = map[string]any{
+			"volume": snap.ScheduleState.Hold.Volume,
+			"since":  snap.ScheduleState.Hold.Since,
+		}
+	}
+	return view
 }
 
 type updatePayload struct {
@@ -137,6 +1115,134 @@ type updatePayload struct {
 	IntervalSeconds *float64 `json:"intervalSeconds"`
 	Enabled         *bool    `json:"enabled"`
 	ApplyNow        bool     `json:"applyNow"`
+	ScheduleMode    *string  `json:"scheduleMode"`
+	// Schedule is a cron expression; empty goes back to the interval.
+	Schedule           *string  `json:"schedule"`
+	AdaptiveInterval   *bool    `json:"adaptiveInterval"`
+	MaxIntervalSeconds *float64 `json:"maxIntervalSeconds"`
+	MinTargetVolume    *int     `json:"minTargetVolume"`
+	AllowedVolumes     *[]int   `json:"allowedVolumes"`
+	ErrorThreshold     *int     `json:"errorThreshold"`
+	MaxRetries         *int     `json:"maxRetries"`
+	// RetryBackoffSeconds of 0 selects the default backoff.
+	RetryBackoffSeconds *float64 `json:"retryBackoffSeconds"`
+	// RampDurationMs of 0 sets volumes at once; RampSteps of 0 selects the
+	// default step count.
+	RampDurationMs *int `json:"rampDurationMs"`
+	RampSteps      *int `json:"rampSteps"`
+	// StaggerBetweenDevicesMs of 0 sets the input, apps and output back to
+	// back.
+	StaggerBetweenDevicesMs *int `json:"staggerBetweenDevicesMs"`
+	// DriftAlertThreshold of 0 turns the drift alert off.
+	DriftAlertThreshold *int `json:"driftAlertThreshold"`
+	// Timezone is an IANA zone name; empty selects the system zone.
+	Timezone *string `json:"timezone"`
+	// DeviceName is the input device to target; empty selects the system
+	// default input.
+	DeviceName *string `json:"deviceName"`
+	// OnDeviceAbsent is "error", "skip" or "fallback-default".
+	OnDeviceAbsent *string `json:"onDeviceAbsent"`
+	// FormatVolumes replaces all per-sample-rate rules; an empty list
+	// removes them.
+	FormatVolumes *[]formatVolumePayload `json:"formatVolumes"`
+	// ParkVolume of -1 removes the park volume.
+	ParkVolume           *int  `json:"parkVolume"`
+	FadeOnPark           *bool `json:"fadeOnPark"`
+	ReapplyOnPowerChange *bool `json:"reapplyOnPowerChange"`
+	// PowerPollSeconds of 0 selects the default poll interval.
+	PowerPollSeconds *float64 `json:"powerPollSeconds"`
+	// AppVolumes replaces all per-app rules; an empty list removes them.
+	AppVolumes *[]appVolumePayload `json:"appVolumes"`
+	// DryRun simulates applies; a process started with --dry-run simulates
+	// them whatever this says.
+	DryRun *bool `json:"dryRun"`
+	// Output updates only the output lock settings it sets.
+	Output *outputPayload `json:"output"`
+	// Noise updates only the noise settings it sets.
+	Noise *noisePayload `json:"noise"`
+	// Curve replaces the whole curve; an empty list removes it.
+	Curve *[]curvePointPayload `json:"curve"`
+	// QuietHours replaces all quiet-hours windows; an empty list removes
+	// them.
+	QuietHours *[]timeWindowPayload `json:"quietHours"`
+	// RescheduleOnManualApply false keeps the next run through manual
+	// applies.
+	RescheduleOnManualApply *bool `json:"rescheduleOnManualApply"`
+}
+
+type appVolumePayload struct {
+	App    string `json:"app"`
+	Volume int    `json:"volume"`
+}
+
+type formatVolumePayload struct {
+	SampleRate int `json:"sampleRate"`
+	Volume     int `json:"volume"`
+}
+
+type outputPayload struct {
+	Enabled *bool `json:"enabled"`
+	Volume  *int  `json:"volume"`
+}
+
+type noisePayload struct {
+	Enabled       *bool    `json:"enabled"`
+	ReferenceDBFS *float64 `json:"referenceDbfs"`
+	MinVolume     *int     `json:"minVolume"`
+	MaxVolume     *int     `json:"maxVolume"`
+}
+
+type curvePointPayload struct {
+	Time   string `json:"time"`
+	Volume int    `json:"volume"`
+}
+
+func curveFromPayload(payload []curvePointPayload) ([]domain.CurvePoint, error) {
+	var curve []domain.CurvePoint
+	for _, p := range payload {
+		minute, err := domain.ParseClock(p.Time)
+		if err != nil {
+			return nil, err
+		}
+		curve = append(curve, domain.CurvePoint{Minute: minute, Volume: p.Volume})
+	}
+	return curve, nil
+}
+
+// timeWindowPayload is a quiet-hours window; Weekdays holds labels such as
+// "mon" and is empty for every day.
+type timeWindowPayload struct {
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Weekdays []string `json:"weekdays,omitempty"`
+}
+
+func quietHoursFromPayload(payload []timeWindowPayload) ([]domain.TimeWindow, error) {
+	var windows []domain.TimeWindow
+	for _, p := range payload {
+		start, err := domain.ParseClock(p.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := domain.ParseClock(p.End)
+		if err != nil {
+			return nil, err
+		}
+		w := domain.TimeWindow{Start: start, End: end}
+		for _, label := range p.Weekdays {
+			day, err := domain.ParseWeekday(label)
+			if err != nil {
+				return nil, err
+			}
+			w.Weekdays = append(w.Weekdays, day)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+type lockPayload struct {
+	Volume *int `json:"volume"`
 }
 
 func respondJSON(w http.ResponseWriter, status int, payload any) {
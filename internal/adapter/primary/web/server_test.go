@@ -0,0 +1,277 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// fakeUseCase serves a fixed config and records updates and applies. The
+// embedded UseCase is nil, so a handler calling anything else panics.
+type fakeUseCase struct {
+	UseCase
+	mu        sync.Mutex
+	config    domain.Config
+	updateErr error
+	applyErr  error
+	updates   int
+	applies   int
+	holds     int
+}
+
+func newFakeUseCase() *fakeUseCase {
+	return &fakeUseCase{config: domain.DefaultConfig()}
+}
+
+func (f *fakeUseCase) GetSnapshot() domain.Snapshot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return domain.Snapshot{Config: f.config}
+}
+
+func (f *fakeUseCase) UpdateConfig(config domain.Config, applyNow bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.updateErr != nil {
+		return f.updateErr
+	}
+	f.config = config
+	f.updates++
+	if applyNow {
+		f.applies++
+	}
+	return nil
+}
+
+func (f *fakeUseCase) ApplyNow(int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.applyErr != nil {
+		return f.applyErr
+	}
+	f.applies++
+	return nil
+}
+
+func (f *fakeUseCase) ApplyIfEnabled(volume int) error {
+	if !f.GetSnapshot().Config.Enabled {
+		return domain.ErrNotEnabled
+	}
+	return f.ApplyNow(volume)
+}
+
+func (f *fakeUseCase) Hold(int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.holds++
+	return nil
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConfigAPI(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		body        string
+		updateErr   error
+		wantStatus  int
+		wantTarget  int
+		wantUpdates int
+		wantApplies int
+	}{
+		{"get", http.MethodGet, "", nil, http.StatusOK, 50, 0, 0},
+		{"put", http.MethodPut, `{"targetVolume": 70}`, nil, http.StatusOK, 70, 1, 0},
+		{"put and apply", http.MethodPut, `{"targetVolume": 70, "applyNow": true}`, nil, http.StatusOK, 70, 1, 1},
+		{"invalid JSON", http.MethodPut, `{"targetVolume":`, nil, http.StatusBadRequest, 50, 0, 0},
+		{"invalid interval", http.MethodPut, `{"intervalSeconds": -1}`, nil, http.StatusBadRequest, 50, 0, 0},
+		{"invalid config", http.MethodPut, `{"targetVolume": 70}`, fmt.Errorf("%w: target", domain.ErrInvalidConfig), http.StatusBadRequest, 50, 0, 0},
+		{"locked", http.MethodPut, `{"targetVolume": 70}`, domain.ErrConfigLocked, http.StatusForbidden, 50, 0, 0},
+		{"busy", http.MethodPut, `{"targetVolume": 70}`, domain.ErrConfigBusy, http.StatusConflict, 50, 0, 0},
+		{"failed save", http.MethodPut, `{"targetVolume": 70}`, errors.New("disk full"), http.StatusInternalServerError, 50, 0, 0},
+		{"method", http.MethodDelete, "", nil, http.StatusMethodNotAllowed, 50, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newFakeUseCase()
+			uc.updateErr = tt.updateErr
+			h := NewServer(uc, "").Handler()
+
+			rec := do(t, h, tt.method, "/api/config", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code == http.StatusOK {
+				var view struct {
+					Config struct {
+						TargetVolume int `json:"targetVolume"`
+					} `json:"config"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &view); err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if view.Config.TargetVolume != tt.wantTarget {
+					t.Errorf("targetVolume = %d, want %d", view.Config.TargetVolume, tt.wantTarget)
+				}
+			}
+			if uc.updates != tt.wantUpdates || uc.applies != tt.wantApplies {
+				t.Errorf("updates, applies = %d, %d, want %d, %d", uc.updates, uc.applies, tt.wantUpdates, tt.wantApplies)
+			}
+		})
+	}
+}
+
+func TestApplyAPI(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		disabled    bool
+		applyErr    error
+		wantStatus  int
+		wantApplies int
+	}{
+		{"apply", http.MethodPost, "/api/apply", false, nil, http.StatusOK, 1},
+		{"apply while disabled", http.MethodPost, "/api/apply", true, nil, http.StatusOK, 1},
+		{"respect enabled", http.MethodPost, "/api/apply?respectEnabled=true", false, nil, http.StatusOK, 1},
+		{"respect disabled", http.MethodPost, "/api/apply?respectEnabled=true", true, nil, http.StatusConflict, 0},
+		{"failed apply", http.MethodPost, "/api/apply", false, errors.New("no device"), http.StatusInternalServerError, 0},
+		{"method", http.MethodGet, "/api/apply", false, nil, http.StatusMethodNotAllowed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newFakeUseCase()
+			uc.config.Enabled = !tt.disabled
+			uc.applyErr = tt.applyErr
+			h := NewServer(uc, "").Handler()
+
+			if rec := do(t, h, tt.method, tt.path, ""); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if uc.applies != tt.wantApplies {
+				t.Errorf("applies = %d, want %d", uc.applies, tt.wantApplies)
+			}
+		})
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header []string
+		want   int
+	}{
+		{"no token", "/api/config", nil, http.StatusUnauthorized},
+		{"wrong token", "/api/config", []string{"Authorization", "Bearer nope"}, http.StatusUnauthorized},
+		{"token", "/api/config", []string{"Authorization", "Bearer secret"}, http.StatusOK},
+		{"query token outside events", "/api/config?access_token=secret", nil, http.StatusUnauthorized},
+		{"healthz is public", "/healthz", nil, http.StatusOK},
+		{"UI is public", "/", nil, http.StatusOK},
+	}
+	h := NewServer(newFakeUseCase(), "", WithAuthToken("secret")).Handler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, h, http.MethodGet, tt.path, "", tt.header...)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestApplyRateLimit(t *testing.T) {
+	uc := newFakeUseCase()
+	h := NewServer(uc, "", WithApplyRateLimit(0.5, 2)).Handler()
+
+	for i := 1; i <= 2; i++ {
+		if rec := do(t, h, http.MethodPost, "/api/apply", ""); rec.Code != http.StatusOK {
+			t.Fatalf("apply %d within the burst: status %d", i, rec.Code)
+		}
+	}
+	rec := do(t, h, http.MethodPost, "/api/apply", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("apply over the burst: status %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	// A PUT that applies takes from the same bucket, a plain one does not
+	if rec := do(t, h, http.MethodPut, "/api/config", `{"applyNow": true}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("PUT with applyNow: status %d, want 429", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPut, "/api/config", `{"targetVolume": 60}`); rec.Code != http.StatusOK {
+		t.Errorf("PUT without applyNow: status %d, want 200", rec.Code)
+	}
+	if rec := do(t, h, http.MethodPost, "/api/lock", `{"volume": 60}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("POST /api/lock: status %d, want 429", rec.Code)
+	}
+	if uc.applies != 2 || uc.holds != 0 {
+		t.Errorf("applies = %d, holds = %d, want 2 and 0", uc.applies, uc.holds)
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	b := newTokenBucket(1, 1, func() time.Time { return now })
+	if ok, _ := b.take(); !ok {
+		t.Fatal("full bucket refused")
+	}
+	if ok, wait := b.take(); ok || wait != time.Second {
+		t.Fatalf("empty bucket = %v, %s, want refused for 1s", ok, wait)
+	}
+	now = now.Add(time.Second)
+	if ok, _ := b.take(); !ok {
+		t.Error("bucket did not refill after 1s")
+	}
+}
+
+func TestConfigIntervalSeconds(t *testing.T) {
+	tests := []struct {
+		seconds    string
+		wantStatus int
+		want       time.Duration
+	}{
+		{"0", http.StatusBadRequest, 90 * time.Second},
+		{"0.5", http.StatusBadRequest, 90 * time.Second},
+		{"1.5", http.StatusOK, 1500 * time.Millisecond},
+		{"90", http.StatusOK, 90 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.seconds, func(t *testing.T) {
+			uc := newFakeUseCase()
+			h := NewServer(uc, "").Handler()
+
+			rec := do(t, h, http.MethodPut, "/api/config", `{"intervalSeconds": `+tt.seconds+`}`)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "intervalSeconds must be at least") {
+				t.Errorf("error %q does not explain the minimum", rec.Body)
+			}
+			if got := uc.GetSnapshot().Config.Interval; got != tt.want {
+				t.Errorf("interval = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
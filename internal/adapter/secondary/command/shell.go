@@ -0,0 +1,42 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// ShellRunner implements domain.CommandRunner by running each command
+// through /bin/sh, so that pipes and quoting work as in a terminal.
+// This is a secondary adapter.
+type ShellRunner struct{}
+
+// NewShellRunner creates a new shell command runner.
+func NewShellRunner() domain.CommandRunner {
+	return &ShellRunner{}
+}
+
+// Run executes command and kills it once timeout has passed.
+func (r *ShellRunner) Run(command string, env map[string]string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	// Do not wait on pipes held open by a background child after a kill
+	cmd.WaitDelay = time.Second
+
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output, fmt.Errorf("timed out after %s", timeout)
+	}
+	return output, err
+}
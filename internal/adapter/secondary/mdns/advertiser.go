@@ -0,0 +1,202 @@
+// Package mdns advertises the web server on the local network with
+// multicast DNS (DNS-SD), so that clients can discover running instances.
+// It implements only the responder subset needed for a single service and
+// depends on the standard library alone.
+package mdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"micgain-manager/internal/logging"
+)
+
+// ServiceType is the DNS-SD service type advertised for the web server.
+const ServiceType = "_micgain._tcp"
+
+const (
+	domain = "local."
+	ttl    = 120
+)
+
+var groupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Service describes what is advertised.
+type Service struct {
+	// Instance is the human readable instance name, e.g. the hostname.
+	Instance string
+	Port     int
+	// Text holds "key=value" entries for the TXT record.
+	Text []string
+}
+
+// Advertiser answers mDNS queries for one service until Shutdown.
+type Advertiser struct {
+	service  Service
+	host     string
+	ips      []net.IP
+	conn     *net.UDPConn
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Advertise starts answering queries for the service and announces it.
+func Advertise(service Service) (*Advertiser, error) {
+	if service.Port <= 0 {
+		return nil, errors.New("port is required")
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("hostname: %w", err)
+	}
+	host, _, _ = strings.Cut(host, ".")
+	if service.Instance == "" {
+		service.Instance = host
+	}
+
+	ips := localIPv4s()
+	if len(ips) == 0 {
+		return nil, errors.New("no non-loopback IPv4 address to advertise")
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, groupAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listen mdns: %w", err)
+	}
+
+	a := &Advertiser{
+		service: service,
+		host:    host + "." + domain,
+		ips:     ips,
+		conn:    conn,
+		done:    make(chan struct{}),
+	}
+	go a.serve()
+	go a.announce()
+	logging.Infof("advertising %s.%s.%s on port %d", service.Instance, ServiceType, domain, service.Port)
+	return a, nil
+}
+
+// Shutdown withdraws the advertisement and stops answering queries.
+func (a *Advertiser) Shutdown(ctx context.Context) error {
+	var err error
+	a.stopOnce.Do(func() {
+		close(a.done)
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = a.conn.SetWriteDeadline(deadline)
+		}
+		// A zero TTL tells caches to drop the records immediately
+		err = a.send(a.response(0))
+		a.conn.Close()
+	})
+	return err
+}
+
+func (a *Advertiser) serviceName() string {
+	return ServiceType + "." + domain
+}
+
+func (a *Advertiser) instanceName() string {
+	return a.service.Instance + "." + a.serviceName()
+}
+
+// announce sends unsolicited responses at startup, as RFC 6762 asks.
+func (a *Advertiser) announce() {
+	for i := 0; i < 2; i++ {
+		if err := a.send(a.response(ttl)); err != nil {
+			logging.Warnf("mdns announce: %v", err)
+		}
+		select {
+		case <-a.done:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (a *Advertiser) serve() {
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-a.done:
+				return
+			default:
+			}
+			// A closed or broken socket fails every read; only a timeout
+			// is worth reading again
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			logging.Warnf("mdns read: %v; no longer answering queries", err)
+			return
+		}
+		if a.wantsUs(buf[:n]) {
+			if err := a.send(a.response(ttl)); err != nil {
+				logging.Warnf("mdns respond: %v", err)
+			}
+		}
+	}
+}
+
+// wantsUs reports whether msg is a query for any of our records.
+func (a *Advertiser) wantsUs(msg []byte) bool {
+	questions, err := parseQuestions(msg)
+	if err != nil {
+		return false
+	}
+	for _, q := range questions {
+		switch {
+		case strings.EqualFold(q.name, a.serviceName()) && (q.qtype == typePTR || q.qtype == typeANY):
+			return true
+		case strings.EqualFold(q.name, a.instanceName()) && (q.qtype == typeSRV || q.qtype == typeTXT || q.qtype == typeANY):
+			return true
+		case strings.EqualFold(q.name, a.host) && (q.qtype == typeA || q.qtype == typeANY):
+			return true
+		}
+	}
+	return false
+}
+
+// response builds the full record set with the given TTL.
+func (a *Advertiser) response(recordTTL uint32) []byte {
+	var records []record
+	records = append(records,
+		record{name: a.serviceName(), rtype: typePTR, ttl: recordTTL, data: encodeName(a.instanceName())},
+		record{name: a.instanceName(), rtype: typeSRV, ttl: recordTTL, flush: true, data: srvData(a.service.Port, a.host)},
+		record{name: a.instanceName(), rtype: typeTXT, ttl: recordTTL, flush: true, data: txtData(a.service.Text)},
+	)
+	for _, ip := range a.ips {
+		records = append(records, record{name: a.host, rtype: typeA, ttl: recordTTL, flush: true, data: ip.To4()})
+	}
+	return encodeResponse(records)
+}
+
+func (a *Advertiser) send(msg []byte) error {
+	_, err := a.conn.WriteToUDP(msg, groupAddr)
+	return err
+}
+
+func localIPv4s() []net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+			continue
+		}
+		ips = append(ips, ipNet.IP.To4())
+	}
+	return ips
+}
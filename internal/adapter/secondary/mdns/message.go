@@ -0,0 +1,151 @@
+package mdns
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// DNS record types used by DNS-SD.
+const (
+	typeA   uint16 = 1
+	typePTR uint16 = 12
+	typeTXT uint16 = 16
+	typeSRV uint16 = 33
+	typeANY uint16 = 255
+)
+
+const (
+	classIN    uint16 = 1
+	cacheFlush uint16 = 0x8000
+)
+
+var errMalformed = errors.New("malformed dns message")
+
+type question struct {
+	name  string
+	qtype uint16
+}
+
+type record struct {
+	name  string
+	rtype uint16
+	ttl   uint32
+	flush bool
+	data  []byte
+}
+
+// parseQuestions returns the questions of a query. Responses are ignored.
+func parseQuestions(msg []byte) ([]question, error) {
+	if len(msg) < 12 {
+		return nil, errMalformed
+	}
+	if msg[2]&0x80 != 0 {
+		return nil, nil
+	}
+	count := int(binary.BigEndian.Uint16(msg[4:6]))
+
+	questions := make([]question, 0, count)
+	off := 12
+	for i := 0; i < count; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(msg) {
+			return nil, errMalformed
+		}
+		questions = append(questions, question{
+			name:  name,
+			qtype: binary.BigEndian.Uint16(msg[next : next+2]),
+		})
+		off = next + 4
+	}
+	return questions, nil
+}
+
+// readName decodes a possibly compressed name at off and returns it with
+// the offset just past it.
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errMalformed
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case n&0xC0 == 0xC0:
+			if off+1 >= len(msg) || jumps > 16 {
+				return "", 0, errMalformed
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:off+2]) & 0x3FFF)
+			jumps++
+		case n&0xC0 != 0:
+			// The 0x40 and 0x80 label types are reserved
+			return "", 0, errMalformed
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+// encodeName encodes a dotted name without compression.
+func encodeName(name string) []byte {
+	var buf []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+	return append(buf, 0)
+}
+
+func srvData(port int, target string) []byte {
+	buf := make([]byte, 6)
+	binary.BigEndian.PutUint16(buf[4:], uint16(port))
+	return append(buf, encodeName(target)...)
+}
+
+func txtData(entries []string) []byte {
+	if len(entries) == 0 {
+		return []byte{0}
+	}
+	var buf []byte
+	for _, e := range entries {
+		buf = append(buf, byte(len(e)))
+		buf = append(buf, e...)
+	}
+	return buf
+}
+
+// encodeResponse builds an authoritative mDNS response holding records.
+func encodeResponse(records []record) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[2:], 0x8400)
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(records)))
+	for _, r := range records {
+		msg = append(msg, encodeName(r.name)...)
+		class := classIN
+		if r.flush {
+			class |= cacheFlush
+		}
+		msg = binary.BigEndian.AppendUint16(msg, r.rtype)
+		msg = binary.BigEndian.AppendUint16(msg, class)
+		msg = binary.BigEndian.AppendUint32(msg, r.ttl)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(r.data)))
+		msg = append(msg, r.data...)
+	}
+	return msg
+}
@@ -0,0 +1,110 @@
+package mdns
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// query builds a query header for count questions followed by body.
+func query(count int, body ...byte) []byte {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[4:], uint16(count))
+	return append(msg, body...)
+}
+
+// tail is the type and class of a PTR question.
+var tail = []byte{0, byte(typePTR), 0, byte(classIN)}
+
+func TestParseQuestions(t *testing.T) {
+	name := encodeName("_micgain._tcp.local.")
+	tests := []struct {
+		name    string
+		msg     []byte
+		want    []question
+		wantErr bool
+	}{
+		{"plain", query(1, append(name, tail...)...), []question{{"_micgain._tcp.local.", typePTR}}, false},
+		{
+			// The second name is "host" followed by a pointer to the
+			// "local" label of the first, past the header and 9+5 label bytes
+			"compressed",
+			query(2, slices.Concat(name, tail, []byte{4, 'h', 'o', 's', 't', 0xC0, 12 + 14}, []byte{0, byte(typeA), 0, byte(classIN)})...),
+			[]question{{"_micgain._tcp.local.", typePTR}, {"host.local.", typeA}},
+			false,
+		},
+		{"response ignored", func() []byte { m := query(1, append(name, tail...)...); m[2] = 0x84; return m }(), nil, false},
+		{"short header", make([]byte, 11), nil, true},
+		{"more questions than sent", query(2, append(name, tail...)...), nil, true},
+		{"truncated label", query(1, 8, 'h', 'o'), nil, true},
+		{"unterminated name", query(1, 4, 'h', 'o', 's', 't'), nil, true},
+		{"truncated type", query(1, append(name, 0, byte(typePTR))...), nil, true},
+		{"truncated pointer", query(1, 0xC0), nil, true},
+		{"pointer past the end", query(1, 0xC0, 0xFF), nil, true},
+		{"pointer to itself", query(1, 0xC0, 12), nil, true},
+		{"pointer loop", query(1, slices.Concat([]byte{1, 'a', 0xC0, 14}, []byte{1, 'b', 0xC0, 12})...), nil, true},
+		{"reserved label type", query(1, 0x40, 'a', 0), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseQuestions(tt.msg)
+			if tt.wantErr {
+				if !errors.Is(err, errMalformed) {
+					t.Fatalf("parseQuestions = %v, %v, want errMalformed", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseQuestions: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("questions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeResponse(t *testing.T) {
+	msg := encodeResponse([]record{
+		{name: "_micgain._tcp.local.", rtype: typePTR, ttl: 120, data: encodeName("mac._micgain._tcp.local.")},
+		{name: "mac.local.", rtype: typeA, ttl: 0, flush: true, data: []byte{192, 168, 0, 2}},
+	})
+	if flags := binary.BigEndian.Uint16(msg[2:]); flags != 0x8400 {
+		t.Errorf("flags = %#x, want an authoritative response", flags)
+	}
+	if n := binary.BigEndian.Uint16(msg[6:]); n != 2 {
+		t.Fatalf("answer count = %d, want 2", n)
+	}
+
+	off := 12
+	for _, want := range []struct {
+		name  string
+		class uint16
+		ttl   uint32
+	}{{"_micgain._tcp.local.", classIN, 120}, {"mac.local.", classIN | cacheFlush, 0}} {
+		name, next, err := readName(msg, off)
+		if err != nil || name != want.name {
+			t.Fatalf("record name = %q, %v, want %q", name, err, want.name)
+		}
+		if class := binary.BigEndian.Uint16(msg[next+2:]); class != want.class {
+			t.Errorf("%s class = %#x, want %#x", name, class, want.class)
+		}
+		if got := binary.BigEndian.Uint32(msg[next+4:]); got != want.ttl {
+			t.Errorf("%s ttl = %d, want %d", name, got, want.ttl)
+		}
+		off = next + 10 + int(binary.BigEndian.Uint16(msg[next+8:]))
+	}
+	if off != len(msg) {
+		t.Errorf("%d trailing bytes", len(msg)-off)
+	}
+}
+
+func TestTXTData(t *testing.T) {
+	if got := txtData(nil); !slices.Equal(got, []byte{0}) {
+		t.Errorf("empty TXT = %v, want a single empty string", got)
+	}
+	if got := txtData([]string{"v=1", "tls=0"}); !slices.Equal(got, []byte("\x03v=1\x05tls=0")) {
+		t.Errorf("TXT = %q", got)
+	}
+}
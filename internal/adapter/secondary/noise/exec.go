@@ -0,0 +1,73 @@
+package noise
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// DefaultExecTimeout bounds each call of an external sensor command.
+const DefaultExecTimeout = 5 * time.Second
+
+// ExecSensor implements domain.NoiseSensor by running an external command
+// that prints the current input level in dBFS (e.g. "-42.5") on stdout,
+// so any metering helper can drive the noise adaptive target. A non-zero
+// exit is an error.
+// This is a secondary adapter.
+type ExecSensor struct {
+	argv    []string
+	timeout time.Duration
+}
+
+// NewExecSensor creates a sensor running argv on each reading.
+// It fails early when the command cannot be found.
+func NewExecSensor(argv []string, timeout time.Duration) (domain.NoiseSensor, error) {
+	if len(argv) == 0 {
+		return nil, errors.New("exec sensor: no command given")
+	}
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		return nil, fmt.Errorf("exec sensor: command %q not found: %w", argv[0], err)
+	}
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	return &ExecSensor{argv: argv, timeout: timeout}, nil
+}
+
+// Level runs the command and parses the level it prints.
+func (e *ExecSensor) Level() (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.argv[0], e.argv[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return 0, fmt.Errorf("exec sensor: %s timed out after %s", e.argv[0], e.timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return 0, fmt.Errorf("exec sensor: %s exited with %d: %s", e.argv[0], exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("exec sensor: %s: %w", e.argv[0], err)
+	}
+
+	out := strings.TrimSpace(stdout.String())
+	level, err := strconv.ParseFloat(out, 64)
+	if err != nil || level > 0 {
+		return 0, fmt.Errorf("exec sensor: %s printed %q, want a level in dBFS (0 or below)", e.argv[0], out)
+	}
+	return level, nil
+}
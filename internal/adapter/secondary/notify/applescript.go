@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// osascriptTimeout bounds each notification.
+const osascriptTimeout = 5 * time.Second
+
+// AppleScriptNotifier implements domain.Notifier with macOS notifications
+// posted by osascript's "display notification".
+// This is a secondary adapter.
+type AppleScriptNotifier struct{}
+
+// NewAppleScriptNotifier creates a notifier backed by osascript.
+func NewAppleScriptNotifier() domain.Notifier {
+	return &AppleScriptNotifier{}
+}
+
+// Notify posts a notification with the given title and message.
+func (a *AppleScriptNotifier) Notify(title, message string) error {
+	script := fmt.Sprintf("display notification %s with title %s", quoteAppleScript(message), quoteAppleScript(title))
+
+	ctx, cancel := context.WithTimeout(context.Background(), osascriptTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "osascript", "-e", script)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("osascript failed: %w, output: %s", err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// quoteAppleScript renders s as an AppleScript string literal.
+func quoteAppleScript(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
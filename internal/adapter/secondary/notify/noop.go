@@ -0,0 +1,16 @@
+package notify
+
+import "micgain-manager/internal/domain"
+
+// NoopNotifier implements domain.Notifier by dropping every notification.
+type NoopNotifier struct{}
+
+// NewNoopNotifier creates a notifier that does nothing.
+func NewNoopNotifier() domain.Notifier {
+	return &NoopNotifier{}
+}
+
+// Notify does nothing and always succeeds.
+func (n *NoopNotifier) Notify(title, message string) error {
+	return nil
+}
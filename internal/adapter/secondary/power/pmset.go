@@ -0,0 +1,48 @@
+package power
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// pmsetTimeout bounds each pmset call.
+const pmsetTimeout = 5 * time.Second
+
+// PmsetSource implements domain.PowerSource with macOS pmset, whose
+// "pmset -g ps" starts with e.g. "Now drawing from 'AC Power'". Where
+// pmset is not available it returns domain.ErrNotSupported.
+// This is a secondary adapter.
+type PmsetSource struct{}
+
+// NewPmsetSource creates a power source backed by pmset.
+func NewPmsetSource() domain.PowerSource {
+	return &PmsetSource{}
+}
+
+// Source reads the current power source.
+func (p *PmsetSource) Source() (string, error) {
+	if _, err := exec.LookPath("pmset"); err != nil {
+		return "", domain.ErrNotSupported
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pmsetTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "pmset", "-g", "ps").Output()
+	if err != nil {
+		return "", fmt.Errorf("pmset: %w", err)
+	}
+
+	first, _, _ := bytes.Cut(out, []byte("\n"))
+	_, rest, ok := strings.Cut(string(first), "'")
+	source, _, closed := strings.Cut(rest, "'")
+	if !ok || !closed || source == "" {
+		return "", fmt.Errorf("pmset: unexpected output %q", first)
+	}
+	return source, nil
+}
@@ -0,0 +1,193 @@
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRepo is the GitHub repository releases are looked up in.
+const DefaultRepo = "koinunopochi/micgain-manager"
+
+// checksumsAsset is the release asset listing "sha256  filename" lines.
+const checksumsAsset = "checksums.txt"
+
+// maxDownloadSize caps every response body read, so a misbehaving server
+// cannot make the update fill memory.
+const maxDownloadSize = 256 << 20
+
+// Release is a published release and its downloadable assets.
+type Release struct {
+	Tag    string
+	URL    string
+	Assets []Asset
+}
+
+// Asset is a single file attached to a release.
+type Asset struct {
+	Name        string
+	DownloadURL string
+}
+
+// GitHubClient reads releases from the GitHub REST API.
+// This is a secondary adapter.
+type GitHubClient struct {
+	repo   string
+	client *http.Client
+}
+
+// NewGitHubClient creates a client for the given "owner/name" repository.
+func NewGitHubClient(repo string) *GitHubClient {
+	return &GitHubClient{
+		repo:   repo,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Latest returns the latest published release.
+func (g *GitHubClient) Latest(ctx context.Context) (Release, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", g.repo)
+	body, err := g.get(ctx, url, "application/vnd.github+json")
+	if err != nil {
+		return Release{}, err
+	}
+
+	var payload struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+		Assets  []struct {
+			Name               string `json:"name"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Release{}, fmt.Errorf("decode release: %w", err)
+	}
+
+	release := Release{Tag: payload.TagName, URL: payload.HTMLURL}
+	for _, a := range payload.Assets {
+		release.Assets = append(release.Assets, Asset{Name: a.Name, DownloadURL: a.BrowserDownloadURL})
+	}
+	return release, nil
+}
+
+// DownloadVerified downloads the asset and checks it against the sha256
+// listed in the release's checksums.txt.
+func (g *GitHubClient) DownloadVerified(ctx context.Context, release Release, asset Asset) ([]byte, error) {
+	var sums *Asset
+	for i := range release.Assets {
+		if release.Assets[i].Name == checksumsAsset {
+			sums = &release.Assets[i]
+		}
+	}
+	if sums == nil {
+		return nil, fmt.Errorf("release %s has no %s", release.Tag, checksumsAsset)
+	}
+
+	list, err := g.get(ctx, sums.DownloadURL, "")
+	if err != nil {
+		return nil, err
+	}
+	want, err := findChecksum(list, asset.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := g.get(ctx, asset.DownloadURL, "")
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %s, want %s", asset.Name, got, want)
+	}
+	return data, nil
+}
+
+func (g *GitHubClient) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)
+	}
+	return readLimited(resp.Body, maxDownloadSize)
+}
+
+// readLimited reads r to the end, failing once it goes past limit bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+func findChecksum(list []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum listed for %s", name)
+}
+
+// Newer reports whether the tag is a higher version than current.
+// Both are "vMAJOR.MINOR.PATCH"; anything else cannot be compared.
+func Newer(tag, current string) (bool, error) {
+	latest, err := parseVersion(tag)
+	if err != nil {
+		return false, err
+	}
+	running, err := parseVersion(current)
+	if err != nil {
+		return false, err
+	}
+	for i := range latest {
+		if latest[i] != running[i] {
+			return latest[i] > running[i], nil
+		}
+	}
+	return false, nil
+}
+
+func parseVersion(v string) ([3]int, error) {
+	var parts [3]int
+	core, _, _ := strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	fields := strings.Split(core, ".")
+	if len(fields) != 3 {
+		return parts, fmt.Errorf("invalid version %q", v)
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, fmt.Errorf("invalid version %q", v)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
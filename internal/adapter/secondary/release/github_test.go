@@ -0,0 +1,107 @@
+package release
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    [3]int
+		wantErr bool
+	}{
+		{"v1.2.3", [3]int{1, 2, 3}, false},
+		{"1.2.3", [3]int{1, 2, 3}, false},
+		{"v10.0.12", [3]int{10, 0, 12}, false},
+		{"v1.2.3-rc.1", [3]int{1, 2, 3}, false},
+		{"v1.2", [3]int{}, true},
+		{"v1.2.3.4", [3]int{}, true},
+		{"v1.x.3", [3]int{}, true},
+		{"dev", [3]int{}, true},
+		{"", [3]int{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseVersion(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVersion(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseVersion(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		tag, current string
+		want         bool
+		wantErr      bool
+	}{
+		{"v1.2.4", "v1.2.3", true, false},
+		{"v1.3.0", "v1.2.9", true, false},
+		{"v2.0.0", "v1.99.99", true, false},
+		{"v1.10.0", "v1.9.0", true, false},
+		{"v1.2.3", "v1.2.3", false, false},
+		{"v1.2.2", "v1.2.3", false, false},
+		{"v0.9.0", "v1.0.0", false, false},
+		{"v1.2.3", "1.2.3", false, false},
+		{"v1.2.3", "dev", false, true},
+		{"latest", "v1.2.3", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag+"_vs_"+tt.current, func(t *testing.T) {
+			got, err := Newer(tt.tag, tt.current)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Newer error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Newer(%q, %q) = %v, want %v", tt.tag, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindChecksum(t *testing.T) {
+	list := []byte(strings.Join([]string{
+		"AAAA1111  micgain-manager_linux_amd64",
+		"bbbb2222 *micgain-manager_windows_amd64.exe",
+		"",
+		"malformed line here",
+		"cccc3333  micgain-manager_linux_arm64",
+	}, "\n"))
+	tests := []struct {
+		name    string
+		asset   string
+		want    string
+		wantErr bool
+	}{
+		{"lower cased", "micgain-manager_linux_amd64", "aaaa1111", false},
+		{"binary marker", "micgain-manager_windows_amd64.exe", "bbbb2222", false},
+		{"after a malformed line", "micgain-manager_linux_arm64", "cccc3333", false},
+		{"no prefix match", "micgain-manager_linux_arm", "", true},
+		{"missing", "micgain-manager_darwin_arm64", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findChecksum(list, tt.asset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findChecksum error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("findChecksum = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	if data, err := readLimited(strings.NewReader("12345"), 5); err != nil || string(data) != "12345" {
+		t.Errorf("at the limit: %q, %v", data, err)
+	}
+	if _, err := readLimited(strings.NewReader("123456"), 5); err == nil {
+		t.Error("past the limit: want an error")
+	}
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// codec converts a config file between its format on disk and the JSON
+// the layers are merged in.
+type codec interface {
+	// decode turns the contents of a config file into a JSON object.
+	decode(data []byte) ([]byte, error)
+	// encode turns a JSON object into the contents of a config file.
+	// previous is what the file held before, or nil, so that formats
+	// meant for hand editing can keep its layout.
+	encode(data, previous []byte) ([]byte, error)
+}
+
+// codecFor picks the codec from the extension of path: .yaml/.yml, .toml
+// or, for anything else, JSON.
+func codecFor(path string) codec {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yamlCodec{}
+	case ".toml":
+		return tomlCodec{}
+	default:
+		return jsonCodec{}
+	}
+}
+
+// jsonCodec stores the JSON as is.
+type jsonCodec struct{}
+
+func (jsonCodec) decode(data []byte) ([]byte, error)    { return data, nil }
+func (jsonCodec) encode(data, _ []byte) ([]byte, error) { return data, nil }
+
+// yamlCodec stores YAML. Saving keeps the key order and the comments of
+// the previous file for the keys it still has.
+type yamlCodec struct{}
+
+func (yamlCodec) decode(data []byte) ([]byte, error) {
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		// An empty file
+		tree = map[string]any{}
+	}
+	return json.Marshal(tree)
+}
+
+func (yamlCodec) encode(data, previous []byte) ([]byte, error) {
+	tree, err := jsonTree(data)
+	if err != nil {
+		return nil, err
+	}
+	var mapping yaml.Node
+	if err := mapping.Encode(tree); err != nil {
+		return nil, err
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&mapping}}
+	var old yaml.Node
+	if err := yaml.Unmarshal(previous, &old); err == nil && len(old.Content) == 1 {
+		doc.HeadComment = old.HeadComment
+		doc.FootComment = old.FootComment
+		keepLayout(&mapping, old.Content[0])
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// keepLayout orders the keys of mapping as in old, with new keys last, and
+// carries over the comments of old, nested mappings and list items
+// included.
+func keepLayout(mapping, old *yaml.Node) {
+	if mapping.Kind == yaml.SequenceNode && old.Kind == yaml.SequenceNode {
+		for i := range min(len(mapping.Content), len(old.Content)) {
+			keepLayout(mapping.Content[i], old.Content[i])
+		}
+		return
+	}
+	if mapping.Kind != yaml.MappingNode || old.Kind != yaml.MappingNode {
+		return
+	}
+	mapping.HeadComment = old.HeadComment
+	mapping.FootComment = old.FootComment
+
+	pairs := make(map[string][2]*yaml.Node, len(mapping.Content)/2)
+	var order []string
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i].Value
+		pairs[key] = [2]*yaml.Node{mapping.Content[i], mapping.Content[i+1]}
+		order = append(order, key)
+	}
+
+	content := make([]*yaml.Node, 0, len(mapping.Content))
+	for i := 0; i+1 < len(old.Content); i += 2 {
+		oldKey, oldValue := old.Content[i], old.Content[i+1]
+		pair, ok := pairs[oldKey.Value]
+		if !ok {
+			continue
+		}
+		delete(pairs, oldKey.Value)
+		key, value := pair[0], pair[1]
+		key.HeadComment = oldKey.HeadComment
+		key.LineComment = oldKey.LineComment
+		key.FootComment = oldKey.FootComment
+		value.LineComment = oldValue.LineComment
+		keepLayout(value, oldValue)
+		content = append(content, key, value)
+	}
+	for _, key := range order {
+		if pair, ok := pairs[key]; ok {
+			content = append(content, pair[0], pair[1])
+		}
+	}
+	mapping.Content = content
+}
+
+// tomlCodec stores TOML. Comments are not kept on save.
+type tomlCodec struct{}
+
+func (tomlCodec) decode(data []byte) ([]byte, error) {
+	tree := map[string]any{}
+	if err := toml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+func (tomlCodec) encode(data, _ []byte) ([]byte, error) {
+	tree, err := jsonTree(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonTree decodes a JSON object, keeping whole numbers as integers so
+// that they are not written as floats, and dropping nulls, which TOML
+// cannot hold.
+func jsonTree(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree map[string]any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+	return plainValue(tree).(map[string]any), nil
+}
+
+func plainValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i, item := range v {
+			v[i] = plainValue(item)
+		}
+	case map[string]any:
+		for k, item := range v {
+			if item == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = plainValue(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	curve, err := domain.ParseCurve("08:00=60,22:00=30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet, err := domain.ParseQuietHours("mon-fri@22:00-07:00,12:00-13:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := domain.DefaultConfig()
+	config.TargetVolume = 65
+	config.Interval = 90 * time.Second
+	config.AdaptiveInterval = true
+	config.MaxInterval = 10 * time.Minute
+	config.MaxRetries = 3
+	config.RetryBackoff = 2500 * time.Millisecond
+	config.Curve = curve
+	config.QuietHours = quiet
+	config.Profiles = []domain.Profile{
+		{Name: "meeting", TargetVolume: 80, Interval: 30 * time.Second, Enabled: true},
+		{Name: "night", TargetVolume: 40, Interval: 5 * time.Minute, Curve: curve},
+	}
+	state := domain.ScheduleState{
+		LastApplied:     time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC),
+		LastApplyStatus: domain.StatusSuccess,
+		NextRun:         time.Date(2026, 1, 5, 9, 1, 30, 0, time.UTC),
+	}
+
+	tests := []struct {
+		file string
+		// want are snippets of the file, durations as strings
+		want []string
+	}{
+		{"config.json", []string{`"intervalSeconds": "1m30s"`, `"retryBackoffSeconds": "2.5s"`}},
+		{"config.yaml", []string{"intervalSeconds: 1m30s", "retryBackoffSeconds: 2.5s", "name: meeting"}},
+		{"config.yml", []string{"intervalSeconds: 1m30s"}},
+		{"config.toml", []string{`intervalSeconds = "1m30s"`, `retryBackoffSeconds = "2.5s"`, "[[profiles]]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			repo := newTestRepository(t, tt.file)
+			if err := repo.Save(config, state); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			data, err := os.ReadFile(repo.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("file lacks %q:\n%s", want, data)
+				}
+			}
+
+			got, gotState, err := repo.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if changed := domain.ChangedFields(config, got); changed != nil {
+				t.Errorf("changed by the round trip: %v", changed)
+			}
+			if !reflect.DeepEqual(got.Profiles, config.Profiles) {
+				t.Errorf("profiles = %+v, want %+v", got.Profiles, config.Profiles)
+			}
+			if !gotState.LastApplied.Equal(state.LastApplied) || !gotState.NextRun.Equal(state.NextRun) {
+				t.Errorf("state = last %v, next %v, want %v, %v", gotState.LastApplied, gotState.NextRun, state.LastApplied, state.NextRun)
+			}
+		})
+	}
+}
+
+func TestYAMLKeepsComments(t *testing.T) {
+	repo := newTestRepository(t, "config.yaml")
+	hand := "# my settings\ntargetVolume: 65 # loud enough\nenabled: true\nintervalSeconds: 2m\n"
+	if err := os.WriteFile(repo.path, []byte(hand), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, state, err := repo.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	config.TargetVolume = 70
+	if err := repo.Save(config, state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	data, err := os.ReadFile(repo.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# my settings", "targetVolume: 70 # loud enough", "intervalSeconds: 2m0s"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("saved file lacks %q:\n%s", want, data)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// persistedDuration is a duration on disk, written as a duration string
+// such as "90s" or "1m30s". A number of seconds, as written before schema
+// version 2, is read back too.
+type persistedDuration time.Duration
+
+// Duration returns the duration.
+func (p persistedDuration) Duration() time.Duration {
+	return time.Duration(p)
+}
+
+func (p persistedDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(p).String())
+}
+
+func (p *persistedDuration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*p = persistedDuration(secondsToDuration(seconds))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid duration %s (a string such as \"90s\", or seconds)", data)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (a string such as \"90s\", or seconds)", s)
+	}
+	*p = persistedDuration(d)
+	return nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// FileEffectLog implements domain.EffectRecorder using a JSON lines file.
+// This is a secondary adapter.
+type FileEffectLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileEffectLog creates a new file-based effect log.
+func NewFileEffectLog(path string) (domain.EffectRecorder, error) {
+	if path == "" {
+		return nil, errors.New("path is required")
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create effect log dir: %w", err)
+	}
+
+	return &FileEffectLog{path: path}, nil
+}
+
+// persistedEffect represents a single JSON line on disk.
+type persistedEffect struct {
+	Timestamp  string         `json:"ts"`
+	Type       string         `json:"type"`
+	Params     map[string]any `json:"params,omitempty"`
+	DurationMs float64        `json:"durationMs"`
+	Result     string         `json:"result"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Record appends an executed effect to the log.
+func (f *FileEffectLog) Record(record domain.EffectRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	persisted := persistedEffect{
+		Timestamp:  record.Timestamp.Format(time.RFC3339Nano),
+		Type:       record.Type,
+		Params:     record.Params,
+		DurationMs: float64(record.Duration) / float64(time.Millisecond),
+		Result:     "ok",
+	}
+	if record.Err != nil {
+		persisted.Result = "error"
+		persisted.Error = record.Err.Error()
+	}
+
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return fmt.Errorf("marshal effect: %w", err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open effect log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write effect log: %w", err)
+	}
+	return nil
+}
@@ -12,15 +12,43 @@ import (
 	"micgain-manager/internal/domain"
 )
 
-// FileRepository implements domain.ConfigRepository using JSON files.
-// This is a secondary adapter.
+// FileRepository implements domain.ConfigRepository using JSON files, or
+// YAML or TOML ones by extension. This is a secondary adapter.
+// Settings can be layered: system defaults < user file < environment.
+// Only the user file is ever written.
 type FileRepository struct {
-	path string
-	mu   sync.Mutex
+	path       string
+	codec      codec
+	systemPath string
+	useEnv     bool
+	mu         sync.Mutex
+
+	// Populated by Load
+	layers  []LayerInfo
+	origins map[string]string
+	loaded  map[string]json.RawMessage
+	userRaw map[string]json.RawMessage
+	// templated are the user file settings written with ${VAR} placeholders
+	templated map[string]bool
+	locked    bool
+	// timestampFormat is how Save writes timestamps
+	timestampFormat string
+	// keepGood and fallback are set by WithLastKnownGood
+	keepGood bool
+	fallback bool
+	// lockTimeout is set by WithLockTimeout
+	lockTimeout time.Duration
+	// readOnly is set by WithReadOnly
+	readOnly bool
+	// written is what save last wrote, so Watch can skip our own saves
+	written []byte
 }
 
+// ErrReadOnly is returned by writes to a repository opened WithReadOnly.
+var ErrReadOnly = errors.New("config repository is read-only")
+
 // NewFileRepository creates a new file-based config repository.
-func NewFileRepository(path string) (domain.ConfigRepository, error) {
+func NewFileRepository(path string, opts ...FileOption) (domain.ConfigRepository, error) {
 	if path == "" {
 		return nil, errors.New("path is required")
 	}
@@ -30,124 +58,781 @@ func NewFileRepository(path string) (domain.ConfigRepository, error) {
 		return nil, fmt.Errorf("create config dir: %w", err)
 	}
 
-	return &FileRepository{path: path}, nil
+	f := &FileRepository{path: path, codec: codecFor(path), lockTimeout: DefaultLockTimeout}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f, nil
 }
 
 // persistedData represents the JSON structure on disk.
 type persistedData struct {
-	TargetVolume    int    `json:"targetVolume"`
-	IntervalSeconds int    `json:"intervalSeconds"`
-	Enabled         bool   `json:"enabled"`
-	LastApplied     string `json:"lastApplied,omitempty"`
-	LastApplyStatus string `json:"lastApplyStatus"`
-	LastError       string `json:"lastError,omitempty"`
+	SchemaVersion       int                   `json:"schemaVersion"`
+	TargetVolume        int                   `json:"targetVolume" schema:"min=0,max=100"`
+	IntervalSeconds     persistedDuration     `json:"intervalSeconds" schema:"min=1"`
+	Enabled             bool                  `json:"enabled"`
+	ScheduleMode        string                `json:"scheduleMode,omitempty" schema:"enum=relative|fixed"`
+	Schedule            string                `json:"schedule,omitempty"`
+	Timezone            string                `json:"timezone,omitempty"`
+	LastApplied         *persistedTime        `json:"lastApplied,omitempty"`
+	FirstApplied        *persistedTime        `json:"firstApplied,omitempty"`
+	LastWakeApply       *persistedTime        `json:"lastWakeApply,omitempty"`
+	LastApplyStatus     string                `json:"lastApplyStatus"`
+	LastError           string                `json:"lastError,omitempty"`
+	LastWarning         string                `json:"lastWarning,omitempty"`
+	NextRun             *persistedTime        `json:"nextRun,omitempty"`
+	ConsecutiveFailures int                   `json:"consecutiveFailures,omitempty"`
+	LastObservedVolume  *int                  `json:"lastObservedVolume,omitempty"`
+	LastApplyDryRun     bool                  `json:"lastApplyDryRun,omitempty"`
+	Hold                *persistedHold        `json:"hold,omitempty"`
+	AdaptiveInterval    bool                  `json:"adaptiveInterval,omitempty"`
+	MaxIntervalSeconds  persistedDuration     `json:"maxIntervalSeconds,omitempty" schema:"min=1"`
+	RescheduleOnManual  *bool                 `json:"rescheduleOnManualApply,omitempty"`
+	MinTargetVolume     int                   `json:"minTargetVolume,omitempty" schema:"min=0,max=100"`
+	ErrorThreshold      int                   `json:"errorThreshold,omitempty" schema:"min=0"`
+	MaxRetries          int                   `json:"maxRetries,omitempty" schema:"min=0,max=10"`
+	RetryBackoffSeconds persistedDuration     `json:"retryBackoffSeconds,omitempty" schema:"min=0,max=30"`
+	RampDurationMs      int                   `json:"rampDurationMs,omitempty" schema:"min=0,max=10000"`
+	RampSteps           int                   `json:"rampSteps,omitempty" schema:"min=0,max=100"`
+	StaggerMs           int                   `json:"staggerBetweenDevicesMs,omitempty" schema:"min=0,max=10000"`
+	DriftAlertThreshold int                   `json:"driftAlertThreshold,omitempty" schema:"min=0,max=100"`
+	RedactErrors        bool                  `json:"redactErrors,omitempty"`
+	DeviceName          string                `json:"deviceName,omitempty"`
+	OnDeviceAbsent      string                `json:"onDeviceAbsent,omitempty" schema:"enum=error|skip|fallback-default"`
+	FormatVolumes       []persistedRateVolume `json:"formatVolumes,omitempty"`
+	AllowedVolumes      []int                 `json:"allowedVolumes,omitempty" schema:"min=0,max=100"`
+	AppVolumes          []persistedAppVolume  `json:"appVolumes,omitempty"`
+	Output              *persistedOutput      `json:"output,omitempty"`
+	Noise               *persistedNoise       `json:"noise,omitempty"`
+	ParkVolume          *int                  `json:"parkVolume,omitempty" schema:"min=0,max=100"`
+	FadeOnPark          bool                  `json:"fadeOnPark,omitempty"`
+	ReapplyOnPower      bool                  `json:"reapplyOnPowerChange,omitempty"`
+	PowerPollSeconds    persistedDuration     `json:"powerPollSeconds,omitempty" schema:"min=0"`
+	PreApplyCmd         string                `json:"preApplyCmd,omitempty"`
+	PostApplyCmd        string                `json:"postApplyCmd,omitempty"`
+	AbortOnPreApply     bool                  `json:"abortOnPreApplyFailure,omitempty"`
+	ApplyCmdTimeoutSecs persistedDuration     `json:"applyCmdTimeoutSeconds,omitempty" schema:"min=0"`
+	Curve               []persistedCurvePoint `json:"curve,omitempty"`
+	QuietHours          []persistedWindow     `json:"quietHours,omitempty"`
+	Profiles            []persistedProfile    `json:"profiles,omitempty"`
+	ActiveProfile       string                `json:"activeProfile,omitempty"`
+	DryRun              bool                  `json:"dryRun,omitempty"`
+	Running             *persistedRunning     `json:"running,omitempty"`
+	StartupCheck        *persistedStartup     `json:"startupCheck,omitempty"`
+	TimestampFormat     string                `json:"timestampFormat,omitempty" schema:"enum=rfc3339|epoch"`
+}
+
+// persistedRunning represents the config a running scheduler loop uses.
+type persistedRunning struct {
+	TargetVolume        int                   `json:"targetVolume"`
+	IntervalSeconds     persistedDuration     `json:"intervalSeconds"`
+	Enabled             bool                  `json:"enabled"`
+	ScheduleMode        string                `json:"scheduleMode,omitempty"`
+	Schedule            string                `json:"schedule,omitempty"`
+	Timezone            string                `json:"timezone,omitempty"`
+	AdaptiveInterval    bool                  `json:"adaptiveInterval,omitempty"`
+	MaxIntervalSeconds  persistedDuration     `json:"maxIntervalSeconds,omitempty"`
+	RescheduleOnManual  *bool                 `json:"rescheduleOnManualApply,omitempty"`
+	MinTargetVolume     int                   `json:"minTargetVolume,omitempty"`
+	ErrorThreshold      int                   `json:"errorThreshold,omitempty"`
+	DriftAlertThreshold int                   `json:"driftAlertThreshold,omitempty"`
+	RedactErrors        bool                  `json:"redactErrors,omitempty"`
+	ReapplyOnPower      bool                  `json:"reapplyOnPowerChange,omitempty"`
+	PowerPollSeconds    persistedDuration     `json:"powerPollSeconds,omitempty"`
+	DeviceName          string                `json:"deviceName,omitempty"`
+	OnDeviceAbsent      string                `json:"onDeviceAbsent,omitempty"`
+	FormatVolumes       []persistedRateVolume `json:"formatVolumes,omitempty"`
+	AllowedVolumes      []int                 `json:"allowedVolumes,omitempty"`
+	ParkVolume          *int                  `json:"parkVolume,omitempty"`
+	FadeOnPark          bool                  `json:"fadeOnPark,omitempty"`
+	MaxRetries          int                   `json:"maxRetries,omitempty"`
+	RetryBackoffSeconds persistedDuration     `json:"retryBackoffSeconds,omitempty"`
+	RampDurationMs      int                   `json:"rampDurationMs,omitempty"`
+	RampSteps           int                   `json:"rampSteps,omitempty"`
+	StaggerMs           int                   `json:"staggerBetweenDevicesMs,omitempty"`
+	AppVolumes          []persistedAppVolume  `json:"appVolumes,omitempty"`
+	Output              *persistedOutput      `json:"output,omitempty"`
+	Noise               *persistedNoise       `json:"noise,omitempty"`
+	PreApplyCmd         string                `json:"preApplyCmd,omitempty"`
+	PostApplyCmd        string                `json:"postApplyCmd,omitempty"`
+	AbortOnPreApply     bool                  `json:"abortOnPreApplyFailure,omitempty"`
+	ApplyCmdTimeoutSec  persistedDuration     `json:"applyCmdTimeoutSeconds,omitempty"`
+	Curve               []persistedCurvePoint `json:"curve,omitempty"`
+	QuietHours          []persistedWindow     `json:"quietHours,omitempty"`
+	ActiveProfile       string                `json:"activeProfile,omitempty"`
+	DryRun              bool                  `json:"dryRun,omitempty"`
+}
+
+// persistedAppVolume represents a per-application volume rule on disk.
+type persistedAppVolume struct {
+	App    string `json:"app"`
+	Volume int    `json:"volume" schema:"min=0,max=100"`
+}
+
+// persistedRateVolume represents a per-sample-rate volume rule on disk.
+type persistedRateVolume struct {
+	SampleRate int `json:"sampleRate" schema:"min=1"`
+	Volume     int `json:"volume" schema:"min=0,max=100"`
+}
+
+// persistedOutput represents the output volume lock on disk.
+type persistedOutput struct {
+	Enabled bool `json:"enabled,omitempty"`
+	Volume  int  `json:"volume" schema:"min=0,max=100"`
+}
+
+// persistedNoise represents the noise adaptive target settings on disk.
+type persistedNoise struct {
+	Enabled       bool    `json:"enabled,omitempty"`
+	ReferenceDBFS float64 `json:"referenceDbfs" schema:"min=-120,max=0"`
+	MinVolume     int     `json:"minVolume,omitempty" schema:"min=0,max=100"`
+	MaxVolume     int     `json:"maxVolume" schema:"min=0,max=100"`
+}
+
+// persistedProfile represents a named settings profile on disk.
+type persistedProfile struct {
+	Name            string                `json:"name"`
+	TargetVolume    int                   `json:"targetVolume" schema:"min=0,max=100"`
+	IntervalSeconds persistedDuration     `json:"intervalSeconds" schema:"min=1"`
+	Enabled         bool                  `json:"enabled"`
+	Curve           []persistedCurvePoint `json:"curve,omitempty"`
+}
+
+// persistedCurvePoint represents a curve control point on disk.
+type persistedCurvePoint struct {
+	Time   string `json:"time" schema:"pattern=^([01][0-9]|2[0-3]):[0-5][0-9]$"`
+	Volume int    `json:"volume" schema:"min=0,max=100"`
+}
+
+// persistedWindow represents a quiet-hours window on disk.
+type persistedWindow struct {
+	Start    string   `json:"start" schema:"pattern=^([01][0-9]|2[0-3]):[0-5][0-9]$"`
+	End      string   `json:"end" schema:"pattern=^([01][0-9]|2[0-3]):[0-5][0-9]$"`
+	Weekdays []string `json:"weekdays,omitempty" schema:"enum=sun|mon|tue|wed|thu|fri|sat"`
+}
+
+// persistedHold represents an active volume hold on disk.
+type persistedHold struct {
+	Volume int            `json:"volume"`
+	Since  *persistedTime `json:"since,omitempty"`
+}
+
+// persistedStartup represents the startup read-back of a running loop.
+type persistedStartup struct {
+	At     *persistedTime `json:"at,omitempty"`
+	Volume int            `json:"volume"`
+	Target int            `json:"target"`
+	Error  string         `json:"error,omitempty"`
 }
 
 // Load reads the configuration and state from disk.
 func (f *FileRepository) Load() (domain.Config, domain.ScheduleState, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	return f.load()
+}
 
-	data, err := os.ReadFile(f.path)
+// Save persists the configuration and state to disk. It waits for the
+// config file lock when another process is writing.
+func (f *FileRepository) Save(config domain.Config, state domain.ScheduleState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.readOnly {
+		return ErrReadOnly
+	}
+	unlock, err := LockFile(f.path, f.lockTimeout)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			// Return defaults
-			config := domain.DefaultConfig()
-			state := domain.ScheduleState{
-				LastApplyStatus: domain.StatusNever,
-			}
-			return config, state, nil
-		}
-		return domain.Config{}, domain.ScheduleState{}, fmt.Errorf("read config: %w", err)
+		return err
 	}
+	defer unlock()
+	return f.save(config, state)
+}
 
-	var persisted persistedData
-	if err := json.Unmarshal(data, &persisted); err != nil {
-		return domain.Config{}, domain.ScheduleState{}, fmt.Errorf("unmarshal config: %w", err)
+// Update loads the config, passes it to fn and saves the result, all under
+// the repository lock and the config file lock, so that concurrent
+// read-modify-write cycles cannot lose each other's changes. The state is
+// written back as loaded.
+func (f *FileRepository) Update(fn func(domain.Config) domain.Config) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.readOnly {
+		return ErrReadOnly
 	}
-
-	// Convert to domain models
-	config := domain.Config{
-		TargetVolume: persisted.TargetVolume,
-		Interval:     time.Duration(persisted.IntervalSeconds) * time.Second,
-		Enabled:      persisted.Enabled,
+	unlock, err := LockFile(f.path, f.lockTimeout)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	// Apply defaults if necessary
-	if config.TargetVolume <= 0 {
-		config.TargetVolume = 50
+	config, state, err := f.load()
+	if err != nil {
+		return err
 	}
-	if config.Interval <= 0 {
-		config.Interval = 90 * time.Second
+	config = fn(config)
+	if err := config.Validate(); err != nil {
+		return err
 	}
+	return f.save(config, state)
+}
 
+// loadFrom merges the layers with userPath as the user file. Errors in
+// values that were read fine are returned as *invalidConfigError.
+func (f *FileRepository) loadFrom(userPath string) (domain.Config, domain.ScheduleState, error) {
+	defaults := domain.DefaultConfig()
 	state := domain.ScheduleState{
-		LastApplyStatus: parseStatus(persisted.LastApplyStatus),
+		LastApplyStatus: domain.StatusNever,
 	}
 
-	if persisted.LastApplied != "" {
-		if t, err := time.Parse(time.RFC3339, persisted.LastApplied); err == nil {
-			state.LastApplied = t
+	// Seed with defaults so that fields missing from every layer keep their
+	// default value instead of the JSON zero value.
+	persisted := toPersisted(defaults, state)
+	// Nested, so a partial noise object keeps the other defaults too
+	persisted.Noise = toPersistedNoise(defaults.Noise)
+
+	f.layers = nil
+	f.userRaw = nil
+	f.templated = make(map[string]bool)
+	f.locked = false
+	f.origins = make(map[string]string, len(configKeys))
+	for _, key := range configKeys {
+		f.origins[key] = LayerDefault
+	}
+	if f.systemPath != "" {
+		if err := f.mergeFileLayer(LayerSystem, f.systemPath, codecFor(f.systemPath), true, &persisted); err != nil {
+			return domain.Config{}, domain.ScheduleState{}, err
 		}
 	}
+	if err := f.mergeFileLayer(LayerUser, userPath, f.codec, false, &persisted); err != nil {
+		return domain.Config{}, domain.ScheduleState{}, err
+	}
+	if f.useEnv {
+		if err := f.mergeEnvLayer(&persisted); err != nil {
+			return domain.Config{}, domain.ScheduleState{}, err
+		}
+	}
+	if err := f.rememberLoaded(persisted); err != nil {
+		return domain.Config{}, domain.ScheduleState{}, fmt.Errorf("marshal config: %w", err)
+	}
 
-	if persisted.LastError != "" {
-		state.LastError = errors.New(persisted.LastError)
+	if err := validateTimestampFormat(persisted.TimestampFormat); err != nil {
+		return domain.Config{}, domain.ScheduleState{}, &invalidConfigError{err}
+	}
+	f.timestampFormat = persisted.TimestampFormat
+
+	config, state, err := fromPersisted(persisted)
+	if err != nil {
+		return domain.Config{}, domain.ScheduleState{}, &invalidConfigError{err}
 	}
+	config.Locked = f.locked
 
 	return config, state, nil
 }
 
-// Save persists the configuration and state to disk.
-func (f *FileRepository) Save(config domain.Config, state domain.ScheduleState) error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+func (f *FileRepository) save(config domain.Config, state domain.ScheduleState) error {
+	persisted := toPersisted(config, state)
+	persisted.setTimestampFormat(f.timestampFormat)
+	data, err := f.userLayerData(persisted)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	previous, _ := os.ReadFile(f.path)
+	if data, err = f.codec.encode(data, previous); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
 
+	if err := writeFileAtomic(f.path, data); err != nil {
+		return err
+	}
+	f.written = data
+	if f.keepGood {
+		f.keepLastKnownGood(data)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data through a temporary file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write tmp: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename tmp: %w", err)
+	}
+	return nil
+}
+
+// toPersisted converts domain models into the on-disk structure.
+func toPersisted(config domain.Config, state domain.ScheduleState) persistedData {
 	persisted := persistedData{
-		TargetVolume:    config.TargetVolume,
-		IntervalSeconds: int(config.Interval.Seconds()),
-		Enabled:         config.Enabled,
-		LastApplyStatus: state.LastApplyStatus.String(),
+		SchemaVersion:      SchemaVersion,
+		TargetVolume:       config.TargetVolume,
+		IntervalSeconds:    persistedDuration(config.Interval),
+		Enabled:            config.Enabled,
+		LastApplyStatus:    state.LastApplyStatus.String(),
+		AdaptiveInterval:   config.AdaptiveInterval,
+		MaxIntervalSeconds: persistedDuration(config.MaxInterval),
+		MinTargetVolume:    config.MinTargetVolume,
+		ErrorThreshold:     config.ErrorThreshold,
+		AllowedVolumes:     config.AllowedVolumes,
+	}
+	persisted.RescheduleOnManual = toPersistedReschedule(config.RescheduleOnManualApply)
+	persisted.DriftAlertThreshold = config.DriftAlertThreshold
+	persisted.RedactErrors = config.RedactErrors
+	persisted.ParkVolume = config.ParkVolume
+	persisted.FadeOnPark = config.FadeOnPark
+	persisted.ReapplyOnPower = config.ReapplyOnPowerChange
+	persisted.PowerPollSeconds = persistedDuration(config.PowerPollInterval)
+	persisted.MaxRetries = config.MaxRetries
+	persisted.RetryBackoffSeconds = persistedDuration(config.RetryBackoff)
+	persisted.RampDurationMs = int(config.RampDuration.Milliseconds())
+	persisted.RampSteps = config.RampSteps
+	persisted.StaggerMs = int(config.StaggerBetweenDevices.Milliseconds())
+	persisted.Output = toPersistedOutput(config.Output)
+	if config.Noise != domain.DefaultNoiseControl() {
+		persisted.Noise = toPersistedNoise(config.Noise)
 	}
 
-	if !state.LastApplied.IsZero() {
-		persisted.LastApplied = state.LastApplied.Format(time.RFC3339)
+	persisted.PreApplyCmd = config.PreApplyCmd
+	persisted.PostApplyCmd = config.PostApplyCmd
+	persisted.AbortOnPreApply = config.AbortOnPreApplyFailure
+	persisted.ApplyCmdTimeoutSecs = persistedDuration(config.ApplyCmdTimeout)
+
+	persisted.ScheduleMode = toPersistedScheduleMode(config.ScheduleMode)
+	persisted.Schedule = config.Schedule
+	persisted.Timezone = config.Timezone
+	persisted.DeviceName = config.DeviceName
+	persisted.OnDeviceAbsent = toPersistedDeviceAbsent(config.OnDeviceAbsent)
+	persisted.FormatVolumes = toPersistedFormatVolumes(config.FormatVolumes)
+	persisted.AppVolumes = toPersistedAppVolumes(config.AppVolumes)
+	persisted.Curve = toPersistedCurve(config.Curve)
+	persisted.QuietHours = toPersistedWindows(config.QuietHours)
+	persisted.ActiveProfile = config.ActiveProfile
+	persisted.DryRun = config.DryRun
+	for _, p := range config.Profiles {
+		persisted.Profiles = append(persisted.Profiles, persistedProfile{
+			Name:            p.Name,
+			TargetVolume:    p.TargetVolume,
+			IntervalSeconds: persistedDuration(p.Interval),
+			Enabled:         p.Enabled,
+			Curve:           toPersistedCurve(p.Curve),
+		})
 	}
 
+	persisted.LastApplied = newPersistedTime(state.LastApplied)
+	persisted.FirstApplied = newPersistedTime(state.FirstApplied)
+	persisted.LastWakeApply = newPersistedTime(state.LastWakeApply)
+
 	if state.LastError != nil {
 		persisted.LastError = state.LastError.Error()
 	}
+	persisted.LastWarning = state.LastWarning
+	persisted.ConsecutiveFailures = state.ConsecutiveFailures
+	persisted.LastObservedVolume = state.LastObservedVolume
+	persisted.LastApplyDryRun = state.LastApplyDryRun
+	persisted.NextRun = newPersistedTime(state.NextRun)
+
+	if state.Hold.Active {
+		persisted.Hold = &persistedHold{
+			Volume: state.Hold.Volume,
+			Since:  newPersistedTime(state.Hold.Since),
+		}
+	}
+	if check := state.StartupCheck; check != nil {
+		persisted.StartupCheck = &persistedStartup{
+			At:     newPersistedTime(check.At),
+			Volume: check.Volume,
+			Target: check.Target,
+			Error:  check.Error,
+		}
+	}
+
+	if running := state.Running; running != nil {
+		persisted.Running = &persistedRunning{
+			TargetVolume:        running.TargetVolume,
+			IntervalSeconds:     persistedDuration(running.Interval),
+			Enabled:             running.Enabled,
+			ScheduleMode:        toPersistedScheduleMode(running.ScheduleMode),
+			Schedule:            running.Schedule,
+			Timezone:            running.Timezone,
+			AdaptiveInterval:    running.AdaptiveInterval,
+			MaxIntervalSeconds:  persistedDuration(running.MaxInterval),
+			RescheduleOnManual:  toPersistedReschedule(running.RescheduleOnManualApply),
+			MinTargetVolume:     running.MinTargetVolume,
+			ErrorThreshold:      running.ErrorThreshold,
+			DriftAlertThreshold: running.DriftAlertThreshold,
+			RedactErrors:        running.RedactErrors,
+			ReapplyOnPower:      running.ReapplyOnPowerChange,
+			PowerPollSeconds:    persistedDuration(running.PowerPollInterval),
+			DeviceName:          running.DeviceName,
+			OnDeviceAbsent:      toPersistedDeviceAbsent(running.OnDeviceAbsent),
+			FormatVolumes:       toPersistedFormatVolumes(running.FormatVolumes),
+			AllowedVolumes:      running.AllowedVolumes,
+			ParkVolume:          running.ParkVolume,
+			FadeOnPark:          running.FadeOnPark,
+			MaxRetries:          running.MaxRetries,
+			RetryBackoffSeconds: persistedDuration(running.RetryBackoff),
+			RampDurationMs:      int(running.RampDuration.Milliseconds()),
+			RampSteps:           running.RampSteps,
+			StaggerMs:           int(running.StaggerBetweenDevices.Milliseconds()),
+			AppVolumes:          toPersistedAppVolumes(running.AppVolumes),
+			Output:              toPersistedOutput(running.Output),
+			Noise:               toPersistedNoise(running.Noise),
+			PreApplyCmd:         running.PreApplyCmd,
+			PostApplyCmd:        running.PostApplyCmd,
+			AbortOnPreApply:     running.AbortOnPreApplyFailure,
+			ApplyCmdTimeoutSec:  persistedDuration(running.ApplyCmdTimeout),
+			Curve:               toPersistedCurve(running.Curve),
+			QuietHours:          toPersistedWindows(running.QuietHours),
+			ActiveProfile:       running.ActiveProfile,
+			DryRun:              running.DryRun,
+		}
+	}
+
+	return persisted
+}
 
-	data, err := json.MarshalIndent(persisted, "", "  ")
+// fromPersisted converts the on-disk structure into domain models.
+func fromPersisted(persisted persistedData) (domain.Config, domain.ScheduleState, error) {
+	interval, err := domain.IntervalFromSeconds("intervalSeconds", persisted.IntervalSeconds.Duration().Seconds())
 	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
+		return domain.Config{}, domain.ScheduleState{}, err
 	}
+	config := domain.Config{
+		TargetVolume:     persisted.TargetVolume,
+		Interval:         interval,
+		Enabled:          persisted.Enabled,
+		AdaptiveInterval: persisted.AdaptiveInterval,
+		MaxInterval:      persisted.MaxIntervalSeconds.Duration(),
+		MinTargetVolume:  persisted.MinTargetVolume,
+		ErrorThreshold:   persisted.ErrorThreshold,
+		MaxRetries:       persisted.MaxRetries,
+		RetryBackoff:     persisted.RetryBackoffSeconds.Duration(),
+		RampDuration:     time.Duration(persisted.RampDurationMs) * time.Millisecond,
+		RampSteps:        persisted.RampSteps,
+		AllowedVolumes:   persisted.AllowedVolumes,
 
-	// Atomic write
-	tmp := f.path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0o644); err != nil {
-		return fmt.Errorf("write tmp: %w", err)
+		DriftAlertThreshold: persisted.DriftAlertThreshold,
+		RedactErrors:        persisted.RedactErrors,
+		ParkVolume:          persisted.ParkVolume,
+		FadeOnPark:          persisted.FadeOnPark,
+
+		StaggerBetweenDevices: time.Duration(persisted.StaggerMs) * time.Millisecond,
+
+		ReapplyOnPowerChange: persisted.ReapplyOnPower,
+		PowerPollInterval:    persisted.PowerPollSeconds.Duration(),
+
+		PreApplyCmd:            persisted.PreApplyCmd,
+		PostApplyCmd:           persisted.PostApplyCmd,
+		AbortOnPreApplyFailure: persisted.AbortOnPreApply,
+		ApplyCmdTimeout:        persisted.ApplyCmdTimeoutSecs.Duration(),
 	}
-	if err := os.Rename(tmp, f.path); err != nil {
-		return fmt.Errorf("rename tmp: %w", err)
+
+	curve, err := fromPersistedCurve(persisted.Curve)
+	if err != nil {
+		return domain.Config{}, domain.ScheduleState{}, err
+	}
+	config.Curve = curve
+	config.QuietHours, err = fromPersistedWindows(persisted.QuietHours)
+	if err != nil {
+		return domain.Config{}, domain.ScheduleState{}, err
+	}
+	config.ScheduleMode, err = domain.ParseScheduleMode(persisted.ScheduleMode)
+	if err != nil {
+		return domain.Config{}, domain.ScheduleState{}, err
+	}
+	config.Schedule = persisted.Schedule
+	config.RescheduleOnManualApply = persisted.RescheduleOnManual == nil || *persisted.RescheduleOnManual
+	config.Timezone = persisted.Timezone
+	config.DeviceName = persisted.DeviceName
+	config.OnDeviceAbsent, err = domain.ParseDeviceAbsentPolicy(persisted.OnDeviceAbsent)
+	if err != nil {
+		return domain.Config{}, domain.ScheduleState{}, err
+	}
+	config.AppVolumes = fromPersistedAppVolumes(persisted.AppVolumes)
+	config.FormatVolumes = fromPersistedFormatVolumes(persisted.FormatVolumes)
+	config.Output = fromPersistedOutput(persisted.Output)
+	config.Noise = fromPersistedNoise(persisted.Noise)
+	config.ActiveProfile = persisted.ActiveProfile
+	config.DryRun = persisted.DryRun
+	for _, p := range persisted.Profiles {
+		curve, err := fromPersistedCurve(p.Curve)
+		if err != nil {
+			return domain.Config{}, domain.ScheduleState{}, fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+		config.Profiles = append(config.Profiles, domain.Profile{
+			Name:         p.Name,
+			TargetVolume: p.TargetVolume,
+			Interval:     p.IntervalSeconds.Duration(),
+			Enabled:      p.Enabled,
+			Curve:        curve,
+		})
 	}
 
-	return nil
+	state := domain.ScheduleState{
+		LastApplyStatus:     parseStatus(persisted.LastApplyStatus),
+		LastWarning:         persisted.LastWarning,
+		ConsecutiveFailures: persisted.ConsecutiveFailures,
+		LastObservedVolume:  persisted.LastObservedVolume,
+		LastApplyDryRun:     persisted.LastApplyDryRun,
+	}
+
+	// Restoring NextRun lets a restart resume a failure backoff instead
+	// of retrying a failing controller at once
+	state.NextRun = persisted.NextRun.Time()
+	state.LastApplied = persisted.LastApplied.Time()
+	state.FirstApplied = persisted.FirstApplied.Time()
+	state.LastWakeApply = persisted.LastWakeApply.Time()
+
+	if persisted.LastError != "" {
+		state.LastError = errors.New(persisted.LastError)
+	}
+
+	if persisted.Hold != nil {
+		state.Hold = domain.Hold{Active: true, Volume: persisted.Hold.Volume, Since: persisted.Hold.Since.Time()}
+	}
+	if check := persisted.StartupCheck; check != nil {
+		state.StartupCheck = &domain.StartupCheck{
+			At:     check.At.Time(),
+			Volume: check.Volume,
+			Target: check.Target,
+			Error:  check.Error,
+		}
+	}
+
+	if running := persisted.Running; running != nil {
+		curve, err := fromPersistedCurve(running.Curve)
+		if err != nil {
+			return domain.Config{}, domain.ScheduleState{}, fmt.Errorf("running config: %w", err)
+		}
+		mode, err := domain.ParseScheduleMode(running.ScheduleMode)
+		if err != nil {
+			return domain.Config{}, domain.ScheduleState{}, fmt.Errorf("running config: %w", err)
+		}
+		quiet, err := fromPersistedWindows(running.QuietHours)
+		if err != nil {
+			return domain.Config{}, domain.ScheduleState{}, fmt.Errorf("running config: %w", err)
+		}
+		absent, err := domain.ParseDeviceAbsentPolicy(running.OnDeviceAbsent)
+		if err != nil {
+			return domain.Config{}, domain.ScheduleState{}, fmt.Errorf("running config: %w", err)
+		}
+		state.Running = &domain.Config{
+			ScheduleMode:     mode,
+			Schedule:         running.Schedule,
+			Timezone:         running.Timezone,
+			TargetVolume:     running.TargetVolume,
+			Interval:         running.IntervalSeconds.Duration(),
+			Enabled:          running.Enabled,
+			AdaptiveInterval: running.AdaptiveInterval,
+			MaxInterval:      running.MaxIntervalSeconds.Duration(),
+			MinTargetVolume:  running.MinTargetVolume,
+			AppVolumes:       fromPersistedAppVolumes(running.AppVolumes),
+			Output:           fromPersistedOutput(running.Output),
+			Noise:            fromPersistedNoise(running.Noise),
+
+			ErrorThreshold:       running.ErrorThreshold,
+			DriftAlertThreshold:  running.DriftAlertThreshold,
+			RedactErrors:         running.RedactErrors,
+			ReapplyOnPowerChange: running.ReapplyOnPower,
+			PowerPollInterval:    running.PowerPollSeconds.Duration(),
+			DeviceName:           running.DeviceName,
+			OnDeviceAbsent:       absent,
+			FormatVolumes:        fromPersistedFormatVolumes(running.FormatVolumes),
+			AllowedVolumes:       running.AllowedVolumes,
+			ParkVolume:           running.ParkVolume,
+			FadeOnPark:           running.FadeOnPark,
+			MaxRetries:           running.MaxRetries,
+			RetryBackoff:         running.RetryBackoffSeconds.Duration(),
+			RampDuration:         time.Duration(running.RampDurationMs) * time.Millisecond,
+			RampSteps:            running.RampSteps,
+
+			StaggerBetweenDevices: time.Duration(running.StaggerMs) * time.Millisecond,
+
+			PreApplyCmd:            running.PreApplyCmd,
+			PostApplyCmd:           running.PostApplyCmd,
+			AbortOnPreApplyFailure: running.AbortOnPreApply,
+			ApplyCmdTimeout:        running.ApplyCmdTimeoutSec.Duration(),
+			Curve:                  curve,
+			QuietHours:             quiet,
+			ActiveProfile:          running.ActiveProfile,
+			DryRun:                 running.DryRun,
+		}
+		state.Running.RescheduleOnManualApply = running.RescheduleOnManual == nil || *running.RescheduleOnManual
+	}
+
+	return config, state, nil
 }
 
-func parseStatus(s string) domain.ApplyStatus {
-	switch s {
-	case "ok":
-		return domain.StatusSuccess
-	case "error":
-		return domain.StatusError
-	default:
-		return domain.StatusNever
+// toPersistedOutput leaves an unset output lock off disk.
+func toPersistedOutput(output domain.OutputLock) *persistedOutput {
+	if output == (domain.OutputLock{}) {
+		return nil
+	}
+	return &persistedOutput{Enabled: output.Enabled, Volume: output.Volume}
+}
+
+func fromPersistedOutput(persisted *persistedOutput) domain.OutputLock {
+	if persisted == nil {
+		return domain.OutputLock{}
+	}
+	return domain.OutputLock{Enabled: persisted.Enabled, Volume: persisted.Volume}
+}
+
+func toPersistedNoise(noise domain.NoiseControl) *persistedNoise {
+	return &persistedNoise{
+		Enabled:       noise.Enabled,
+		ReferenceDBFS: noise.ReferenceDBFS,
+		MinVolume:     noise.MinVolume,
+		MaxVolume:     noise.MaxVolume,
+	}
+}
+
+func fromPersistedNoise(persisted *persistedNoise) domain.NoiseControl {
+	if persisted == nil {
+		return domain.DefaultNoiseControl()
+	}
+	return domain.NoiseControl{
+		Enabled:       persisted.Enabled,
+		ReferenceDBFS: persisted.ReferenceDBFS,
+		MinVolume:     persisted.MinVolume,
+		MaxVolume:     persisted.MaxVolume,
 	}
 }
 
+func toPersistedCurve(curve []domain.CurvePoint) []persistedCurvePoint {
+	var persisted []persistedCurvePoint
+	for _, p := range curve {
+		persisted = append(persisted, persistedCurvePoint{
+			Time:   domain.FormatClock(p.Minute),
+			Volume: p.Volume,
+		})
+	}
+	return persisted
+}
+
+func toPersistedWindows(windows []domain.TimeWindow) []persistedWindow {
+	var persisted []persistedWindow
+	for _, w := range windows {
+		var days []string
+		for _, day := range w.Weekdays {
+			days = append(days, domain.WeekdayLabel(day))
+		}
+		persisted = append(persisted, persistedWindow{
+			Start:    domain.FormatClock(w.Start),
+			End:      domain.FormatClock(w.End),
+			Weekdays: days,
+		})
+	}
+	return persisted
+}
+
+func fromPersistedWindows(persisted []persistedWindow) ([]domain.TimeWindow, error) {
+	var windows []domain.TimeWindow
+	for _, p := range persisted {
+		start, err := domain.ParseClock(p.Start)
+		if err != nil {
+			return nil, fmt.Errorf("quietHours: %w", err)
+		}
+		end, err := domain.ParseClock(p.End)
+		if err != nil {
+			return nil, fmt.Errorf("quietHours: %w", err)
+		}
+		w := domain.TimeWindow{Start: start, End: end}
+		for _, label := range p.Weekdays {
+			day, err := domain.ParseWeekday(label)
+			if err != nil {
+				return nil, fmt.Errorf("quietHours: %w", err)
+			}
+			w.Weekdays = append(w.Weekdays, day)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func fromPersistedCurve(persisted []persistedCurvePoint) ([]domain.CurvePoint, error) {
+	var curve []domain.CurvePoint
+	for _, p := range persisted {
+		minute, err := domain.ParseClock(p.Time)
+		if err != nil {
+			return nil, fmt.Errorf("curve: %w", err)
+		}
+		curve = append(curve, domain.CurvePoint{Minute: minute, Volume: p.Volume})
+	}
+	return curve, nil
+}
+
+// secondsToDuration reads a possibly fractional number of seconds.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// toPersistedDeviceAbsent leaves the default error policy out of the file.
+func toPersistedDeviceAbsent(policy domain.DeviceAbsentPolicy) string {
+	if policy == domain.DeviceAbsentError {
+		return ""
+	}
+	return policy.String()
+}
+
+// toPersistedScheduleMode leaves the default relative mode out of the file.
+func toPersistedScheduleMode(mode domain.ScheduleMode) string {
+	if mode == domain.ScheduleRelative {
+		return ""
+	}
+	return mode.String()
+}
+
+func toPersistedAppVolumes(rules []domain.AppVolume) []persistedAppVolume {
+	var persisted []persistedAppVolume
+	for _, r := range rules {
+		persisted = append(persisted, persistedAppVolume{App: r.App, Volume: r.Volume})
+	}
+	return persisted
+}
+
+func fromPersistedAppVolumes(persisted []persistedAppVolume) []domain.AppVolume {
+	var rules []domain.AppVolume
+	for _, p := range persisted {
+		rules = append(rules, domain.AppVolume{App: p.App, Volume: p.Volume})
+	}
+	return rules
+}
+
+func toPersistedFormatVolumes(rules []domain.FormatVolume) []persistedRateVolume {
+	var persisted []persistedRateVolume
+	for _, r := range rules {
+		persisted = append(persisted, persistedRateVolume{SampleRate: r.SampleRate, Volume: r.Volume})
+	}
+	return persisted
+}
+
+func fromPersistedFormatVolumes(persisted []persistedRateVolume) []domain.FormatVolume {
+	var rules []domain.FormatVolume
+	for _, p := range persisted {
+		rules = append(rules, domain.FormatVolume{SampleRate: p.SampleRate, Volume: p.Volume})
+	}
+	return rules
+}
+
+func parseStatus(s string) domain.ApplyStatus {
+	// Unknown labels fall back to StatusNever
+	status, _ := domain.ParseApplyStatus(s)
+	return status
+}
+
 // DefaultPath returns the default configuration file path.
 func DefaultPath() string {
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".config", "micgain-manager", "config.json")
 }
+
+// toPersistedReschedule writes RescheduleOnManualApply only when it is
+// off, since a missing key means the default, on.
+func toPersistedReschedule(reschedule bool) *bool {
+	if reschedule {
+		return nil
+	}
+	return &reschedule
+}
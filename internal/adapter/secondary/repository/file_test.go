@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// newTestRepository returns a repository over a fresh file in a temporary
+// directory.
+func newTestRepository(t *testing.T, name string, opts ...FileOption) *FileRepository {
+	t.Helper()
+	repo, err := NewFileRepository(filepath.Join(t.TempDir(), name), opts...)
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+	return repo.(*FileRepository)
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	repo := newTestRepository(t, "config.json")
+	config := domain.DefaultConfig()
+	config.TargetVolume = 0
+	if err := repo.Save(config, domain.ScheduleState{}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Update(func(c domain.Config) domain.Config {
+				c.TargetVolume++
+				return c
+			})
+			if err != nil {
+				t.Errorf("Update: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, _, err := repo.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.TargetVolume != n {
+		t.Errorf("target after %d increments = %d, lost %d updates", n, got.TargetVolume, n-got.TargetVolume)
+	}
+}
+
+func TestUpdateKeepsStateAndRejectsInvalid(t *testing.T) {
+	repo := newTestRepository(t, "config.json")
+	state := domain.ScheduleState{LastApplyStatus: domain.StatusError, ConsecutiveFailures: 2}
+	if err := repo.Save(domain.DefaultConfig(), state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	err := repo.Update(func(c domain.Config) domain.Config {
+		c.TargetVolume = 101
+		return c
+	})
+	if err == nil {
+		t.Fatal("Update saved an invalid target")
+	}
+	if err := repo.Update(func(c domain.Config) domain.Config {
+		c.TargetVolume = 70
+		return c
+	}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	config, got, err := repo.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if config.TargetVolume != 70 {
+		t.Errorf("target = %d, want 70", config.TargetVolume)
+	}
+	if got.ConsecutiveFailures != 2 || got.LastApplyStatus != domain.StatusError {
+		t.Errorf("state = %d failures, %s, want it kept", got.ConsecutiveFailures, got.LastApplyStatus)
+	}
+}
+
+func TestBackoffSurvivesRestart(t *testing.T) {
+	repo := newTestRepository(t, "config.json")
+	config := domain.DefaultConfig()
+	service := domain.NewSchedulerService()
+	failedAt := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	state := domain.ScheduleState{}
+	for i := 0; i < 3; i++ {
+		state = service.ApplyFailure(state, config, errors.New("osascript failed"), failedAt)
+	}
+	if err := repo.Save(config, state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A new repository over the same file, as after a restart
+	restarted, err := NewFileRepository(repo.path)
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+	config, got, err := restarted.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.ConsecutiveFailures != 3 || !got.NextRun.Equal(state.NextRun) {
+		t.Fatalf("loaded %d failures, next run %v, want 3, %v", got.ConsecutiveFailures, got.NextRun, state.NextRun)
+	}
+	backoff := service.FailureBackoff(config.Interval, 3)
+	if got.NextRun.Sub(failedAt) != backoff {
+		t.Errorf("next run %s after the failure, want the %s backoff", got.NextRun.Sub(failedAt), backoff)
+	}
+	if service.ShouldApply(got, config, failedAt.Add(config.Interval+time.Second)) {
+		t.Error("reloaded state applies after one interval, ignoring the backoff")
+	}
+	if !service.ShouldApply(got, config, got.NextRun.Add(time.Second)) {
+		t.Error("reloaded state does not apply after the backoff")
+	}
+}
+
+func TestFirstAppliedRoundTrip(t *testing.T) {
+	repo := newTestRepository(t, "config.json")
+	first := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	state := domain.ScheduleState{
+		LastApplied:     first.Add(time.Hour),
+		FirstApplied:    first,
+		LastApplyStatus: domain.StatusSuccess,
+	}
+	if err := repo.Save(domain.DefaultConfig(), state); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	_, got, err := repo.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !got.FirstApplied.Equal(first) || !got.LastApplied.Equal(state.LastApplied) {
+		t.Errorf("loaded first %v, last %v, want %v, %v", got.FirstApplied, got.LastApplied, first, state.LastApplied)
+	}
+}
+
+func TestLoadRejectsZeroInterval(t *testing.T) {
+	repo := newTestRepository(t, "config.json")
+	if err := os.WriteFile(repo.path, []byte(`{"intervalSeconds": 0}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := repo.Load()
+	if err == nil || !strings.Contains(err.Error(), "set enabled to false") {
+		t.Errorf("Load = %v, want intervalSeconds 0 rejected pointing at enabled", err)
+	}
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// DefaultLockTimeout bounds how long a write waits for another process
+// holding the config file lock.
+const DefaultLockTimeout = 5 * time.Second
+
+// lockPollInterval is how often a waiting writer retries the lock.
+const lockPollInterval = 50 * time.Millisecond
+
+// LockPath returns the lock file guarding writes to the config at
+// configPath. It holds the PID of the process writing the config, which
+// keeps an flock on it while it does.
+func LockPath(configPath string) string {
+	return configPath + ".lock"
+}
+
+// LockHeldError reports that another process holds the config file lock.
+type LockHeldError struct {
+	Path string
+	// PID is the holder, or 0 when the lock file could not be read.
+	PID int
+}
+
+func (e *LockHeldError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("config %s is in use by another process", e.Path)
+	}
+	return fmt.Sprintf("config %s is in use by process %d", e.Path, e.PID)
+}
+
+func (e *LockHeldError) Unwrap() error { return domain.ErrConfigBusy }
+
+// WithLockTimeout sets how long writes wait for a config file lock held by
+// another process before failing with *LockHeldError. Zero fails at once.
+func WithLockTimeout(timeout time.Duration) FileOption {
+	return func(f *FileRepository) {
+		f.lockTimeout = timeout
+	}
+}
+
+// LockFile takes the lock for the config at configPath, retrying until
+// timeout while another process holds it, and returns the function that
+// releases it. Within a process the lock is shared: a caller may hold it
+// around a load and save that take it again, and the file is unlocked when
+// the last of them releases it.
+func LockFile(configPath string, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		unlock, err := TryLockFile(configPath)
+		var held *LockHeldError
+		if !errors.As(err, &held) || !time.Now().Before(deadline) {
+			return unlock, err
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// heldLocks counts the owners of each lock file this process holds, so a
+// nested LockFile shares the outer lock and only the last release drops it.
+var (
+	heldMu    sync.Mutex
+	heldLocks = map[string]*heldLock{}
+)
+
+type heldLock struct {
+	file   *os.File
+	owners int
+}
+
+// TryLockFile takes the lock for the config at configPath without waiting.
+// It fails with *LockHeldError when another process holds it. The lock is
+// an flock on the lock file, so the kernel drops it when its process exits
+// and a lock left behind by a crash is simply taken.
+func TryLockFile(configPath string) (func(), error) {
+	path := LockPath(configPath)
+	heldMu.Lock()
+	defer heldMu.Unlock()
+
+	if held, ok := heldLocks[path]; ok {
+		held.owners++
+		return releaseOnce(path), nil
+	}
+
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open lock file: %w", err)
+		}
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			file.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, &LockHeldError{Path: configPath, PID: lockHolder(path)}
+			}
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+
+		// The holder we waited on may have removed the file before we
+		// got the flock; a lock on an unlinked file guards nothing
+		if !sameFile(file, path) {
+			file.Close()
+			continue
+		}
+
+		if err := writeHolder(file); err != nil {
+			_ = os.Remove(path)
+			file.Close()
+			return nil, fmt.Errorf("write lock file: %w", err)
+		}
+		heldLocks[path] = &heldLock{file: file, owners: 1}
+		return releaseOnce(path), nil
+	}
+}
+
+// releaseOnce returns the release function for one owner of the lock at
+// path. The file is removed while the flock is still held, so a process
+// that opened it meanwhile sees it is gone and opens a new one.
+func releaseOnce(path string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			heldMu.Lock()
+			defer heldMu.Unlock()
+			held := heldLocks[path]
+			if held.owners--; held.owners > 0 {
+				return
+			}
+			delete(heldLocks, path)
+			_ = os.Remove(path)
+			held.file.Close()
+		})
+	}
+}
+
+func sameFile(file *os.File, path string) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(opened, current)
+}
+
+// writeHolder replaces the lock file contents with this process's PID, for
+// LockHeldError to report.
+func writeHolder(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return err
+}
+
+// lockHolder reads the PID in a lock file, or 0 when it has none yet.
+func lockHolder(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
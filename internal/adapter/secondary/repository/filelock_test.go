@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// lockHelperEnv makes the test binary run TestLockHelperProcess as another
+// process holding the lock.
+const lockHelperEnv = "MICGAIN_LOCK_HELPER"
+
+// TestLockHelperProcess is not a test. Under lockHelperEnv it takes the
+// lock for the config path in the env, prints "locked" and then either
+// holds it until stdin closes or, for "crash", exits without releasing it.
+func TestLockHelperProcess(t *testing.T) {
+	mode, path, ok := strings.Cut(os.Getenv(lockHelperEnv), ":")
+	if !ok {
+		t.Skip("helper process only")
+	}
+	if _, err := TryLockFile(path); err != nil {
+		os.Stdout.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	os.Stdout.WriteString("locked\n")
+	if mode == "crash" {
+		os.Exit(0)
+	}
+	bufio.NewReader(os.Stdin).ReadString('\n')
+	os.Exit(0)
+}
+
+// startLockHolder runs a helper process that takes the lock and returns
+// once it holds it.
+func startLockHolder(t *testing.T, mode, configPath string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+	cmd.Env = append(os.Environ(), lockHelperEnv+"="+mode+":"+configPath)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		stdin.Close()
+		cmd.Wait()
+	})
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	if line != "locked\n" {
+		t.Fatalf("helper did not take the lock: %q", line)
+	}
+	return cmd
+}
+
+func TestTryLockFileLiveHolder(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	holder := startLockHolder(t, "hold", configPath)
+
+	_, err := TryLockFile(configPath)
+	var held *LockHeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("TryLockFile = %v, want *LockHeldError", err)
+	}
+	if held.PID != holder.Process.Pid {
+		t.Errorf("holder PID = %d, want %d", held.PID, holder.Process.Pid)
+	}
+	if !errors.Is(err, domain.ErrConfigBusy) {
+		t.Errorf("%v does not wrap ErrConfigBusy", err)
+	}
+}
+
+func TestTryLockFileStaleTakeover(t *testing.T) {
+	tests := []struct {
+		name  string
+		leave func(t *testing.T, configPath string)
+	}{
+		{"holder crashed", func(t *testing.T, configPath string) {
+			holder := startLockHolder(t, "crash", configPath)
+			holder.Wait()
+		}},
+		{"leftover pid file", func(t *testing.T, configPath string) {
+			if err := os.WriteFile(LockPath(configPath), []byte("999999\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"empty file", func(t *testing.T, configPath string) {
+			if err := os.WriteFile(LockPath(configPath), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.json")
+			tt.leave(t, configPath)
+
+			unlock, err := TryLockFile(configPath)
+			if err != nil {
+				t.Fatalf("TryLockFile over a stale lock: %v", err)
+			}
+			data, _ := os.ReadFile(LockPath(configPath))
+			if got := strings.TrimSpace(string(data)); got != strconv.Itoa(os.Getpid()) {
+				t.Errorf("lock file holds %q, want this process", got)
+			}
+			unlock()
+			if _, err := os.Stat(LockPath(configPath)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("lock file left after release: %v", err)
+			}
+		})
+	}
+}
+
+func TestLockFileNoWait(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	startLockHolder(t, "hold", configPath)
+
+	start := time.Now()
+	_, err := LockFile(configPath, 0)
+	if !errors.Is(err, domain.ErrConfigBusy) {
+		t.Fatalf("LockFile = %v, want ErrConfigBusy", err)
+	}
+	if elapsed := time.Since(start); elapsed >= lockPollInterval {
+		t.Errorf("no-wait lock took %v, want no retry", elapsed)
+	}
+}
+
+func TestLockFileSharedWithinProcess(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	outer, err := TryLockFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := TryLockFile(configPath)
+	if err != nil {
+		t.Fatalf("nested TryLockFile: %v", err)
+	}
+
+	// Releasing the inner owner, even twice, must keep the lock
+	inner()
+	inner()
+	if lockedByOtherProcess(configPath) {
+		t.Fatal("another process took the lock while the outer owner held it")
+	}
+
+	outer()
+	if !lockedByOtherProcess(configPath) {
+		t.Error("another process could not take the lock after the last release")
+	}
+}
+
+// lockedByOtherProcess reports whether a helper process can take the lock.
+func lockedByOtherProcess(configPath string) bool {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+	cmd.Env = append(os.Environ(), lockHelperEnv+"=crash:"+configPath)
+	out, err := cmd.Output()
+	return err == nil && string(out) == "locked\n"
+}
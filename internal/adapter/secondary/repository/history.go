@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// historyMaxBytes caps the history file. An append that would grow it past
+// the cap first moves it aside to a single rotated file, so at most about
+// twice this much history is kept.
+const historyMaxBytes = 1 << 20
+
+// FileHistoryRepository implements domain.HistoryRepository using a JSON lines file.
+// This is a secondary adapter.
+type FileHistoryRepository struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileHistoryRepository creates a new file-based history repository.
+func NewFileHistoryRepository(path string) (domain.HistoryRepository, error) {
+	if path == "" {
+		return nil, errors.New("path is required")
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create history dir: %w", err)
+	}
+
+	return &FileHistoryRepository{path: path}, nil
+}
+
+// persistedRecord represents a single JSON line on disk.
+type persistedRecord struct {
+	Timestamp string `json:"timestamp"`
+	Volume    int    `json:"volume"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	Warning   string `json:"warning,omitempty"`
+	Trigger   string `json:"trigger,omitempty"`
+	// Observed is the volume read back before a scheduled apply
+	Observed *int `json:"observed,omitempty"`
+	// Drift repeats Observed when a significant drift was corrected
+	Drift      *int  `json:"significantDriftFrom,omitempty"`
+	DurationMs int64 `json:"durationMs,omitempty"`
+	DryRun     bool  `json:"dryRun,omitempty"`
+}
+
+// Append writes a record to the end of the history file.
+func (f *FileHistoryRepository) Append(record domain.ApplyRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	persisted := persistedRecord{
+		Timestamp:  record.Timestamp.Format(time.RFC3339),
+		Volume:     record.Volume,
+		Status:     record.Status.String(),
+		Error:      record.Error,
+		Warning:    record.Warning,
+		Trigger:    record.Trigger.String(),
+		DurationMs: record.Duration.Milliseconds(),
+		Observed:   record.Observed,
+		DryRun:     record.DryRun,
+	}
+	if record.SignificantDrift {
+		persisted.Drift = record.Observed
+	}
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return fmt.Errorf("marshal history: %w", err)
+	}
+	if err := f.rotate(int64(len(data)) + 1); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return nil
+}
+
+// rotate moves the history file to its rotated path when appending n more
+// bytes would grow it past historyMaxBytes, replacing an older rotation.
+func (f *FileHistoryRepository) rotate(n int64) error {
+	info, err := os.Stat(f.path)
+	if err != nil || info.Size() == 0 || info.Size()+n <= historyMaxBytes {
+		return nil
+	}
+	if err := os.Rename(f.path, f.rotatedPath()); err != nil {
+		return fmt.Errorf("rotate history: %w", err)
+	}
+	return nil
+}
+
+func (f *FileHistoryRepository) rotatedPath() string {
+	return f.path + ".1"
+}
+
+// Query scans the rotated and current history files and returns the
+// requested page, newest first. Only offset+limit matching records are
+// held in memory at any time.
+func (f *FileHistoryRepository) Query(q domain.HistoryQuery) ([]domain.ApplyRecord, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	window := q.Offset + q.Limit
+	if q.Limit <= 0 {
+		window = 0
+	}
+	ring := make([]domain.ApplyRecord, 0, window)
+	total := 0
+
+	for _, path := range []string{f.rotatedPath(), f.path} {
+		err := scanHistory(path, func(record domain.ApplyRecord) {
+			if !q.Matches(record) {
+				return
+			}
+			total++
+			if window == 0 {
+				return
+			}
+			if len(ring) < window {
+				ring = append(ring, record)
+			} else {
+				ring[(total-1)%window] = record
+			}
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Unroll the ring newest first, then skip the offset
+	page := make([]domain.ApplyRecord, 0, q.Limit)
+	for i := 0; i < len(ring); i++ {
+		idx := (total - 1 - i) % window
+		if i < q.Offset {
+			continue
+		}
+		page = append(page, ring[idx])
+	}
+	return page, total, nil
+}
+
+// scanHistory calls fn for every record in the file at path, oldest first.
+// A missing file holds no records.
+func scanHistory(path string, fn func(domain.ApplyRecord)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("open history: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var persisted persistedRecord
+		if err := json.Unmarshal(scanner.Bytes(), &persisted); err != nil {
+			// Skip torn or corrupt lines rather than failing the whole query
+			continue
+		}
+		fn(fromPersistedRecord(persisted))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read history: %w", err)
+	}
+	return nil
+}
+
+func fromPersistedRecord(persisted persistedRecord) domain.ApplyRecord {
+	record := domain.ApplyRecord{
+		Volume:  persisted.Volume,
+		Status:  parseStatus(persisted.Status),
+		Error:   persisted.Error,
+		Warning: persisted.Warning,
+	}
+	if t, err := time.Parse(time.RFC3339, persisted.Timestamp); err == nil {
+		record.Timestamp = t
+	}
+	if trigger, err := domain.ParseApplyTrigger(persisted.Trigger); err == nil {
+		record.Trigger = trigger
+	}
+	record.Observed = persisted.Observed
+	if persisted.Drift != nil {
+		// Records written before every observation was kept have only this
+		record.SignificantDrift = true
+		record.Observed = persisted.Drift
+	}
+	record.Duration = time.Duration(persisted.DurationMs) * time.Millisecond
+	record.DryRun = persisted.DryRun
+	return record
+}
+
+// HistoryPath returns the history file path that belongs to a config file.
+func HistoryPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "history.jsonl")
+}
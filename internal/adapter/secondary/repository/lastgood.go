@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// LastKnownGoodPath returns where the copy of the last valid user config
+// is kept for a config at configPath.
+func LastKnownGoodPath(configPath string) string {
+	return configPath + ".good"
+}
+
+// WithLastKnownGood keeps a copy of the user config each time it is loaded
+// or saved as valid. With fallback, a user config that can be read but is
+// invalid, e.g. after a bad hand edit, is copied aside to ".invalid" and
+// the last known good copy is loaded in its place, so the daemon still
+// starts. Unreadable files are never replaced.
+func WithLastKnownGood(fallback bool) FileOption {
+	return func(f *FileRepository) {
+		f.keepGood = true
+		f.fallback = fallback
+	}
+}
+
+// invalidConfigError marks a config that was read but holds invalid values.
+type invalidConfigError struct {
+	err error
+}
+
+func (e *invalidConfigError) Error() string { return e.err.Error() }
+func (e *invalidConfigError) Unwrap() error { return e.err }
+
+func (f *FileRepository) load() (domain.Config, domain.ScheduleState, error) {
+	config, state, err := f.loadFrom(f.path)
+	if err != nil || !f.keepGood {
+		return f.fallBack(config, state, err)
+	}
+
+	// Mirror the use case, which moves a target outside another layer's
+	// allowlist instead of rejecting the config
+	check := config
+	check.TargetVolume = check.NearestAllowed(check.TargetVolume)
+	if verr := check.Validate(); verr != nil {
+		if f.fallback {
+			return f.fallBack(config, state, &invalidConfigError{verr})
+		}
+		return config, state, nil
+	}
+
+	if data, err := os.ReadFile(f.path); err == nil {
+		f.keepLastKnownGood(data)
+	}
+	return config, state, nil
+}
+
+// fallBack loads the last known good copy in place of an invalid user
+// config. Any other outcome is returned unchanged.
+func (f *FileRepository) fallBack(config domain.Config, state domain.ScheduleState, err error) (domain.Config, domain.ScheduleState, error) {
+	var invalid *invalidConfigError
+	if !f.fallback || !errors.As(err, &invalid) {
+		return config, state, err
+	}
+	good := LastKnownGoodPath(f.path)
+	if _, statErr := os.Stat(good); statErr != nil {
+		return config, state, err
+	}
+	goodConfig, goodState, goodErr := f.loadFrom(good)
+	if goodErr != nil {
+		return config, state, err
+	}
+
+	aside := f.path + ".invalid"
+	if data, readErr := os.ReadFile(f.path); readErr == nil {
+		if writeErr := writeFileAtomic(aside, data); writeErr != nil {
+			aside = "(not saved: " + writeErr.Error() + ")"
+		}
+	}
+	logging.Warnf("config %s is invalid: %v", f.path, err)
+	logging.Warnf("using the last known good config %s instead; the invalid file was copied to %s and is replaced on the next save", good, aside)
+	return goodConfig, goodState, nil
+}
+
+// keepLastKnownGood refreshes the copy of a valid user config. Failing to
+// do so only loses the fallback, so it is logged rather than returned.
+func (f *FileRepository) keepLastKnownGood(data []byte) {
+	good := LastKnownGoodPath(f.path)
+	if current, err := os.ReadFile(good); err == nil && bytes.Equal(current, data) {
+		return
+	}
+	if err := writeFileAtomic(good, data); err != nil {
+		logging.Warnf("keep last known good config: %v", err)
+	}
+}
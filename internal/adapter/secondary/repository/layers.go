@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// Config layer names, from lowest to highest precedence.
+const (
+	LayerDefault = "default"
+	LayerSystem  = "system"
+	LayerUser    = "user"
+	LayerEnv     = "env"
+)
+
+// Environment variables read by the env layer.
+const (
+	EnvTargetVolume = "MICGAIN_TARGET_VOLUME"
+	EnvInterval     = "MICGAIN_INTERVAL"
+	EnvEnabled      = "MICGAIN_ENABLED"
+	EnvCurve        = "MICGAIN_CURVE"
+	EnvMinVolume    = "MICGAIN_MIN_TARGET_VOLUME"
+)
+
+// configKeys are the JSON keys that hold settings (as opposed to schedule
+// state). Only these keys are merged from the system and env layers.
+var configKeys = []string{
+	"targetVolume", "intervalSeconds", "enabled", "scheduleMode", "schedule", "timezone", "adaptiveInterval", "maxIntervalSeconds", "rescheduleOnManualApply",
+	"minTargetVolume", "errorThreshold", "maxRetries", "retryBackoffSeconds", "rampDurationMs", "rampSteps", "staggerBetweenDevicesMs", "driftAlertThreshold", "redactErrors", "deviceName", "onDeviceAbsent", "formatVolumes", "allowedVolumes", "appVolumes", "output", "noise", "curve", "quietHours", "profiles", "activeProfile", "dryRun",
+	"parkVolume", "fadeOnPark", "reapplyOnPowerChange", "powerPollSeconds", "preApplyCmd", "postApplyCmd", "abortOnPreApplyFailure", "applyCmdTimeoutSeconds", "timestampFormat",
+}
+
+// lockedKey locks the config when set in the system layer. It is honoured
+// nowhere else, so that users cannot unlock by editing their own file.
+const lockedKey = "locked"
+
+// FileOption configures optional behavior of the file repository.
+type FileOption func(*FileRepository)
+
+// WithSystemLayer merges an admin-provisioned config below the user file.
+// A missing system file is ignored.
+func WithSystemLayer(path string) FileOption {
+	return func(f *FileRepository) {
+		f.systemPath = path
+	}
+}
+
+// WithEnvLayer merges MICGAIN_* environment variables above the user file.
+func WithEnvLayer() FileOption {
+	return func(f *FileRepository) {
+		f.useEnv = true
+	}
+}
+
+// WithReadOnly never writes the config file: older layouts are upgraded in
+// memory only, and Save and Update fail with ErrReadOnly.
+func WithReadOnly() FileOption {
+	return func(f *FileRepository) {
+		f.readOnly = true
+	}
+}
+
+// LayerInfo describes a single config layer.
+type LayerInfo struct {
+	Name   string
+	Source string
+	Found  bool
+}
+
+// Layers returns the layers consulted by the last Load, lowest first.
+func (f *FileRepository) Layers() []LayerInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]LayerInfo(nil), f.layers...)
+}
+
+// Origins returns which layer each setting came from in the last Load.
+func (f *FileRepository) Origins() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	origins := make(map[string]string, len(f.origins))
+	for k, v := range f.origins {
+		origins[k] = v
+	}
+	return origins
+}
+
+// mergeFileLayer overlays the keys present in the file, read with format,
+// onto persisted. When configOnly is set, schedule state keys in the file
+// are ignored.
+func (f *FileRepository) mergeFileLayer(name, path string, format codec, configOnly bool, persisted *persistedData) error {
+	info := LayerInfo{Name: name, Source: path}
+	defer func() { f.layers = append(f.layers, info) }()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read %s config: %w", name, err)
+	}
+	info.Found = true
+	if data, err = format.decode(data); err != nil {
+		return fmt.Errorf("decode %s config: %w", name, err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("unmarshal %s config: %w", name, err)
+	}
+	migrated, err := migrate(path, raw)
+	if err != nil {
+		return err
+	}
+	if migrated && name == LayerUser && !f.readOnly {
+		f.rewriteMigrated(path, raw)
+	}
+	if name == LayerSystem {
+		if locked, ok := raw[lockedKey]; ok {
+			if err := json.Unmarshal(locked, &f.locked); err != nil {
+				return fmt.Errorf("%s config: invalid %s: %w", name, lockedKey, err)
+			}
+		}
+	}
+	if configOnly {
+		for key := range raw {
+			if !isConfigKey(key) {
+				delete(raw, key)
+			}
+		}
+	}
+	original := maps.Clone(raw)
+	templated, err := expandTemplates(raw)
+	if err != nil {
+		return fmt.Errorf("%s config %s: %w", name, path, err)
+	}
+	if configOnly || migrated || len(templated) > 0 {
+		if data, err = json.Marshal(raw); err != nil {
+			return fmt.Errorf("marshal %s config: %w", name, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, persisted); err != nil {
+		return fmt.Errorf("unmarshal %s config: %w", name, err)
+	}
+	for key := range raw {
+		if isConfigKey(key) {
+			f.origins[key] = name
+		}
+	}
+	if name == LayerUser {
+		// Saves write templated settings back as written, not as expanded
+		f.userRaw = original
+		for _, key := range templated {
+			f.templated[key] = true
+		}
+	}
+	return nil
+}
+
+// mergeEnvLayer overlays MICGAIN_* environment variables onto persisted.
+func (f *FileRepository) mergeEnvLayer(persisted *persistedData) error {
+	info := LayerInfo{Name: LayerEnv, Source: "MICGAIN_*"}
+	defer func() { f.layers = append(f.layers, info) }()
+
+	if v, ok := os.LookupEnv(EnvTargetVolume); ok {
+		volume, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid volume %q", EnvTargetVolume, v)
+		}
+		persisted.TargetVolume = volume
+		f.origins["targetVolume"] = LayerEnv
+		info.Found = true
+	}
+	if v, ok := os.LookupEnv(EnvInterval); ok {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q", EnvInterval, v)
+		}
+		persisted.IntervalSeconds = persistedDuration(interval)
+		f.origins["intervalSeconds"] = LayerEnv
+		info.Found = true
+	}
+	if v, ok := os.LookupEnv(EnvEnabled); ok {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid bool %q", EnvEnabled, v)
+		}
+		persisted.Enabled = enabled
+		f.origins["enabled"] = LayerEnv
+		info.Found = true
+	}
+	if v, ok := os.LookupEnv(EnvMinVolume); ok {
+		volume, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s: invalid volume %q", EnvMinVolume, v)
+		}
+		persisted.MinTargetVolume = volume
+		f.origins["minTargetVolume"] = LayerEnv
+		info.Found = true
+	}
+	if v, ok := os.LookupEnv(EnvCurve); ok {
+		curve, err := domain.ParseCurve(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvCurve, err)
+		}
+		persisted.Curve = toPersistedCurve(curve)
+		f.origins["curve"] = LayerEnv
+		info.Found = true
+	}
+	return nil
+}
+
+// userLayerData marshals the data for the user file. Settings that came
+// from the system or env layer and were not changed since Load keep the
+// user file's own value (or stay absent), so that those layers are never
+// baked into the user file.
+func (f *FileRepository) userLayerData(persisted persistedData) ([]byte, error) {
+	data, err := json.MarshalIndent(persisted, "", "  ")
+	if err != nil || f.loaded == nil {
+		return data, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	// Load seeds the nested noise defaults, which Save leaves out; compare
+	// like with like
+	current := maps.Clone(raw)
+	if persisted.Noise == nil {
+		if current["noise"], err = json.Marshal(toPersistedNoise(domain.DefaultNoiseControl())); err != nil {
+			return nil, err
+		}
+	}
+	rewritten := false
+	for _, key := range configKeys {
+		if origin := f.origins[key]; origin != LayerSystem && origin != LayerEnv && !f.templated[key] {
+			continue
+		}
+		if _, ok := current[key]; !ok && f.loaded[key] == nil {
+			continue
+		}
+		if !bytes.Equal(compactJSON(current[key]), compactJSON(f.loaded[key])) {
+			// Changed by the caller, so it now belongs to the user layer
+			f.origins[key] = LayerUser
+			f.loaded[key] = current[key]
+			delete(f.templated, key)
+			continue
+		}
+		if userValue, ok := f.userRaw[key]; ok {
+			raw[key] = userValue
+		} else {
+			delete(raw, key)
+		}
+		rewritten = true
+	}
+	if !rewritten {
+		return data, nil
+	}
+	return json.MarshalIndent(raw, "", "  ")
+}
+
+// rememberLoaded records the merged settings so Save can tell which ones changed.
+func (f *FileRepository) rememberLoaded(persisted persistedData) error {
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &f.loaded)
+}
+
+func compactJSON(raw json.RawMessage) []byte {
+	if raw == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
+
+func isConfigKey(key string) bool {
+	for _, k := range configKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSystemPath returns the default admin-provisioned config path.
+func DefaultSystemPath() string {
+	return "/etc/micgain-manager/config.json"
+}
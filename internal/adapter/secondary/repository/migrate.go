@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"micgain-manager/internal/logging"
+)
+
+// SchemaVersion is the config file layout this build reads and writes,
+// recorded in the file's "schemaVersion" key.
+const SchemaVersion = 2
+
+// schemaVersionKey holds the layout version of a config file. Files
+// written before it existed have no version, which is version 0.
+const schemaVersionKey = "schemaVersion"
+
+// migrations upgrade a config file one layout version at a time: the
+// migration at index i turns version i into version i+1.
+var migrations = []func(raw map[string]json.RawMessage) error{
+	// 0 -> 1: the unversioned layout is kept as is; only the version is
+	// recorded
+	func(map[string]json.RawMessage) error { return nil },
+	// 1 -> 2: durations are written as strings ("90s"); the seconds form
+	// is still read, so the values are left to the next save
+	func(map[string]json.RawMessage) error { return nil },
+}
+
+// FutureSchemaError reports a config file written by a newer build, whose
+// layout this build does not know.
+type FutureSchemaError struct {
+	Path    string
+	Version int
+}
+
+func (e *FutureSchemaError) Error() string {
+	return fmt.Sprintf("config %s has schemaVersion %d, but this build only knows up to %d; upgrade micgain-manager",
+		e.Path, e.Version, SchemaVersion)
+}
+
+// migrate upgrades raw, read from path, to the current layout in place
+// and reports whether it changed. Files from a newer build are rejected
+// rather than loaded with their unknown settings dropped.
+func migrate(path string, raw map[string]json.RawMessage) (bool, error) {
+	version := 0
+	if value, ok := raw[schemaVersionKey]; ok {
+		if err := json.Unmarshal(value, &version); err != nil || version < 0 {
+			return false, fmt.Errorf("config %s: invalid %s %s", path, schemaVersionKey, value)
+		}
+	}
+	if version > SchemaVersion {
+		return false, &FutureSchemaError{Path: path, Version: version}
+	}
+	if version == SchemaVersion {
+		return false, nil
+	}
+	for v := version; v < SchemaVersion; v++ {
+		if err := migrations[v](raw); err != nil {
+			return false, fmt.Errorf("config %s: migrate schemaVersion %d to %d: %w", path, v, v+1, err)
+		}
+	}
+	raw[schemaVersionKey] = json.RawMessage(fmt.Sprint(SchemaVersion))
+	logging.Infof("config %s: migrated schemaVersion %d to %d", path, version, SchemaVersion)
+	return true, nil
+}
+
+// rewriteMigrated saves a migrated user file, so the upgrade runs once.
+// Failing to do so only defers the rewrite to the next save, so it is
+// logged rather than returned.
+func (f *FileRepository) rewriteMigrated(path string, raw map[string]json.RawMessage) {
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err == nil {
+		previous, _ := os.ReadFile(path)
+		data, err = f.codec.encode(data, previous)
+	}
+	if err != nil {
+		logging.Warnf("rewrite migrated config: %v", err)
+		return
+	}
+	unlock, err := LockFile(path, f.lockTimeout)
+	if err != nil {
+		logging.Warnf("rewrite migrated config: %v", err)
+		return
+	}
+	defer unlock()
+	if err := writeFileAtomic(path, data); err != nil {
+		logging.Warnf("rewrite migrated config: %v", err)
+		return
+	}
+	if path == f.path {
+		f.written = data
+	}
+}
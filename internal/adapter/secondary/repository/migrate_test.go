@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// copyFixture copies testdata/name into a temporary directory and returns
+// the path of the copy, so that a test may rewrite it.
+func copyFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	return path
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		wantRewrite bool
+	}{
+		{"v0.json", true},
+		{"v2.json", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			path := copyFixture(t, tt.fixture)
+			before, _ := os.ReadFile(path)
+			repo, err := NewFileRepository(path)
+			if err != nil {
+				t.Fatalf("NewFileRepository: %v", err)
+			}
+
+			config, state, err := repo.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if config.TargetVolume != 65 || config.Interval != 2*time.Minute || !config.Enabled {
+				t.Errorf("config = target %d, interval %s, enabled %v, want 65, 2m0s, true",
+					config.TargetVolume, config.Interval, config.Enabled)
+			}
+			if config.RetryBackoff != 2500*time.Millisecond {
+				t.Errorf("retry backoff = %s, want 2.5s", config.RetryBackoff)
+			}
+			applied := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+			if !state.LastApplied.Equal(applied) || !state.NextRun.Equal(applied.Add(2*time.Minute)) {
+				t.Errorf("state = last %v, next %v, want %v and 2m later", state.LastApplied, state.NextRun, applied)
+			}
+			if state.LastApplyStatus != domain.StatusSuccess {
+				t.Errorf("status = %s, want ok", state.LastApplyStatus)
+			}
+
+			after, _ := os.ReadFile(path)
+			if rewritten := !bytes.Equal(before, after); rewritten != tt.wantRewrite {
+				t.Fatalf("file rewritten = %v, want %v", rewritten, tt.wantRewrite)
+			}
+			var raw map[string]any
+			if err := json.Unmarshal(after, &raw); err != nil {
+				t.Fatalf("rewritten file: %v", err)
+			}
+			if raw[schemaVersionKey] != float64(SchemaVersion) {
+				t.Errorf("schemaVersion on disk = %v, want %d", raw[schemaVersionKey], SchemaVersion)
+			}
+
+			// The upgrade runs once
+			if _, _, err := repo.Load(); err != nil {
+				t.Fatalf("second Load: %v", err)
+			}
+			if again, _ := os.ReadFile(path); !bytes.Equal(again, after) {
+				t.Error("second Load rewrote the file again")
+			}
+		})
+	}
+}
+
+func TestMigrateReadOnlyKeepsFile(t *testing.T) {
+	path := copyFixture(t, "v0.json")
+	before, _ := os.ReadFile(path)
+	repo, err := NewFileRepository(path, WithReadOnly())
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+	if _, _, err := repo.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("read-only Load rewrote the file")
+	}
+}
+
+func TestMigrateFutureVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"schemaVersion": 99, "targetVolume": 65}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository: %v", err)
+	}
+	_, _, err = repo.Load()
+	var future *FutureSchemaError
+	if !errors.As(err, &future) || future.Version != 99 {
+		t.Fatalf("Load = %v, want a FutureSchemaError for version 99", err)
+	}
+}
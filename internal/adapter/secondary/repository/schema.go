@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SchemaID identifies the config schema document.
+const SchemaID = "urn:micgain-manager:config"
+
+// ConfigSchema returns a JSON Schema for the settings in a config file.
+// It is generated from the on-disk structures, so it follows them: field
+// types come from the Go types and ranges, enums and patterns from the
+// "schema" struct tags. Schedule state written by the tool is left out,
+// but allowed, since it lives in the same file.
+func ConfigSchema() map[string]any {
+	root := structSchema(reflect.TypeFor[persistedData](), isConfigKey)
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["$id"] = SchemaID
+	root["title"] = "micgain-manager config"
+	root["additionalProperties"] = true
+	delete(root, "required")
+
+	properties := root["properties"].(map[string]any)
+	// Honoured only in the system layer, so it is not a struct field
+	properties[lockedKey] = map[string]any{
+		"type":        "boolean",
+		"description": "Locks the config. Only honoured in the system config layer.",
+	}
+	properties[schemaVersionKey] = map[string]any{
+		"type":        "integer",
+		"minimum":     0,
+		"maximum":     SchemaVersion,
+		"description": "Layout version of the file. Older files are upgraded on load.",
+	}
+	return root
+}
+
+// structSchema describes the JSON fields of t accepted by include.
+// Fields without omitempty are required.
+func structSchema(t reflect.Type, include func(string) bool) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !include(name) {
+			continue
+		}
+		properties[name] = typeSchema(field.Type, field.Tag.Get("schema"))
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// typeSchema describes a value of type t. The constraints in tag apply to
+// the value itself, or to the items of a slice.
+func typeSchema(t reflect.Type, tag string) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var schema map[string]any
+	switch {
+	case t == reflect.TypeFor[persistedDuration]():
+		// Ranges apply to the seconds form only
+		schema = map[string]any{
+			"type":        []string{"string", "number"},
+			"description": `A duration such as "90s", or a number of seconds.`,
+		}
+	case t.Kind() == reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), tag)}
+	case t.Kind() == reflect.Struct:
+		return structSchema(t, func(string) bool { return true })
+	case t.Kind() == reflect.Bool:
+		schema = map[string]any{"type": "boolean"}
+	case t.Kind() == reflect.Int:
+		schema = map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float64:
+		schema = map[string]any{"type": "number"}
+	default:
+		schema = map[string]any{"type": "string"}
+	}
+
+	for _, constraint := range strings.Split(tag, ",") {
+		key, value, ok := strings.Cut(constraint, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "min":
+			schema["minimum"], _ = strconv.Atoi(value)
+		case "max":
+			schema["maximum"], _ = strconv.Atoi(value)
+		case "enum":
+			schema["enum"] = strings.Split(value, "|")
+		case "pattern":
+			schema["pattern"] = value
+		}
+	}
+	return schema
+}
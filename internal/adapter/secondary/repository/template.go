@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expandTemplates resolves ${VAR} and ${VAR:-default} placeholders in the
+// config keys of a file layer from the environment, so that one committed
+// config can adapt per host. It returns the keys that held placeholders.
+// A string that is a single placeholder expanding to a number or a boolean
+// becomes that number or boolean, so numeric settings can be templated
+// too. A variable that is unset, with no default, is an error.
+func expandTemplates(raw map[string]json.RawMessage) ([]string, error) {
+	var templated []string
+	for key, value := range raw {
+		if !isConfigKey(key) || !strings.Contains(string(value), "${") {
+			continue
+		}
+		var tree any
+		if err := json.Unmarshal(value, &tree); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		expanded, err := expandValue(tree)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		data, err := json.Marshal(expanded)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		raw[key] = data
+		templated = append(templated, key)
+	}
+	return templated, nil
+}
+
+func expandValue(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return expandString(v)
+	case []any:
+		for i, item := range v {
+			expanded, err := expandValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+	case map[string]any:
+		for k, item := range v {
+			expanded, err := expandValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = expanded
+		}
+	}
+	return value, nil
+}
+
+func expandString(s string) (any, error) {
+	var out strings.Builder
+	rest := s
+	whole := strings.HasPrefix(s, "${") && strings.Index(s, "}") == len(s)-1
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			out.WriteString(rest)
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in %q", s)
+		}
+		out.WriteString(rest[:start])
+		value, err := lookupPlaceholder(rest[start+2 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString(value)
+		rest = rest[start+end+1:]
+	}
+
+	result := out.String()
+	if whole {
+		var literal any
+		if err := json.Unmarshal([]byte(result), &literal); err == nil {
+			switch literal.(type) {
+			case float64, bool:
+				return literal, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+// lookupPlaceholder resolves "VAR" or "VAR:-default". As in the shell, the
+// default is used when the variable is unset or empty.
+func lookupPlaceholder(expr string) (string, error) {
+	name, def, hasDefault := strings.Cut(expr, ":-")
+	if name == "" {
+		return "", fmt.Errorf("empty placeholder ${%s}", expr)
+	}
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	if hasDefault {
+		return def, nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set (use ${%s:-default} for a default)", name, name)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Timestamp formats for the config file, chosen with its "timestampFormat" key.
+const (
+	// TimestampRFC3339 writes timestamps as RFC3339 strings (the default).
+	TimestampRFC3339 = "rfc3339"
+	// TimestampEpoch writes timestamps as Unix seconds.
+	TimestampEpoch = "epoch"
+)
+
+// validateTimestampFormat accepts the known formats; empty means RFC3339.
+func validateTimestampFormat(format string) error {
+	switch format {
+	case "", TimestampRFC3339, TimestampEpoch:
+		return nil
+	default:
+		return fmt.Errorf("unknown timestampFormat %q (rfc3339 or epoch)", format)
+	}
+}
+
+// persistedTime is a timestamp on disk, written as an RFC3339 string or as
+// Unix seconds. Either form is read back, whatever the configured format.
+type persistedTime struct {
+	t     time.Time
+	epoch bool
+}
+
+// newPersistedTime returns nil for the zero time, so it is left out.
+func newPersistedTime(t time.Time) *persistedTime {
+	if t.IsZero() {
+		return nil
+	}
+	return &persistedTime{t: t}
+}
+
+// Time returns the timestamp, or the zero time when it is unset.
+func (p *persistedTime) Time() time.Time {
+	if p == nil {
+		return time.Time{}
+	}
+	return p.t
+}
+
+func (p persistedTime) MarshalJSON() ([]byte, error) {
+	if p.epoch {
+		return []byte(strconv.FormatInt(p.t.Unix(), 10)), nil
+	}
+	return json.Marshal(p.t.Format(time.RFC3339))
+}
+
+// UnmarshalJSON accepts both forms. An unreadable value loads as unset
+// rather than failing the whole config.
+func (p *persistedTime) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*p = persistedTime{t: time.Unix(int64(seconds), 0), epoch: true}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			*p = persistedTime{t: t}
+		}
+	}
+	return nil
+}
+
+// setTimestampFormat switches every timestamp in persisted to format.
+func (persisted *persistedData) setTimestampFormat(format string) {
+	persisted.TimestampFormat = format
+	epoch := format == TimestampEpoch
+	for _, t := range []*persistedTime{persisted.LastApplied, persisted.FirstApplied, persisted.LastWakeApply, persisted.NextRun} {
+		if t != nil {
+			t.epoch = epoch
+		}
+	}
+	if persisted.Hold != nil && persisted.Hold.Since != nil {
+		persisted.Hold.Since.epoch = epoch
+	}
+}
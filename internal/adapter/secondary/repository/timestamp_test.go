@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+func TestTimestampFormats(t *testing.T) {
+	applied := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	epoch := fmt.Sprint(applied.Unix())
+	rfc3339 := `"` + applied.Format(time.RFC3339) + `"`
+
+	tests := []struct {
+		name   string
+		format string
+		// written is how the file on disk holds lastApplied before the save
+		written string
+		want    string
+	}{
+		{"rfc3339", TimestampRFC3339, rfc3339, rfc3339},
+		{"default", "", rfc3339, rfc3339},
+		{"epoch", TimestampEpoch, epoch, epoch},
+		{"rfc3339 reading epoch", TimestampRFC3339, epoch, rfc3339},
+		{"epoch reading rfc3339", TimestampEpoch, rfc3339, epoch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepository(t, "config.json")
+			data := fmt.Sprintf(`{"schemaVersion": %d, "timestampFormat": %q, "lastApplied": %s, "lastApplyStatus": "ok"}`,
+				SchemaVersion, tt.format, tt.written)
+			if err := os.WriteFile(repo.path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			config, state, err := repo.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if !state.LastApplied.Equal(applied) {
+				t.Fatalf("loaded lastApplied %v, want %v", state.LastApplied, applied)
+			}
+
+			state.NextRun = applied.Add(90 * time.Second)
+			if err := repo.Save(config, state); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			saved, err := os.ReadFile(repo.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := `"lastApplied": ` + tt.want; !strings.Contains(string(saved), want) {
+				t.Errorf("saved file lacks %s:\n%s", want, saved)
+			}
+
+			_, state, err = repo.Load()
+			if err != nil {
+				t.Fatalf("reload: %v", err)
+			}
+			if !state.LastApplied.Equal(applied) || !state.NextRun.Equal(applied.Add(90*time.Second)) {
+				t.Errorf("reloaded last %v, next %v", state.LastApplied, state.NextRun)
+			}
+			if state.LastApplyStatus != domain.StatusSuccess {
+				t.Errorf("status = %s, want ok", state.LastApplyStatus)
+			}
+		})
+	}
+}
+
+func TestUnknownTimestampFormat(t *testing.T) {
+	repo := newTestRepository(t, "config.json")
+	if err := os.WriteFile(repo.path, []byte(`{"timestampFormat": "unix-ms"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := repo.Load(); err == nil || !strings.Contains(err.Error(), "unix-ms") {
+		t.Errorf("Load = %v, want an unknown timestampFormat error", err)
+	}
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"micgain-manager/internal/logging"
+)
+
+// WatchDebounce is how long Watch waits for writes to a config file to
+// settle before reporting them, so that an editor saving in several steps
+// causes a single reload.
+const WatchDebounce = 300 * time.Millisecond
+
+// Watch reports changes to the user file and the system file. It watches
+// their directories rather than the files, since atomic saves (ours and
+// most editors') replace the file. A change that leaves the user file as
+// this repository last saved it is not reported, so saving does not
+// trigger a reload of what was just saved.
+func (f *FileRepository) Watch(ctx context.Context) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("watch config: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(f.path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("watch config: %w", err)
+	}
+	files := map[string]bool{filepath.Clean(f.path): true}
+	if f.systemPath != "" {
+		// A missing system directory just means there is no system file
+		if err := watcher.Add(filepath.Dir(f.systemPath)); err != nil {
+			logging.Debugf("watch system config: %v", err)
+		} else {
+			files[filepath.Clean(f.systemPath)] = true
+		}
+	}
+
+	changes := make(chan struct{}, 1)
+	go f.watch(ctx, watcher, files, changes)
+	return changes, nil
+}
+
+func (f *FileRepository) watch(ctx context.Context, watcher *fsnotify.Watcher, files map[string]bool, changes chan<- struct{}) {
+	defer watcher.Close()
+	defer close(changes)
+
+	var settle <-chan time.Time
+	systemChanged := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Clean(event.Name)
+			if !files[name] || event.Op == fsnotify.Chmod {
+				continue
+			}
+			if name != filepath.Clean(f.path) {
+				systemChanged = true
+			}
+			settle = time.After(WatchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logging.Warnf("watch config: %v", err)
+		case <-settle:
+			settle = nil
+			if !systemChanged && f.ownSave() {
+				continue
+			}
+			systemChanged = false
+			select {
+			case changes <- struct{}{}:
+			default:
+				// A change is already pending
+			}
+		}
+	}
+}
+
+// ownSave reports whether the user file holds what this repository last
+// saved.
+func (f *FileRepository) ownSave() bool {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.written != nil && bytes.Equal(data, f.written)
+}
@@ -0,0 +1,94 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sendTimeout bounds a ping so that a slow endpoint never holds anything up.
+const sendTimeout = 5 * time.Second
+
+// Settings is the user's telemetry choice. It lives in its own file next
+// to the config, so that it survives config resets and is never merged
+// from the system or env layers.
+type Settings struct {
+	Enabled     bool   `json:"enabled"`
+	Endpoint    string `json:"endpoint,omitempty"`
+	NoticeShown bool   `json:"noticeShown,omitempty"`
+}
+
+// Ping is everything a startup ping sends. It has no identifiers: no host
+// name, user, address, config values or install id.
+type Ping struct {
+	Version    string `json:"version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	Controller string `json:"controller"`
+}
+
+// SettingsPath returns the settings file next to the given config file.
+func SettingsPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "telemetry.json")
+}
+
+// LoadSettings reads the settings; a missing file means telemetry is off.
+func LoadSettings(path string) (Settings, error) {
+	var settings Settings
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// SaveSettings writes the settings file.
+func SaveSettings(path string, settings Settings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Send posts the ping as JSON to endpoint.
+func Send(ctx context.Context, endpoint string, ping Ping) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(ping)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", endpoint, resp.Status)
+	}
+	return nil
+}
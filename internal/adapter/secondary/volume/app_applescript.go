@@ -0,0 +1,51 @@
+package volume
+
+import (
+	"fmt"
+	"strings"
+
+	"micgain-manager/internal/domain"
+)
+
+// unsupportedScriptErrors are AppleScript error numbers meaning the app has
+// no scriptable input volume: "doesn't understand", "can't get" and
+// "can't set" the property, or an unknown term at compile time.
+var unsupportedScriptErrors = []string{"(-1708)", "(-1728)", "(-10006)", "(-2741)", "(-2753)"}
+
+// AppleScriptAppController implements domain.AppVolumeController by asking
+// each application, through osascript, to set its own input volume.
+// Only apps whose scripting dictionary exposes an input volume support it.
+// This is a secondary adapter.
+type AppleScriptAppController struct{}
+
+// NewAppleScriptAppController creates a new AppleScript app volume controller.
+func NewAppleScriptAppController() domain.AppVolumeController {
+	return &AppleScriptAppController{}
+}
+
+// SetAppVolume sets the app's input volume. An app that is not running is
+// left alone rather than launched.
+func (a *AppleScriptAppController) SetAppVolume(app string, volume int) error {
+	if volume < 0 || volume > 100 {
+		return fmt.Errorf("volume must be between 0 and 100, got %d", volume)
+	}
+
+	name := quoteAppleScript(app)
+	script := fmt.Sprintf(`if application %s is running then tell application %s to set input volume to %d`, name, name, volume)
+	if _, err := runOSAScript(script); err != nil {
+		for _, code := range unsupportedScriptErrors {
+			if strings.Contains(err.Error(), code) {
+				return fmt.Errorf("%w: %s has no scriptable input volume", domain.ErrNotSupported, app)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// quoteAppleScript renders s as an AppleScript string literal.
+func quoteAppleScript(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -1,8 +1,11 @@
 package volume
 
 import (
+	"bytes"
 	"fmt"
 	"os/exec"
+	"strconv"
+	"strings"
 
 	"micgain-manager/internal/domain"
 )
@@ -16,6 +19,9 @@ func NewAppleScriptController() domain.VolumeController {
 	return &AppleScriptController{}
 }
 
+// String names the controller in the apply plan.
+func (a *AppleScriptController) String() string { return "applescript (osascript)" }
+
 // SetVolume sets the microphone input volume using osascript.
 func (a *AppleScriptController) SetVolume(volume int) error {
 	if volume < 0 || volume > 100 {
@@ -23,10 +29,88 @@ func (a *AppleScriptController) SetVolume(volume int) error {
 	}
 
 	cmd := exec.Command("osascript", "-e", fmt.Sprintf("set volume input volume %d", volume))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("osascript failed: %w, output: %s", err, string(output))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("osascript failed: %w, output: %s%s", err, stdout.String(), stderr.String())
 	}
 
+	// osascript may exit 0 while still reporting a degraded result on stderr
+	if warning := strings.TrimSpace(stderr.String()); warning != "" {
+		return &domain.ApplyWarning{Message: warning}
+	}
+
+	return nil
+}
+
+// Probe checks that osascript is installed and can read the volume
+// settings, which changes nothing.
+func (a *AppleScriptController) Probe() error {
+	if _, err := exec.LookPath("osascript"); err != nil {
+		return fmt.Errorf("osascript not found (the applescript controller needs macOS): %w", err)
+	}
+	if _, err := runOSAScript("get volume settings"); err != nil {
+		return err
+	}
 	return nil
 }
+
+// GetVolume reads the current microphone input volume using osascript.
+func (a *AppleScriptController) GetVolume() (int, error) {
+	out, err := runOSAScript("input volume of (get volume settings)")
+	if err != nil {
+		return 0, err
+	}
+	volume, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected osascript output %q", out)
+	}
+	return volume, nil
+}
+
+// SetKindVolume sets the input or output volume using osascript.
+func (a *AppleScriptController) SetKindVolume(kind domain.VolumeKind, volume int) error {
+	if kind == domain.VolumeInput {
+		return a.SetVolume(volume)
+	}
+	if volume < 0 || volume > 100 {
+		return fmt.Errorf("volume must be between 0 and 100, got %d", volume)
+	}
+	_, err := runOSAScript(fmt.Sprintf("set volume output volume %d", volume))
+	return err
+}
+
+// GetKindVolume reads the input or output volume using osascript.
+func (a *AppleScriptController) GetKindVolume(kind domain.VolumeKind) (int, error) {
+	if kind == domain.VolumeInput {
+		return a.GetVolume()
+	}
+	out, err := runOSAScript("output volume of (get volume settings)")
+	if err != nil {
+		return 0, err
+	}
+	volume, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected osascript output %q", out)
+	}
+	return volume, nil
+}
+
+// FrontmostApp returns the name of the application that currently has focus.
+// It is used for diagnostics only.
+func FrontmostApp() (string, error) {
+	return runOSAScript(`tell application "System Events" to get name of first application process whose frontmost is true`)
+}
+
+// runOSAScript runs a single AppleScript statement and returns its trimmed stdout.
+func runOSAScript(script string) (string, error) {
+	cmd := exec.Command("osascript", "-e", script)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("osascript failed: %w, output: %s%s", err, stdout.String(), stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
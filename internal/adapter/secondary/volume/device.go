@@ -0,0 +1,135 @@
+package volume
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"micgain-manager/internal/domain"
+)
+
+// switchAudioSource is the switchaudio-osx command used to select input
+// devices; AppleScript can only reach the default input.
+const switchAudioSource = "SwitchAudioSource"
+
+// SetDeviceVolume sets the input volume of the named device. The device is
+// made the default input for the duration of the call and the previous
+// default is restored afterwards.
+func (a *AppleScriptController) SetDeviceVolume(device string, volume int) error {
+	return withInputDevice(device, func() error {
+		return a.SetVolume(volume)
+	})
+}
+
+// GetDeviceVolume reads the input volume of the named device, selecting it
+// the same way as SetDeviceVolume. A failure to restore the default input
+// does not fail the read; the next set reports it.
+func (a *AppleScriptController) GetDeviceVolume(device string) (int, error) {
+	var volume int
+	err := withInputDevice(device, func() error {
+		var err error
+		volume, err = a.GetVolume()
+		return err
+	})
+	var warning *domain.ApplyWarning
+	if errors.As(err, &warning) {
+		return volume, nil
+	}
+	return volume, err
+}
+
+// withInputDevice runs fn with device as the default input. Failing to
+// switch back after a successful fn is reported as a warning, since the
+// volume itself was handled.
+func withInputDevice(device string, fn func() error) error {
+	if _, err := exec.LookPath(switchAudioSource); err != nil {
+		return fmt.Errorf("%w: selecting input device %q requires %s (brew install switchaudio-osx)",
+			domain.ErrNotSupported, device, switchAudioSource)
+	}
+	previous, err := runSwitchAudioSource("-t", "input", "-c")
+	if err != nil {
+		return err
+	}
+	if previous == device {
+		return fn()
+	}
+	if _, err := runSwitchAudioSource("-t", "input", "-s", device); err != nil {
+		return fmt.Errorf("select input device %q: %w", device, err)
+	}
+
+	err = fn()
+	if _, restoreErr := runSwitchAudioSource("-t", "input", "-s", previous); restoreErr != nil {
+		restore := fmt.Sprintf("could not restore default input %q: %v", previous, restoreErr)
+		var warning *domain.ApplyWarning
+		switch {
+		case errors.As(err, &warning):
+			return &domain.ApplyWarning{Message: warning.Message + "; " + restore}
+		case err == nil:
+			return &domain.ApplyWarning{Message: restore}
+		default:
+			return fmt.Errorf("%w; %s", err, restore)
+		}
+	}
+	return err
+}
+
+// runSwitchAudioSource runs SwitchAudioSource and returns its trimmed stdout.
+func runSwitchAudioSource(args ...string) (string, error) {
+	cmd := exec.Command(switchAudioSource, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %w, output: %s%s", switchAudioSource, err, stdout.String(), stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// spAudioData is the part of "system_profiler -json SPAudioDataType"
+// describing the audio devices.
+type spAudioData struct {
+	SPAudioDataType []struct {
+		Items []spAudioDevice `json:"_items"`
+	} `json:"SPAudioDataType"`
+}
+
+type spAudioDevice struct {
+	Name          string `json:"_name"`
+	InputChannels int    `json:"coreaudio_device_input"`
+	DefaultInput  string `json:"coreaudio_default_audio_input_device"`
+	// SampleRate is the current rate in Hz.
+	SampleRate float64 `json:"coreaudio_device_srate"`
+}
+
+// ListInputDevices lists the devices with input channels as reported by
+// system_profiler.
+func (a *AppleScriptController) ListInputDevices() ([]domain.AudioDevice, error) {
+	out, err := exec.Command("system_profiler", "-json", "SPAudioDataType").Output()
+	if err != nil {
+		return nil, fmt.Errorf("system_profiler failed: %w", err)
+	}
+	var data spAudioData
+	if err := json.Unmarshal(out, &data); err != nil {
+		return nil, fmt.Errorf("unexpected system_profiler output: %w", err)
+	}
+
+	var devices []domain.AudioDevice
+	for _, group := range data.SPAudioDataType {
+		for _, item := range group.Items {
+			if item.InputChannels == 0 {
+				continue
+			}
+			devices = append(devices, domain.AudioDevice{
+				ID:      len(devices) + 1,
+				Name:    item.Name,
+				Default: item.DefaultInput == "spaudio_yes",
+
+				SampleRate: int(item.SampleRate),
+			})
+		}
+	}
+	return devices, nil
+}
@@ -0,0 +1,116 @@
+package volume
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// DefaultExecTimeout bounds each call of an external controller command.
+const DefaultExecTimeout = 10 * time.Second
+
+// ExecNotSupportedExit is the exit code for "this operation is not supported".
+const ExecNotSupportedExit = 3
+
+// ExecController implements domain.VolumeController by running an external
+// command, so that volume control can be extended with any script:
+//
+//	<command> set <volume>   exit 0 on success
+//	<command> get            print the volume (0-100) on stdout
+//
+// A non-zero exit is an error. Output on stderr from a successful "set"
+// is reported as a warning, like osascript's. A "get" that exits with
+// ExecNotSupportedExit means the command cannot read the volume back.
+// This is a secondary adapter.
+type ExecController struct {
+	argv    []string
+	timeout time.Duration
+}
+
+// NewExecController creates a controller running argv plus the operation.
+// It fails early when the command cannot be found.
+func NewExecController(argv []string, timeout time.Duration) (domain.VolumeController, error) {
+	if len(argv) == 0 {
+		return nil, errors.New("exec controller: no command given")
+	}
+	if _, err := exec.LookPath(argv[0]); err != nil {
+		return nil, fmt.Errorf("exec controller: command %q not found: %w", argv[0], err)
+	}
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	return &ExecController{argv: argv, timeout: timeout}, nil
+}
+
+// String names the controller and its command in the apply plan.
+func (e *ExecController) String() string { return "exec: " + strings.Join(e.argv, " ") }
+
+// SetVolume runs "<command> set <volume>".
+func (e *ExecController) SetVolume(volume int) error {
+	if volume < 0 || volume > 100 {
+		return fmt.Errorf("volume must be between 0 and 100, got %d", volume)
+	}
+	_, stderr, err := e.run("set", strconv.Itoa(volume))
+	if err != nil {
+		return err
+	}
+	if warning := strings.TrimSpace(stderr); warning != "" {
+		return &domain.ApplyWarning{Message: warning}
+	}
+	return nil
+}
+
+// GetVolume runs "<command> get" and parses the volume it prints.
+func (e *ExecController) GetVolume() (int, error) {
+	stdout, _, err := e.run("get")
+	if err != nil {
+		return 0, err
+	}
+	out := strings.TrimSpace(stdout)
+	volume, err := strconv.Atoi(out)
+	if err != nil || volume < 0 || volume > 100 {
+		return 0, fmt.Errorf("exec controller: %s get printed %q, want a volume between 0 and 100", e.argv[0], out)
+	}
+	return volume, nil
+}
+
+// ListInputDevices is not part of the exec protocol.
+func (e *ExecController) ListInputDevices() ([]domain.AudioDevice, error) {
+	return nil, fmt.Errorf("%w: exec controller cannot list input devices", domain.ErrNotSupported)
+}
+
+func (e *ExecController) run(args ...string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	argv := append(append([]string(nil), e.argv[1:]...), args...)
+	cmd := exec.CommandContext(ctx, e.argv[0], argv...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	op := strings.Join(args, " ")
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", "", fmt.Errorf("exec controller: %s %s timed out after %s", e.argv[0], op, e.timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() == ExecNotSupportedExit {
+			return "", "", fmt.Errorf("%w: %s %s", domain.ErrNotSupported, e.argv[0], op)
+		}
+		return "", "", fmt.Errorf("exec controller: %s %s exited with %d: %s", e.argv[0], op, exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("exec controller: %s %s: %w", e.argv[0], op, err)
+	}
+	return stdout.String(), stderr.String(), nil
+}
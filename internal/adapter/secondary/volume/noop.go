@@ -1,17 +1,82 @@
 package volume
 
-import "micgain-manager/internal/domain"
+import (
+	"sync"
+
+	"micgain-manager/internal/domain"
+)
 
 // NoopController implements domain.VolumeController with no-op behavior.
 // Useful for testing or non-macOS environments.
-type NoopController struct{}
+type NoopController struct {
+	mu     sync.Mutex
+	volume int
+	output int
+}
 
 // NewNoopController creates a new no-op volume controller.
 func NewNoopController() domain.VolumeController {
-	return &NoopController{}
+	return &NoopController{volume: domain.DefaultConfig().TargetVolume, output: domain.DefaultConfig().TargetVolume}
 }
 
+// String names the controller in the apply plan.
+func (n *NoopController) String() string { return "noop" }
+
 // SetVolume does nothing and always succeeds.
+// The volume is remembered so that GetVolume can report it back.
 func (n *NoopController) SetVolume(volume int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.volume = volume
+	return nil
+}
+
+// GetVolume returns the last volume passed to SetVolume.
+func (n *NoopController) GetVolume() (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.volume, nil
+}
+
+// SetDeviceVolume ignores the device and behaves like SetVolume.
+func (n *NoopController) SetDeviceVolume(device string, volume int) error {
+	return n.SetVolume(volume)
+}
+
+// GetDeviceVolume ignores the device and behaves like GetVolume.
+func (n *NoopController) GetDeviceVolume(device string) (int, error) {
+	return n.GetVolume()
+}
+
+// SetKindVolume remembers the volume of either kind, like SetVolume.
+func (n *NoopController) SetKindVolume(kind domain.VolumeKind, volume int) error {
+	if kind == domain.VolumeInput {
+		return n.SetVolume(volume)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.output = volume
 	return nil
 }
+
+// GetKindVolume returns the last volume of the kind passed to SetKindVolume.
+func (n *NoopController) GetKindVolume(kind domain.VolumeKind) (int, error) {
+	if kind == domain.VolumeInput {
+		return n.GetVolume()
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.output, nil
+}
+
+// noopDevices is the fixed device list of the noop controller, so that
+// output stays the same on every machine.
+var noopDevices = []domain.AudioDevice{
+	{ID: 1, Name: "Built-in Microphone", Default: true, SampleRate: 48000},
+	{ID: 2, Name: "USB Audio Device", SampleRate: 44100},
+}
+
+// ListInputDevices returns a fixed fake device list.
+func (n *NoopController) ListInputDevices() ([]domain.AudioDevice, error) {
+	return append([]domain.AudioDevice(nil), noopDevices...), nil
+}
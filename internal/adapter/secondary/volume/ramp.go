@@ -0,0 +1,128 @@
+package volume
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// RampingController decorates a VolumeController so that volume sets step
+// from the current reading to the target over the configured ramp instead
+// of jumping there. Without a ramp, or when the current volume cannot be
+// read, it sets the target at once. It forwards device and output volume
+// sets, ramped the same way, when the wrapped controller supports them.
+type RampingController struct {
+	domain.VolumeController
+
+	mu   sync.Mutex
+	ramp domain.Ramp
+}
+
+// NewRampingController wraps controller. The ramp is off until SetRamp.
+func NewRampingController(controller domain.VolumeController) *RampingController {
+	return &RampingController{VolumeController: controller}
+}
+
+// String names the wrapped controller, so the apply plan shows what
+// actually sets the volume.
+func (r *RampingController) String() string {
+	if named, ok := r.VolumeController.(fmt.Stringer); ok {
+		return named.String()
+	}
+	return fmt.Sprintf("%T", r.VolumeController)
+}
+
+// SetRamp sets the ramp used by later volume sets.
+func (r *RampingController) SetRamp(ramp domain.Ramp) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ramp = ramp
+}
+
+// SetVolume ramps the input volume to volume.
+func (r *RampingController) SetVolume(volume int) error {
+	return r.rampTo(volume, r.VolumeController.GetVolume, r.VolumeController.SetVolume)
+}
+
+// SetDeviceVolume ramps the input volume of the named device.
+func (r *RampingController) SetDeviceVolume(device string, volume int) error {
+	devices, ok := r.VolumeController.(domain.DeviceVolumeController)
+	if !ok {
+		return fmt.Errorf("%w: %s cannot select input device %q", domain.ErrNotSupported, r, device)
+	}
+	get := func() (int, error) { return devices.GetDeviceVolume(device) }
+	set := func(v int) error { return devices.SetDeviceVolume(device, v) }
+	return r.rampTo(volume, get, set)
+}
+
+// GetDeviceVolume reads the input volume of the named device.
+func (r *RampingController) GetDeviceVolume(device string) (int, error) {
+	devices, ok := r.VolumeController.(domain.DeviceVolumeController)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s cannot select input device %q", domain.ErrNotSupported, r, device)
+	}
+	return devices.GetDeviceVolume(device)
+}
+
+// SetKindVolume ramps the volume of the given kind.
+func (r *RampingController) SetKindVolume(kind domain.VolumeKind, volume int) error {
+	kinds, ok := r.VolumeController.(domain.KindVolumeController)
+	if !ok {
+		return fmt.Errorf("%w: %s cannot set the %s volume", domain.ErrNotSupported, r, kind)
+	}
+	get := func() (int, error) { return kinds.GetKindVolume(kind) }
+	set := func(v int) error { return kinds.SetKindVolume(kind, v) }
+	return r.rampTo(volume, get, set)
+}
+
+// GetKindVolume reads the volume of the given kind.
+func (r *RampingController) GetKindVolume(kind domain.VolumeKind) (int, error) {
+	kinds, ok := r.VolumeController.(domain.KindVolumeController)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s cannot read the %s volume", domain.ErrNotSupported, r, kind)
+	}
+	return kinds.GetKindVolume(kind)
+}
+
+// rampTo sets target through the steps of the current ramp, starting from
+// what get reads. A step that fails ends the ramp with its error; warnings
+// from the steps are kept and the last one is returned once the target is
+// set.
+func (r *RampingController) rampTo(target int, get func() (int, error), set func(int) error) error {
+	r.mu.Lock()
+	ramp := r.ramp
+	r.mu.Unlock()
+	if ramp.Duration <= 0 {
+		return set(target)
+	}
+
+	current, err := get()
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotSupported) {
+			logging.Debugf("ramp: read volume, setting %d at once: %v", target, err)
+		}
+		current = -1
+	}
+
+	volumes := ramp.Volumes(current, target)
+	pause := ramp.Pause(len(volumes))
+	var warning error
+	for i, volume := range volumes {
+		if i > 0 {
+			time.Sleep(pause)
+		}
+		err := set(volume)
+		var applyWarning *domain.ApplyWarning
+		switch {
+		case errors.As(err, &applyWarning):
+			warning = err
+		case err != nil:
+			return err
+		}
+	}
+	return warning
+}
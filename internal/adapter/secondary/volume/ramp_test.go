@@ -0,0 +1,89 @@
+package volume
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// recordingController records every volume set, starting from volume. A
+// negative volume makes GetVolume unsupported.
+type recordingController struct {
+	volume int
+	sets   []int
+	// failAt fails the set of that volume
+	failAt int
+}
+
+func (c *recordingController) SetVolume(volume int) error {
+	if c.failAt != 0 && volume == c.failAt {
+		return errors.New("osascript failed")
+	}
+	c.sets = append(c.sets, volume)
+	c.volume = volume
+	return nil
+}
+
+func (c *recordingController) GetVolume() (int, error) {
+	if c.volume < 0 {
+		return 0, domain.ErrNotSupported
+	}
+	return c.volume, nil
+}
+
+func (c *recordingController) ListInputDevices() ([]domain.AudioDevice, error) {
+	return nil, domain.ErrNotSupported
+}
+
+func TestRampingController(t *testing.T) {
+	tests := []struct {
+		name    string
+		ramp    domain.Ramp
+		current int
+		target  int
+		failAt  int
+		want    []int
+		wantErr bool
+	}{
+		{"off", domain.Ramp{}, 20, 80, 0, []int{80}, false},
+		{"up", domain.Ramp{Duration: 30 * time.Millisecond, Steps: 4}, 20, 80, 0, []int{35, 50, 65, 80}, false},
+		{"down", domain.Ramp{Duration: 20 * time.Millisecond, Steps: 3}, 80, 20, 0, []int{60, 40, 20}, false},
+		{"fewer steps than requested", domain.Ramp{Duration: 10 * time.Millisecond, Steps: 10}, 50, 52, 0, []int{51, 52}, false},
+		{"already there", domain.Ramp{Duration: 10 * time.Millisecond, Steps: 4}, 80, 80, 0, []int{80}, false},
+		{"unreadable jumps", domain.Ramp{Duration: 10 * time.Millisecond, Steps: 4}, -1, 80, 0, []int{80}, false},
+		{"failed step ends it", domain.Ramp{Duration: 30 * time.Millisecond, Steps: 4}, 20, 80, 50, []int{35}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := &recordingController{volume: tt.current, failAt: tt.failAt}
+			r := NewRampingController(controller)
+			r.SetRamp(tt.ramp)
+
+			start := time.Now()
+			err := r.SetVolume(tt.target)
+			elapsed := time.Since(start)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetVolume = %v, want error %v", err, tt.wantErr)
+			}
+			if !slices.Equal(controller.sets, tt.want) {
+				t.Errorf("sets = %v, want %v", controller.sets, tt.want)
+			}
+			if len(tt.want) > 1 && !tt.wantErr && elapsed < tt.ramp.Duration {
+				t.Errorf("ramp took %s, want at least %s", elapsed, tt.ramp.Duration)
+			}
+		})
+	}
+}
+
+func TestRampingControllerUnsupported(t *testing.T) {
+	r := NewRampingController(&recordingController{volume: 20})
+	if err := r.SetDeviceVolume("USB", 50); !errors.Is(err, domain.ErrNotSupported) {
+		t.Errorf("SetDeviceVolume = %v, want ErrNotSupported", err)
+	}
+	if err := r.SetKindVolume(domain.VolumeOutput, 50); !errors.Is(err, domain.ErrNotSupported) {
+		t.Errorf("SetKindVolume = %v, want ErrNotSupported", err)
+	}
+}
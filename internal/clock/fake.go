@@ -0,0 +1,118 @@
+// Package clock provides a domain.Clock that only moves when told to, for
+// driving the scheduler step by step in tests.
+package clock
+
+import (
+	"sync"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+// Fake is a domain.Clock whose time moves only through Advance.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	// changed is signalled whenever a ticker starts or stops.
+	changed *sync.Cond
+}
+
+// NewFake returns a fake clock set to start.
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTicker returns a ticker that fires as Advance passes its period.
+// Like time.NewTicker it panics on a non-positive period.
+func (f *Fake) NewTicker(d time.Duration) domain.Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clock: f, c: make(chan time.Time, 1), period: d, next: f.now.Add(d)}
+	f.tickers = append(f.tickers, t)
+	f.changed.Broadcast()
+	return t
+}
+
+// BlockUntil waits until n tickers are running, so that a test can tell
+// the code under test is waiting on the clock before advancing it.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.running() < n {
+		f.changed.Wait()
+	}
+}
+
+func (f *Fake) running() int {
+	n := 0
+	for _, t := range f.tickers {
+		if !t.stopped {
+			n++
+		}
+	}
+	return n
+}
+
+// Advance moves the clock forward by d and fires every ticker whose next
+// tick it passes. As with time.Ticker, a tick the receiver has not taken
+// yet is not followed by another, so a long jump yields one tick.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for _, t := range f.tickers {
+		if t.stopped {
+			continue
+		}
+		for !t.next.After(f.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock   *Fake
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.period = d
+	t.next = t.clock.now.Add(d)
+	t.stopped = false
+	t.clock.changed.Broadcast()
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+	t.clock.changed.Broadcast()
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AppVolume is a rule enforcing the input level of one application, for
+// apps that keep their own input gain apart from the system level.
+type AppVolume struct {
+	App    string
+	Volume int
+}
+
+// ParseAppVolumes parses a comma separated list of "App=volume" rules.
+func ParseAppVolumes(s string) ([]AppVolume, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var rules []AppVolume
+	for _, part := range strings.Split(s, ",") {
+		app, vol, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid app volume %q: expected App=volume", part)
+		}
+		volume, err := strconv.Atoi(strings.TrimSpace(vol))
+		if err != nil {
+			return nil, fmt.Errorf("invalid app volume %q", vol)
+		}
+		rules = append(rules, AppVolume{App: strings.TrimSpace(app), Volume: volume})
+	}
+	return rules, nil
+}
+
+// FormatAppVolumes renders rules in the format accepted by ParseAppVolumes.
+func FormatAppVolumes(rules []AppVolume) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		parts = append(parts, fmt.Sprintf("%s=%d", r.App, r.Volume))
+	}
+	return strings.Join(parts, ",")
+}
+
+func validateAppVolumes(rules []AppVolume) error {
+	seen := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		if r.App == "" {
+			return fmt.Errorf("app volume needs an application name")
+		}
+		if seen[r.App] {
+			return fmt.Errorf("duplicate app volume for %q", r.App)
+		}
+		seen[r.App] = true
+		if err := ValidateVolume(r.Volume); err != nil {
+			return fmt.Errorf("app %q: %w", r.App, err)
+		}
+	}
+	return nil
+}
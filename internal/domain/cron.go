@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// parseSchedule parses a cron expression: five fields (minute, hour, day of
+// month, month, day of week) as in "0 9-18 * * mon-fri", or a descriptor
+// such as "@hourly" or "@every 30m".
+func parseSchedule(spec string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidSchedule, spec, err)
+	}
+	return schedule, nil
+}
+
+func validateSchedule(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	schedule, err := parseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	// Any start will do: an expression that matches at all matches within
+	// the five years the parser searches
+	if schedule.Next(time.Unix(0, 0).UTC()).IsZero() {
+		return fmt.Errorf("%w %q: never matches", ErrInvalidSchedule, spec)
+	}
+	return nil
+}
+
+// NextScheduled returns the first time after t matching Schedule, read on
+// the wall clock in config's zone, or the zero time when no schedule is
+// set.
+func (c Config) NextScheduled(t time.Time) time.Time {
+	if c.Schedule == "" {
+		return time.Time{}
+	}
+	schedule, err := parseSchedule(c.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	return schedule.Next(t.In(c.Location()))
+}
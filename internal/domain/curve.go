@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minutesPerDay is the length of the curve's time axis.
+const minutesPerDay = 24 * 60
+
+// CurveMaxInterval caps the scheduler interval while a curve is configured
+// so that the enforced target tracks the curve closely enough.
+const CurveMaxInterval = time.Minute
+
+// CurvePoint is a control point of a piecewise-linear volume curve.
+// Minute is the minute of the day (0-1439) in local time.
+type CurvePoint struct {
+	Minute int
+	Volume int
+}
+
+// TargetPoint is a resolved target volume at a point in time.
+type TargetPoint struct {
+	At     time.Time
+	Volume int
+}
+
+// ParseClock converts an "HH:MM" string into a minute of the day.
+func ParseClock(s string) (int, error) {
+	hh, mm, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q: expected HH:MM", s)
+	}
+	h, err := strconv.Atoi(hh)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid time %q: hour must be 00-23", s)
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time %q: minute must be 00-59", s)
+	}
+	return h*60 + m, nil
+}
+
+// FormatClock converts a minute of the day into an "HH:MM" string.
+func FormatClock(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// ParseCurve parses a comma separated list of "HH:MM=volume" control points.
+func ParseCurve(s string) ([]CurvePoint, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var points []CurvePoint
+	for _, part := range strings.Split(s, ",") {
+		clock, vol, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid curve point %q: expected HH:MM=volume", part)
+		}
+		minute, err := ParseClock(clock)
+		if err != nil {
+			return nil, err
+		}
+		volume, err := strconv.Atoi(vol)
+		if err != nil {
+			return nil, fmt.Errorf("invalid curve volume %q", vol)
+		}
+		points = append(points, CurvePoint{Minute: minute, Volume: volume})
+	}
+	return points, nil
+}
+
+// FormatCurve renders control points in the format accepted by ParseCurve.
+func FormatCurve(points []CurvePoint) string {
+	parts := make([]string, 0, len(points))
+	for _, p := range points {
+		parts = append(parts, fmt.Sprintf("%s=%d", FormatClock(p.Minute), p.Volume))
+	}
+	return strings.Join(parts, ",")
+}
+
+// validateCurve checks that every control point is in range and unique.
+func validateCurve(points []CurvePoint) error {
+	seen := make(map[int]bool, len(points))
+	for _, p := range points {
+		if p.Minute < 0 || p.Minute >= minutesPerDay {
+			return fmt.Errorf("curve time must be within a day, got minute %d", p.Minute)
+		}
+		if err := ValidateVolume(p.Volume); err != nil {
+			return fmt.Errorf("curve point %s: %w", FormatClock(p.Minute), err)
+		}
+		if seen[p.Minute] {
+			return fmt.Errorf("curve has duplicate point at %s", FormatClock(p.Minute))
+		}
+		seen[p.Minute] = true
+	}
+	return nil
+}
+
+// sortedCurve returns a copy of the points ordered by time of day.
+func sortedCurve(points []CurvePoint) []CurvePoint {
+	sorted := append([]CurvePoint(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Minute < sorted[j].Minute })
+	return sorted
+}
+
+// InterpolateCurve returns the curve volume at the given time. The curve
+// wraps around midnight, so the segment between the last and the first
+// point spans the day boundary.
+func InterpolateCurve(points []CurvePoint, at time.Time) int {
+	if len(points) == 0 {
+		return 0
+	}
+	sorted := sortedCurve(points)
+	if len(sorted) == 1 {
+		return sorted[0].Volume
+	}
+
+	// Fractional minute of the day keeps the curve smooth between ticks
+	minute := float64(at.Hour()*60+at.Minute()) + float64(at.Second())/60
+
+	prev := sorted[len(sorted)-1]
+	prevMinute := float64(prev.Minute - minutesPerDay)
+	for _, next := range sorted {
+		nextMinute := float64(next.Minute)
+		if minute < nextMinute {
+			return lerp(prev.Volume, next.Volume, (minute-prevMinute)/(nextMinute-prevMinute))
+		}
+		prev, prevMinute = next, nextMinute
+	}
+
+	// Past the last point: interpolate towards the first point of the next day
+	first := sorted[0]
+	return lerp(prev.Volume, first.Volume, (minute-prevMinute)/(float64(first.Minute+minutesPerDay)-prevMinute))
+}
+
+func lerp(from, to int, t float64) int {
+	v := float64(from) + (float64(to)-float64(from))*t
+	return int(v + 0.5)
+}
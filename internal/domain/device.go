@@ -0,0 +1,67 @@
+package domain
+
+import "fmt"
+
+// AudioDevice is an input device reported by a VolumeController.
+type AudioDevice struct {
+	// ID numbers the device in the order the system lists it. It is for
+	// display only; Config.DeviceName selects devices by Name.
+	ID   int
+	Name string
+	// Default reports whether the device is the system default input.
+	Default bool
+	// SampleRate is the device's current sample rate in Hz, zero when the
+	// controller does not report it.
+	SampleRate int
+}
+
+// HasDevice reports whether devices include one named name.
+func HasDevice(devices []AudioDevice, name string) bool {
+	for _, d := range devices {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceAbsentPolicy decides what an apply does when the controller lists
+// the input devices and Config.DeviceName is not among them.
+type DeviceAbsentPolicy int
+
+const (
+	// DeviceAbsentError sets the named device anyway, failing the apply.
+	DeviceAbsentError DeviceAbsentPolicy = iota
+	// DeviceAbsentSkip waits for the device to return: the apply sets
+	// nothing and is reported as StatusWaitingDevice rather than a failure,
+	// so no failure backoff builds up meanwhile.
+	DeviceAbsentSkip
+	// DeviceAbsentFallback sets the system default input instead.
+	DeviceAbsentFallback
+)
+
+func (p DeviceAbsentPolicy) String() string {
+	switch p {
+	case DeviceAbsentSkip:
+		return "skip"
+	case DeviceAbsentFallback:
+		return "fallback-default"
+	default:
+		return "error"
+	}
+}
+
+// ParseDeviceAbsentPolicy converts a policy label back into a
+// DeviceAbsentPolicy. An empty label is the default error policy.
+func ParseDeviceAbsentPolicy(s string) (DeviceAbsentPolicy, error) {
+	switch s {
+	case "", "error":
+		return DeviceAbsentError, nil
+	case "skip":
+		return DeviceAbsentSkip, nil
+	case "fallback-default":
+		return DeviceAbsentFallback, nil
+	default:
+		return DeviceAbsentError, fmt.Errorf("unknown device absent policy %q (error, skip or fallback-default)", s)
+	}
+}
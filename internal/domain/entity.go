@@ -1,6 +1,11 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
 
 // Config represents the configuration entity in the domain.
 // This is a pure domain model with no dependencies on external concerns.
@@ -8,6 +13,141 @@ type Config struct {
 	TargetVolume int
 	Interval     time.Duration
 	Enabled      bool
+	// ScheduleMode decides whether runs follow the last apply or fixed
+	// wall-clock boundaries.
+	ScheduleMode ScheduleMode
+	// Schedule is an optional cron expression (e.g. "0 9-18 * * mon-fri")
+	// that, when set, decides the runs instead of Interval and
+	// ScheduleMode. Interval still paces the loop between runs.
+	Schedule string
+	// Timezone is the IANA zone (e.g. "Asia/Tokyo") that curve times, quiet
+	// hours, fixed schedule boundaries and Schedule are read in. Empty means the
+	// system zone.
+	Timezone string
+	// AdaptiveInterval lengthens the interval up to MaxInterval while the
+	// read-back volume keeps matching the target.
+	AdaptiveInterval bool
+	MaxInterval      time.Duration
+	// RescheduleOnManualApply counts the next run from a manual apply, as
+	// from any other, so a tick due right after it does not apply again.
+	// When unset the schedule keeps the next run it had, as if the manual
+	// apply had not happened. DefaultConfig sets it.
+	RescheduleOnManualApply bool
+	// MinTargetVolume is a floor applied to every volume at apply time,
+	// whatever resolved it. With AllowedVolumes the floor is the smallest
+	// allowed volume at or above it (Floor). Zero disables the floor.
+	MinTargetVolume int
+	// MaxRetries is how many times a failed volume set is retried within
+	// one apply before the apply counts as failed. Zero disables retries.
+	// The first retry waits RetryBackoff (DefaultRetryBackoff when zero)
+	// and each further one twice as long as the last, capped at
+	// MaxRetryBackoff.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// RampDuration, when set, moves the volume from its current reading to
+	// the target in up to RampSteps sets (zero for DefaultRampSteps) spread
+	// over that time, instead of jumping there.
+	RampDuration time.Duration
+	RampSteps    int
+	// ErrorThreshold is how many consecutive failures it takes before the
+	// reported status turns from degraded to error. Zero or one reports
+	// every failure as an error.
+	ErrorThreshold int
+	// DriftAlertThreshold flags scheduled corrections of a volume that had
+	// drifted further than this from the target. Zero disables the alert.
+	DriftAlertThreshold int
+	// RedactErrors keeps the detail of apply failures, which may include
+	// raw osascript output, off disk and out of snapshots.
+	RedactErrors bool
+	// DeviceName targets the named input device instead of the system
+	// default input. Empty means the default input.
+	DeviceName string
+	// OnDeviceAbsent decides what applies do while DeviceName is missing
+	// from the controller's device list.
+	OnDeviceAbsent DeviceAbsentPolicy
+	// FormatVolumes replace TargetVolume while the input device runs at
+	// one of their sample rates, as last read by a scheduled apply.
+	FormatVolumes []FormatVolume
+	// AllowedVolumes restricts every target to a fixed set when non-empty.
+	AllowedVolumes []int
+	// AppVolumes are per-application input levels enforced on each tick
+	// alongside the system level.
+	AppVolumes []AppVolume
+	// Output locks the speaker volume on each tick, independently of the
+	// input lock.
+	Output OutputLock
+	// StaggerBetweenDevices spaces the sets of one apply (the input, each
+	// app and the output) this far apart, so they do not hit the audio
+	// subsystem at once. Zero sets them back to back.
+	StaggerBetweenDevices time.Duration
+	// Noise makes the target follow a NoiseSensor reading when enabled.
+	Noise NoiseControl
+	// ParkVolume, when set, is the volume left behind on disabling the
+	// scheduler, so whoever takes over starts from a neutral level.
+	// FadeOnPark steps there over ParkFadeDuration instead of jumping.
+	ParkVolume *int
+	FadeOnPark bool
+	// ReapplyOnPowerChange reapplies the target whenever the power source
+	// (AC or battery) changes, read every PowerPollInterval (zero for
+	// DefaultPowerPollInterval).
+	ReapplyOnPowerChange bool
+	PowerPollInterval    time.Duration
+	// PreApplyCmd and PostApplyCmd are shell commands run around every
+	// apply, each bounded by ApplyCmdTimeout (zero for the default). A
+	// failing pre-apply command aborts the apply when AbortOnPreApplyFailure
+	// is set and only warns otherwise. They can be set in config files only,
+	// never through the web API.
+	PreApplyCmd            string
+	PostApplyCmd           string
+	AbortOnPreApplyFailure bool
+	ApplyCmdTimeout        time.Duration
+	// Curve optionally replaces TargetVolume with a time-of-day curve.
+	Curve []CurvePoint
+	// QuietHours are daily windows in which no scheduled apply runs, not
+	// even to enforce a hold. Manual applies still go through.
+	QuietHours []TimeWindow
+	// Profiles are named settings sets; ActiveProfile is the one last used.
+	Profiles      []Profile
+	ActiveProfile string
+	// DryRun simulates applies for trying out a config: volumes go to a
+	// controller that does not touch the OS, and app volumes and apply
+	// commands are skipped.
+	DryRun bool
+	// Locked forbids every change for kiosk deployments. It can only be set
+	// by the system config layer or a command-line flag, never saved.
+	Locked bool
+}
+
+// ScheduleMode selects how the next run is derived from the interval.
+type ScheduleMode int
+
+const (
+	// ScheduleRelative runs one interval after the last apply.
+	ScheduleRelative ScheduleMode = iota
+	// ScheduleFixed runs on interval boundaries counted from local midnight.
+	ScheduleFixed
+)
+
+func (m ScheduleMode) String() string {
+	switch m {
+	case ScheduleFixed:
+		return "fixed"
+	default:
+		return "relative"
+	}
+}
+
+// ParseScheduleMode converts a mode label back into a ScheduleMode.
+// An empty label is the default relative mode.
+func ParseScheduleMode(s string) (ScheduleMode, error) {
+	switch s {
+	case "", "relative":
+		return ScheduleRelative, nil
+	case "fixed":
+		return ScheduleFixed, nil
+	default:
+		return ScheduleRelative, fmt.Errorf("unknown schedule mode %q (relative or fixed)", s)
+	}
 }
 
 // ScheduleState represents the current state of the scheduler.
@@ -15,8 +155,49 @@ type ScheduleState struct {
 	LastApplied     time.Time
 	LastApplyStatus ApplyStatus
 	LastError       error
+	LastWarning     string
 	NextRun         time.Time
 	IsRunning       bool
+	// FirstApplied is the first successful apply since the scheduler loop
+	// last started, for uptime reporting.
+	FirstApplied time.Time
+	// ConsecutiveFailures counts failed applies since the last success and
+	// drives the failure backoff.
+	ConsecutiveFailures int
+	Hold                Hold
+	// StableCount and AdaptedInterval track the adaptive interval.
+	StableCount     int
+	AdaptedInterval time.Duration
+	Ambient         Ambient
+	// LastWakeApply is when the scheduler last reapplied because the
+	// system woke from sleep.
+	LastWakeApply time.Time
+	// LastApplyDryRun marks the last apply as simulated by a dry run.
+	LastApplyDryRun bool
+	// LastObservedVolume is the volume read back before the last scheduled
+	// apply, or nil when it has not been read.
+	LastObservedVolume *int
+	// SampleRate is the input device's sample rate in Hz read by the last
+	// scheduled apply, for Config.FormatVolumes; zero when unknown.
+	SampleRate int
+	// RecentResults are the outcomes of the last SuccessRateWindow
+	// applies, oldest first, seeded from the history at startup.
+	RecentResults []bool
+	// StartupCheck is the volume read back when the running loop started,
+	// or nil when no process runs the loop.
+	StartupCheck *StartupCheck
+	// Running is the config the live scheduler loop is using, or nil when
+	// no process runs the loop. Changes saved by another process do not
+	// reach a running loop until it is restarted.
+	Running *Config
+}
+
+// Hold represents a user-requested lock on a specific volume.
+// While active, the held volume overrides the configured target.
+type Hold struct {
+	Active bool
+	Volume int
+	Since  time.Time
 }
 
 // ApplyStatus represents the status of a volume application attempt.
@@ -26,6 +207,12 @@ const (
 	StatusNever ApplyStatus = iota
 	StatusSuccess
 	StatusError
+	// StatusDegraded is only reported, never recorded: the last apply
+	// failed but fewer times in a row than Config.ErrorThreshold.
+	StatusDegraded
+	// StatusWaitingDevice marks an apply skipped because the configured
+	// device is absent, under DeviceAbsentSkip.
+	StatusWaitingDevice
 )
 
 func (s ApplyStatus) String() string {
@@ -36,6 +223,10 @@ func (s ApplyStatus) String() string {
 		return "ok"
 	case StatusError:
 		return "error"
+	case StatusDegraded:
+		return "degraded"
+	case StatusWaitingDevice:
+		return "waiting for device"
 	default:
 		return "unknown"
 	}
@@ -45,16 +236,312 @@ func (s ApplyStatus) String() string {
 type Snapshot struct {
 	Config        Config
 	ScheduleState ScheduleState
+	// DryRun reports that applies are simulated, by Config.DryRun or for
+	// the whole process.
+	DryRun bool
+	// DroppedEvents counts the snapshots subscribers missed because a newer
+	// one replaced them before they read it, since the process started.
+	DroppedEvents uint64
+}
+
+// ApplyRecord represents a single volume application attempt in the history.
+type ApplyRecord struct {
+	Timestamp time.Time
+	Volume    int
+	Status    ApplyStatus
+	Error     string
+	Warning   string
+	Trigger   ApplyTrigger
+	// Observed is the volume read back before a scheduled apply, nil when
+	// it was not read. SignificantDrift marks an apply that corrected a
+	// drift beyond Config.DriftAlertThreshold from it.
+	Observed         *int
+	SignificantDrift bool
+	// Duration is how long the apply took; zero in records written before
+	// durations were kept.
+	Duration time.Duration
+	// DryRun marks an apply that was only simulated.
+	DryRun bool
+}
+
+// ApplyTrigger records why an apply happened.
+type ApplyTrigger int
+
+const (
+	// TriggerUnknown is used for records written before triggers existed.
+	TriggerUnknown ApplyTrigger = iota
+	TriggerScheduled
+	TriggerManual
+	TriggerConfig
+	TriggerLock
+	TriggerUnlock
+	TriggerPower
+	TriggerWake
+)
+
+func (t ApplyTrigger) String() string {
+	switch t {
+	case TriggerScheduled:
+		return "scheduled"
+	case TriggerManual:
+		return "manual"
+	case TriggerConfig:
+		return "config"
+	case TriggerLock:
+		return "lock"
+	case TriggerUnlock:
+		return "unlock"
+	case TriggerPower:
+		return "power"
+	case TriggerWake:
+		return "wake"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseApplyTrigger converts a trigger label back into an ApplyTrigger.
+func ParseApplyTrigger(s string) (ApplyTrigger, error) {
+	for t := TriggerUnknown; t <= TriggerWake; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return TriggerUnknown, fmt.Errorf("unknown trigger %q", s)
+}
+
+// EffectRecord describes a side effect executed by the application,
+// such as a volume change or a config write.
+type EffectRecord struct {
+	Timestamp time.Time
+	Type      string
+	Params    map[string]any
+	Duration  time.Duration
+	Err       error
+}
+
+// HistoryQuery describes a bounded, filtered read of the apply history.
+// Records are returned newest first.
+type HistoryQuery struct {
+	Since   time.Time
+	Status  *ApplyStatus
+	Trigger *ApplyTrigger
+	Limit   int
+	Offset  int
+}
+
+// Matches reports whether a record satisfies the query filters.
+func (q HistoryQuery) Matches(record ApplyRecord) bool {
+	if !q.Since.IsZero() && record.Timestamp.Before(q.Since) {
+		return false
+	}
+	if q.Status != nil && record.Status != *q.Status {
+		return false
+	}
+	if q.Trigger != nil && record.Trigger != *q.Trigger {
+		return false
+	}
+	return true
 }
 
-// Validate checks if the configuration values are valid.
+// ParseApplyStatus converts a status label back into an ApplyStatus.
+func ParseApplyStatus(s string) (ApplyStatus, error) {
+	switch s {
+	case "never":
+		return StatusNever, nil
+	case "ok":
+		return StatusSuccess, nil
+	case "error":
+		return StatusError, nil
+	case "waiting for device":
+		return StatusWaitingDevice, nil
+	default:
+		return StatusNever, fmt.Errorf("unknown status %q", s)
+	}
+}
+
+// IntervalFromSeconds converts a possibly fractional number of seconds from
+// a config file or API request, rejecting values below MinInterval. Zero
+// does not mean "no scheduling": that is what Enabled is for.
+func IntervalFromSeconds(field string, seconds float64) (time.Duration, error) {
+	if seconds == 0 {
+		return 0, fmt.Errorf("%s must be at least %g, got 0 (set enabled to false to stop scheduling)", field, MinInterval.Seconds())
+	}
+	if seconds*float64(time.Second) < float64(MinInterval) {
+		return 0, fmt.Errorf("%s must be at least %g, got %g", field, MinInterval.Seconds(), seconds)
+	}
+	if seconds*float64(time.Second) > math.MaxInt64 {
+		return 0, fmt.Errorf("%s is too large: %g", field, seconds)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// Validate checks if the configuration values are valid. Its errors wrap
+// ErrInvalidConfig.
 func (c Config) Validate() error {
-	if c.TargetVolume < 0 || c.TargetVolume > 100 {
-		return ErrInvalidVolume
+	if err := c.validate(); err != nil {
+		return &configError{err}
 	}
-	if c.Interval < time.Second {
+	return nil
+}
+
+func (c Config) validate() error {
+	if err := ValidateVolume(c.TargetVolume); err != nil {
+		return err
+	}
+	if err := ValidateVolume(c.MinTargetVolume); err != nil {
+		return err
+	}
+	if c.ErrorThreshold < 0 {
+		return fmt.Errorf("error threshold must not be negative")
+	}
+	if c.DriftAlertThreshold < 0 || c.DriftAlertThreshold > 100 {
+		return fmt.Errorf("drift alert threshold must be between 0 and 100")
+	}
+	for _, v := range c.AllowedVolumes {
+		if err := ValidateVolume(v); err != nil {
+			return err
+		}
+	}
+	if err := c.CheckAllowed(c.TargetVolume); err != nil {
+		return err
+	}
+	if c.Floor() > 100 {
+		return fmt.Errorf("%w: no allowed volume is at or above minTargetVolume %d (allowed: %v)",
+			ErrVolumeNotAllowed, c.MinTargetVolume, c.AllowedVolumes)
+	}
+	if len(c.AllowedVolumes) > 0 && len(c.Curve) > 0 {
+		return ErrCurveWithAllowlist
+	}
+	if c.Interval < MinInterval {
 		return ErrInvalidInterval
 	}
+	if c.AdaptiveInterval && c.MaxInterval < c.Interval {
+		return ErrInvalidMaxInterval
+	}
+	if c.ApplyCmdTimeout < 0 {
+		return fmt.Errorf("apply command timeout must not be negative")
+	}
+	if err := validateCurve(c.Curve); err != nil {
+		return err
+	}
+	if err := validateQuietHours(c.QuietHours); err != nil {
+		return err
+	}
+	if err := validateAppVolumes(c.AppVolumes); err != nil {
+		return err
+	}
+	if err := c.validateFormatVolumes(); err != nil {
+		return err
+	}
+	if err := validateOutput(c.Output); err != nil {
+		return err
+	}
+	if err := validateNoise(c.Noise); err != nil {
+		return err
+	}
+	if err := validatePowerPoll(c.PowerPollInterval); err != nil {
+		return err
+	}
+	if err := validateRetry(c.MaxRetries, c.RetryBackoff); err != nil {
+		return err
+	}
+	if err := validateRamp(c.RampDuration, c.RampSteps); err != nil {
+		return err
+	}
+	if c.StaggerBetweenDevices < 0 || c.StaggerBetweenDevices > MaxStagger {
+		return fmt.Errorf("stagger between devices must be between 0 and %s", MaxStagger)
+	}
+	if c.ParkVolume != nil {
+		if err := ValidateVolume(*c.ParkVolume); err != nil {
+			return fmt.Errorf("park volume: %w", err)
+		}
+	}
+	if err := validateSchedule(c.Schedule); err != nil {
+		return err
+	}
+	if _, err := LoadTimezone(c.Timezone); err != nil {
+		return err
+	}
+	if err := validateProfiles(c.Profiles, c.ActiveProfile); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Location returns the zone named by Timezone. A name that no longer loads,
+// which Validate rejects, falls back to the system zone.
+func (c Config) Location() *time.Location {
+	loc, err := LoadTimezone(c.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// LoadTimezone loads an IANA zone name, where empty means the system zone.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: use an IANA name such as \"Asia/Tokyo\", or empty for the system zone", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// CheckAllowed returns ErrVolumeNotAllowed when an allowlist is configured
+// and volume is not on it.
+func (c Config) CheckAllowed(volume int) error {
+	if len(c.AllowedVolumes) == 0 || slices.Contains(c.AllowedVolumes, volume) {
+		return nil
+	}
+	return fmt.Errorf("%w: %d (allowed: %v)", ErrVolumeNotAllowed, volume, c.AllowedVolumes)
+}
+
+// Floor returns the lowest volume an apply may set: MinTargetVolume, or
+// with an allowlist the smallest allowed volume at or above it, so that
+// raising a volume to the floor never leaves the allowlist. It is above
+// 100 when no allowed volume reaches MinTargetVolume, which Validate
+// rejects.
+func (c Config) Floor() int {
+	if len(c.AllowedVolumes) == 0 {
+		return c.MinTargetVolume
+	}
+	floor := 101
+	for _, v := range c.AllowedVolumes {
+		if v >= c.MinTargetVolume {
+			floor = min(floor, v)
+		}
+	}
+	return floor
+}
+
+// NearestAllowed returns the allowed volume closest to volume, or volume
+// itself when no allowlist is configured.
+func (c Config) NearestAllowed(volume int) int {
+	nearest := volume
+	for i, v := range c.AllowedVolumes {
+		if i == 0 || abs(v-volume) < abs(nearest-volume) {
+			nearest = v
+		}
+	}
+	return nearest
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// ValidateVolume checks that a volume level is within the supported range.
+func ValidateVolume(volume int) error {
+	if volume < 0 || volume > 100 {
+		return ErrInvalidVolume
+	}
 	return nil
 }
 
@@ -64,5 +551,9 @@ func DefaultConfig() Config {
 		TargetVolume: 50,
 		Interval:     90 * time.Second,
 		Enabled:      true,
+		MaxInterval:  15 * time.Minute,
+		Noise:        DefaultNoiseControl(),
+
+		RescheduleOnManualApply: true,
 	}
 }
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntervalFromSeconds(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    time.Duration
+		// wantErr is part of the error, empty for none
+		wantErr string
+	}{
+		{0, 0, "set enabled to false to stop scheduling"},
+		{0.5, 0, "must be at least 1, got 0.5"},
+		{1.5, 1500 * time.Millisecond, ""},
+		{90, 90 * time.Second, ""},
+	}
+	for _, tt := range tests {
+		got, err := IntervalFromSeconds("intervalSeconds", tt.seconds)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("IntervalFromSeconds(%g) = %s, %v, want error %q", tt.seconds, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("IntervalFromSeconds(%g) = %s, %v, want %s", tt.seconds, got, err, tt.want)
+		}
+	}
+}
+
+func TestFloorWithAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []int
+		min     int
+		volume  int
+		want    int
+		wantErr bool
+	}{
+		{"no allowlist", nil, 50, 20, 50, false},
+		{"snaps up to allowed", []int{20, 80}, 50, 20, 80, false},
+		{"floor is allowed", []int{20, 50, 80}, 50, 20, 50, false},
+		{"above the floor", []int{20, 80}, 50, 80, 80, false},
+		{"no floor", []int{20, 80}, 0, 20, 20, false},
+		{"floor above every allowed volume", []int{20, 40}, 50, 20, 0, true},
+	}
+	service := NewSchedulerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.AllowedVolumes = tt.allowed
+			config.MinTargetVolume = tt.min
+			config.TargetVolume = tt.volume
+			err := config.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrVolumeNotAllowed) {
+					t.Fatalf("Validate = %v, want ErrVolumeNotAllowed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			got, _ := service.ApplyFloor(config, tt.volume)
+			if got != tt.want {
+				t.Errorf("ApplyFloor(%d) = %d, want %d", tt.volume, got, tt.want)
+			}
+			if err := config.CheckAllowed(got); err != nil {
+				t.Errorf("floored volume: %v", err)
+			}
+		})
+	}
+}
+
+func TestNoiseVolumeFlooredStaysAllowed(t *testing.T) {
+	config := DefaultConfig()
+	config.AllowedVolumes = []int{20, 80}
+	config.MinTargetVolume = 50
+	config.TargetVolume = 80
+	service := NewSchedulerService()
+	// NudgeForNoise snaps to the nearest allowed volume, and the floor is
+	// applied after it
+	for v := 0; v <= 100; v++ {
+		got, _ := service.ApplyFloor(config, config.NearestAllowed(v))
+		if err := config.CheckAllowed(got); err != nil || got < config.MinTargetVolume {
+			t.Fatalf("noise volume %d applied as %d: %v", v, got, err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrInvalidVolume indicates that the volume value is out of range.
@@ -9,6 +12,96 @@ var (
 	// ErrInvalidInterval indicates that the interval is too short.
 	ErrInvalidInterval = errors.New("interval must be at least 1 second")
 
+	// ErrInvalidMaxInterval indicates that the adaptive cap is below the base interval.
+	ErrInvalidMaxInterval = errors.New("max interval must not be shorter than interval")
+
 	// ErrNotEnabled indicates that the scheduler is not enabled.
 	ErrNotEnabled = errors.New("scheduler is not enabled")
+
+	// ErrVolumeHeld indicates that a different volume was requested while a hold is active.
+	ErrVolumeHeld = errors.New("volume is held; unlock before applying a different volume")
+
+	// ErrProfileNotFound indicates that no profile has the requested name.
+	ErrProfileNotFound = errors.New("profile not found")
+
+	// ErrDeviceAbsent indicates that the configured input device is not
+	// connected and Config.OnDeviceAbsent says to wait for it.
+	ErrDeviceAbsent = errors.New("input device is not connected")
+
+	// ErrNotSupported indicates that a controller does not support an operation.
+	ErrNotSupported = errors.New("operation not supported by controller")
+
+	// ErrVolumeNotAllowed indicates a volume outside the configured allowlist.
+	ErrVolumeNotAllowed = errors.New("volume is not in the allowed volumes")
+
+	// ErrCurveWithAllowlist indicates a curve was combined with an allowlist,
+	// whose interpolated values could not honour it.
+	ErrCurveWithAllowlist = errors.New("curve cannot be combined with allowed volumes")
+
+	// ErrInvalidRetry indicates retry settings out of range.
+	ErrInvalidRetry = errors.New("invalid retry settings")
+
+	// ErrInvalidRamp indicates ramp settings out of range.
+	ErrInvalidRamp = errors.New("invalid ramp settings")
+
+	// ErrInvalidSchedule indicates a cron expression that does not parse or
+	// never matches.
+	ErrInvalidSchedule = errors.New("invalid schedule")
+
+	// ErrInvalidQuietHours indicates malformed or overlapping quiet hours.
+	ErrInvalidQuietHours = errors.New("invalid quiet hours")
+
+	// ErrInvalidTimezone indicates a timezone that is not a known IANA name.
+	ErrInvalidTimezone = errors.New("invalid timezone")
+
+	// ErrConfigLocked indicates a change was attempted while the config is
+	// locked by an administrator.
+	ErrConfigLocked = errors.New("config is locked")
+
+	// ErrConfigBusy indicates the config file is being written by another
+	// process.
+	ErrConfigBusy = errors.New("config file is in use")
+
+	// ErrNotHeld indicates that an unlock was requested without an active hold.
+	ErrNotHeld = errors.New("volume is not held")
+
+	// ErrApplyFailed replaces the detail of a failed apply when
+	// Config.RedactErrors is set.
+	ErrApplyFailed = errors.New("apply failed")
+
+	// ErrInvalidConfig is wrapped by every error Config.Validate returns,
+	// alongside the more specific error if there is one.
+	ErrInvalidConfig = errors.New("invalid config")
 )
+
+// configError marks a validation failure as ErrInvalidConfig, keeping its
+// message.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string   { return e.err.Error() }
+func (e *configError) Unwrap() []error { return []error{ErrInvalidConfig, e.err} }
+
+// ApplyWarning is returned by a VolumeController when the volume was applied
+// but the backend reported a non-fatal problem, e.g. osascript printed to
+// stderr while exiting successfully. Callers should treat it as a success.
+type ApplyWarning struct {
+	Message string
+}
+
+func (w *ApplyWarning) Error() string {
+	return "warning: " + w.Message
+}
+
+// QuantizationError is returned in strict volume mode when the device
+// settled on a different volume than requested, usually because the
+// hardware only supports coarser steps.
+type QuantizationError struct {
+	Requested int
+	Achieved  int
+}
+
+func (e *QuantizationError) Error() string {
+	return fmt.Sprintf("requested volume %d but the device settled at %d (hardware step granularity)", e.Requested, e.Achieved)
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DecisionFactor is one input to the scheduling decision, with its value
+// and whether it blocks an apply on its own.
+type DecisionFactor struct {
+	Name  string
+	Value string
+	Gated bool
+}
+
+// ApplyDecision is the outcome of ShouldApply together with every factor
+// that went into it, in the order they are checked.
+type ApplyDecision struct {
+	Apply   bool
+	Factors []DecisionFactor
+}
+
+// Blocking returns the names of the factors that prevent an apply.
+func (d ApplyDecision) Blocking() []string {
+	var names []string
+	for _, f := range d.Factors {
+		if f.Gated {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// ExplainApply traces the ShouldApply decision at now. Factors that only
+// shape the apply (output, target, interval, drift alert) are listed but
+// never gate.
+func (s *SchedulerService) ExplainApply(state ScheduleState, config Config, now time.Time) ApplyDecision {
+	var factors []DecisionFactor
+	add := func(name, value string, gated bool) {
+		factors = append(factors, DecisionFactor{Name: name, Value: value, Gated: gated})
+	}
+
+	add("enabled", fmt.Sprint(config.Enabled), !config.Enabled && !state.Hold.Active && !config.Output.Enabled)
+	if state.Hold.Active {
+		add("hold", fmt.Sprintf("active at %d since %s (overrides enabled)", state.Hold.Volume, state.Hold.Since.Format(time.RFC3339)), false)
+	} else {
+		add("hold", "inactive", false)
+	}
+	// The output lock keeps ticks running on its own, setting the output only
+	output := config.Output.String()
+	if config.Output.Enabled && !config.Enabled && !state.Hold.Active {
+		output += " (input not enforced)"
+	}
+	add("output", output, false)
+	add("running", fmt.Sprint(state.IsRunning), state.IsRunning)
+	switch until := config.QuietUntil(now); {
+	case len(config.QuietHours) == 0:
+		add("quiet hours", "none", false)
+	case !until.IsZero():
+		add("quiet hours", fmt.Sprintf("active until %s", until.Format(time.RFC3339)), true)
+	default:
+		add("quiet hours", "outside "+FormatQuietHours(config.QuietHours), false)
+	}
+
+	due := state.NextRun.IsZero() || now.After(state.NextRun)
+	interval := s.EffectiveInterval(state, config)
+	if state.ConsecutiveFailures > 0 {
+		backoff := s.FailureBackoff(interval, state.ConsecutiveFailures)
+		add("backoff", fmt.Sprintf("%d consecutive failures, waiting %s", state.ConsecutiveFailures, backoff), !due)
+	} else {
+		add("backoff", "none", false)
+	}
+	if config.Schedule != "" {
+		add("schedule", config.Schedule+" (overrides interval)", false)
+	}
+	if interval != config.Interval {
+		add("interval", fmt.Sprintf("%s (configured %s)", interval, config.Interval), false)
+	} else {
+		add("interval", interval.String(), false)
+	}
+	switch {
+	case state.NextRun.IsZero():
+		add("next run", "not scheduled yet (due)", false)
+	case due:
+		add("next run", fmt.Sprintf("due since %s", state.NextRun.Format(time.RFC3339)), false)
+	default:
+		add("next run", fmt.Sprintf("in %s (at %s)", state.NextRun.Sub(now).Round(time.Second), state.NextRun.Format(time.RFC3339)), state.ConsecutiveFailures == 0)
+	}
+
+	target, raised := s.ApplyFloor(config, s.ResolveTarget(state, config, now))
+	source := s.TargetSource(state, config)
+	if source == "noise" {
+		source = fmt.Sprintf("noise, %.1f dBFS", state.Ambient.Level)
+	}
+	if raised {
+		source += ", raised to minTargetVolume"
+	}
+	add("target", fmt.Sprintf("%d (%s)", target, source), false)
+	if config.DriftAlertThreshold > 0 {
+		add("drift alert", fmt.Sprintf("read back before applying, alert beyond %d", config.DriftAlertThreshold), false)
+	} else {
+		add("drift alert", "off", false)
+	}
+
+	decision := ApplyDecision{Apply: true, Factors: factors}
+	for _, f := range factors {
+		if f.Gated {
+			decision.Apply = false
+		}
+	}
+	return decision
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+)
+
+// HistoryCSVHeader names the columns of HistoryCSVRow.
+var HistoryCSVHeader = []string{
+	"timestamp", "trigger", "requested_volume", "observed_volume", "status", "duration_ms", "error",
+}
+
+// HistoryCSVRow flattens a record for spreadsheet export. The observed
+// volume is only known for scheduled applies and the duration only for
+// records that kept one; both are empty otherwise. Quoting is
+// left to the CSV writer.
+func HistoryCSVRow(r ApplyRecord) []string {
+	observed := ""
+	if r.Observed != nil {
+		observed = strconv.Itoa(*r.Observed)
+	}
+	duration := ""
+	if r.Duration > 0 {
+		duration = strconv.FormatInt(r.Duration.Milliseconds(), 10)
+	}
+	return []string{
+		r.Timestamp.Format(time.RFC3339),
+		r.Trigger.String(),
+		strconv.Itoa(r.Volume),
+		observed,
+		r.Status.String(),
+		duration,
+		r.Error,
+	}
+}
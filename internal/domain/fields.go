@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"slices"
+)
+
+// ConfigField is a setting of Config that a running scheduler loop reads,
+// under the name it is reported by.
+type ConfigField struct {
+	Name string
+	// Same reports whether a and b agree on the setting.
+	Same func(a, b Config) bool
+}
+
+// ConfigFields lists every setting of Config in declaration order, except
+// Locked, which is never saved, and Profiles, which only take effect once
+// activated through ActiveProfile.
+var ConfigFields = []ConfigField{
+	{"targetVolume", func(a, b Config) bool { return a.TargetVolume == b.TargetVolume }},
+	{"interval", func(a, b Config) bool { return a.Interval == b.Interval }},
+	{"enabled", func(a, b Config) bool { return a.Enabled == b.Enabled }},
+	{"scheduleMode", func(a, b Config) bool { return a.ScheduleMode == b.ScheduleMode }},
+	{"schedule", func(a, b Config) bool { return a.Schedule == b.Schedule }},
+	{"timezone", func(a, b Config) bool { return a.Timezone == b.Timezone }},
+	{"adaptiveInterval", func(a, b Config) bool { return a.AdaptiveInterval == b.AdaptiveInterval }},
+	{"maxInterval", func(a, b Config) bool { return a.MaxInterval == b.MaxInterval }},
+	{"rescheduleOnManualApply", func(a, b Config) bool { return a.RescheduleOnManualApply == b.RescheduleOnManualApply }},
+	{"minTargetVolume", func(a, b Config) bool { return a.MinTargetVolume == b.MinTargetVolume }},
+	{"maxRetries", func(a, b Config) bool { return a.MaxRetries == b.MaxRetries }},
+	{"retryBackoff", func(a, b Config) bool { return a.RetryBackoff == b.RetryBackoff }},
+	{"rampDuration", func(a, b Config) bool { return a.RampDuration == b.RampDuration }},
+	{"rampSteps", func(a, b Config) bool { return a.RampSteps == b.RampSteps }},
+	{"errorThreshold", func(a, b Config) bool { return a.ErrorThreshold == b.ErrorThreshold }},
+	{"driftAlertThreshold", func(a, b Config) bool { return a.DriftAlertThreshold == b.DriftAlertThreshold }},
+	{"redactErrors", func(a, b Config) bool { return a.RedactErrors == b.RedactErrors }},
+	{"deviceName", func(a, b Config) bool { return a.DeviceName == b.DeviceName }},
+	{"onDeviceAbsent", func(a, b Config) bool { return a.OnDeviceAbsent == b.OnDeviceAbsent }},
+	{"formatVolumes", func(a, b Config) bool { return slices.Equal(a.FormatVolumes, b.FormatVolumes) }},
+	{"allowedVolumes", func(a, b Config) bool { return slices.Equal(a.AllowedVolumes, b.AllowedVolumes) }},
+	{"appVolumes", func(a, b Config) bool { return slices.Equal(a.AppVolumes, b.AppVolumes) }},
+	{"output", func(a, b Config) bool { return a.Output == b.Output }},
+	{"staggerBetweenDevices", func(a, b Config) bool { return a.StaggerBetweenDevices == b.StaggerBetweenDevices }},
+	{"noise", func(a, b Config) bool { return a.Noise == b.Noise }},
+	{"parkVolume", func(a, b Config) bool { return sameIntPtr(a.ParkVolume, b.ParkVolume) }},
+	{"fadeOnPark", func(a, b Config) bool { return a.FadeOnPark == b.FadeOnPark }},
+	{"reapplyOnPowerChange", func(a, b Config) bool { return a.ReapplyOnPowerChange == b.ReapplyOnPowerChange }},
+	{"powerPollInterval", func(a, b Config) bool { return a.PowerPollInterval == b.PowerPollInterval }},
+	{"preApplyCmd", func(a, b Config) bool { return a.PreApplyCmd == b.PreApplyCmd }},
+	{"postApplyCmd", func(a, b Config) bool { return a.PostApplyCmd == b.PostApplyCmd }},
+	{"abortOnPreApplyFailure", func(a, b Config) bool { return a.AbortOnPreApplyFailure == b.AbortOnPreApplyFailure }},
+	{"applyCmdTimeout", func(a, b Config) bool { return a.ApplyCmdTimeout == b.ApplyCmdTimeout }},
+	{"curve", func(a, b Config) bool { return slices.Equal(a.Curve, b.Curve) }},
+	{"quietHours", func(a, b Config) bool { return FormatQuietHours(a.QuietHours) == FormatQuietHours(b.QuietHours) }},
+	{"activeProfile", func(a, b Config) bool { return a.ActiveProfile == b.ActiveProfile }},
+	{"dryRun", func(a, b Config) bool { return a.DryRun == b.DryRun }},
+}
+
+// ChangedFields names the settings in which a and b differ.
+func ChangedFields(a, b Config) []string {
+	var fields []string
+	for _, f := range ConfigFields {
+		if !f.Same(a, b) {
+			fields = append(fields, f.Name)
+		}
+	}
+	return fields
+}
+
+func sameIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigFieldsCoverConfig(t *testing.T) {
+	var want []string
+	for _, f := range reflect.VisibleFields(reflect.TypeFor[Config]()) {
+		if f.Name == "Locked" || f.Name == "Profiles" {
+			continue
+		}
+		want = append(want, strings.ToLower(f.Name[:1])+f.Name[1:])
+	}
+	var got []string
+	for _, f := range ConfigFields {
+		got = append(got, f.Name)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ConfigFields = %v, want %v", got, want)
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	park := 20
+	running := Config{TargetVolume: 40, Interval: 90 * time.Second, Enabled: true}
+	tests := []struct {
+		name   string
+		change func(*Config)
+		want   []string
+	}{
+		{"unchanged", func(*Config) {}, nil},
+		{"target", func(c *Config) { c.TargetVolume = 50 }, []string{"targetVolume"}},
+		{"allowed volumes", func(c *Config) { c.AllowedVolumes = []int{40, 60} }, []string{"allowedVolumes"}},
+		{"redact errors", func(c *Config) { c.RedactErrors = true }, []string{"redactErrors"}},
+		{"error threshold", func(c *Config) { c.ErrorThreshold = 3 }, []string{"errorThreshold"}},
+		{"park volume", func(c *Config) { c.ParkVolume = &park }, []string{"parkVolume"}},
+		{"fade on park", func(c *Config) { c.FadeOnPark = true }, []string{"fadeOnPark"}},
+		{"two", func(c *Config) { c.Enabled = false; c.DeviceName = "USB" }, []string{"enabled", "deviceName"}},
+		{"locked", func(c *Config) { c.Locked = true }, nil},
+	}
+	service := NewSchedulerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := running
+			tt.change(&config)
+			got := service.RestartRequired(ScheduleState{Running: &running}, config)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("RestartRequired = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestartRequiredSameParkVolume(t *testing.T) {
+	a, b := 20, 20
+	running := Config{ParkVolume: &a}
+	if got := NewSchedulerService().RestartRequired(ScheduleState{Running: &running}, Config{ParkVolume: &b}); got != nil {
+		t.Errorf("RestartRequired = %v, want none for equal park volumes", got)
+	}
+	if got := NewSchedulerService().RestartRequired(ScheduleState{}, Config{ParkVolume: &b}); got != nil {
+		t.Errorf("RestartRequired without a running loop = %v, want none", got)
+	}
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatVolume is a target for the input device while it runs at one
+// sample rate, for interfaces whose right gain differs between rates.
+type FormatVolume struct {
+	// SampleRate is in Hz, e.g. 48000.
+	SampleRate int
+	Volume     int
+}
+
+// ParseFormatVolumes parses a comma separated list of "rate=volume" rules,
+// e.g. "44100=40,48000=55".
+func ParseFormatVolumes(s string) ([]FormatVolume, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var rules []FormatVolume
+	for _, part := range strings.Split(s, ",") {
+		r, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid format volume %q: expected rate=volume", part)
+		}
+		rate, err := strconv.Atoi(strings.TrimSpace(r))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample rate %q", r)
+		}
+		volume, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid format volume %q", v)
+		}
+		rules = append(rules, FormatVolume{SampleRate: rate, Volume: volume})
+	}
+	return rules, nil
+}
+
+// FormatFormatVolumes renders rules in the format accepted by
+// ParseFormatVolumes.
+func FormatFormatVolumes(rules []FormatVolume) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		parts = append(parts, fmt.Sprintf("%d=%d", r.SampleRate, r.Volume))
+	}
+	return strings.Join(parts, ",")
+}
+
+// FormatTarget returns the target of the rule for sampleRate, if any. An
+// unknown rate (zero) matches no rule.
+func (c Config) FormatTarget(sampleRate int) (int, bool) {
+	if sampleRate <= 0 {
+		return 0, false
+	}
+	for _, r := range c.FormatVolumes {
+		if r.SampleRate == sampleRate {
+			return r.Volume, true
+		}
+	}
+	return 0, false
+}
+
+func (c Config) validateFormatVolumes() error {
+	seen := make(map[int]bool, len(c.FormatVolumes))
+	for _, r := range c.FormatVolumes {
+		if r.SampleRate <= 0 {
+			return fmt.Errorf("format volume needs a positive sample rate, got %d", r.SampleRate)
+		}
+		if seen[r.SampleRate] {
+			return fmt.Errorf("duplicate format volume for %d Hz", r.SampleRate)
+		}
+		seen[r.SampleRate] = true
+		if err := ValidateVolume(r.Volume); err != nil {
+			return fmt.Errorf("format volume for %d Hz: %w", r.SampleRate, err)
+		}
+		if err := c.CheckAllowed(r.Volume); err != nil {
+			return fmt.Errorf("format volume for %d Hz: %w", r.SampleRate, err)
+		}
+	}
+	return nil
+}
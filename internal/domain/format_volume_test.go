@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseFormatVolumes(t *testing.T) {
+	rules, err := ParseFormatVolumes(" 44100=40, 48000=55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatFormatVolumes(rules); got != "44100=40,48000=55" {
+		t.Errorf("round trip = %q", got)
+	}
+	for _, bad := range []string{"44100", "rate=40", "44100=loud"} {
+		if _, err := ParseFormatVolumes(bad); err == nil {
+			t.Errorf("ParseFormatVolumes(%q) succeeded", bad)
+		}
+	}
+	if rules, err := ParseFormatVolumes(""); err != nil || rules != nil {
+		t.Errorf("ParseFormatVolumes(\"\") = %v, %v, want no rules", rules, err)
+	}
+}
+
+func TestValidateFormatVolumes(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []FormatVolume
+		ok    bool
+	}{
+		{"valid", []FormatVolume{{44100, 40}, {48000, 55}}, true},
+		{"zero rate", []FormatVolume{{0, 40}}, false},
+		{"duplicate", []FormatVolume{{48000, 40}, {48000, 55}}, false},
+		{"volume", []FormatVolume{{48000, 101}}, false},
+		{"not allowed", []FormatVolume{{48000, 45}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.TargetVolume = 40
+			config.AllowedVolumes = []int{40, 55}
+			config.FormatVolumes = tt.rules
+			err := config.Validate()
+			if (err == nil) != tt.ok {
+				t.Fatalf("Validate = %v, want ok %v", err, tt.ok)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Validate = %v, want ErrInvalidConfig", err)
+			}
+		})
+	}
+}
+
+func TestResolveTargetFormat(t *testing.T) {
+	config := DefaultConfig()
+	config.FormatVolumes = []FormatVolume{{48000, 55}}
+	service := NewSchedulerService()
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rate       int
+		want       int
+		wantSource string
+	}{
+		{48000, 55, "format"},
+		{44100, 50, "config"},
+		{0, 50, "config"},
+	}
+	for _, tt := range tests {
+		state := ScheduleState{SampleRate: tt.rate}
+		if got := service.ResolveTarget(state, config, now); got != tt.want {
+			t.Errorf("ResolveTarget at %d Hz = %d, want %d", tt.rate, got, tt.want)
+		}
+		if got := service.TargetSource(state, config); got != tt.wantSource {
+			t.Errorf("TargetSource at %d Hz = %q, want %q", tt.rate, got, tt.wantSource)
+		}
+	}
+
+	held := ScheduleState{SampleRate: 48000, Hold: Hold{Active: true, Volume: 30}}
+	if got := service.ResolveTarget(held, config, now); got != 30 {
+		t.Errorf("ResolveTarget with a hold = %d, want the held 30", got)
+	}
+}
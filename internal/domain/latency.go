@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// IntervalLatencyFactor is how many times the measured controller latency
+// the interval should be, so that slow calls do not pile up into the next
+// tick.
+const IntervalLatencyFactor = 3
+
+// SafeMinInterval is the shortest interval comfortably above latency, one
+// volume read and set, rounded up to a whole second.
+func (s *SchedulerService) SafeMinInterval(latency time.Duration) time.Duration {
+	safe := latency * IntervalLatencyFactor
+	if rounded := safe.Truncate(time.Second); rounded < safe {
+		safe = rounded + time.Second
+	}
+	return max(safe, MinInterval)
+}
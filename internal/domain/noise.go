@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Noise adaptive target tuning. Readings within NoiseDeadband of the
+// reference leave the target alone; others move it by NoiseStep per tick.
+const (
+	NoiseDeadband = 3.0
+	NoiseStep     = 2
+)
+
+// NoiseControl configures an adaptive target that follows the input level
+// read from a NoiseSensor: a level above ReferenceDBFS lowers the volume,
+// one below raises it, never leaving MinVolume..MaxVolume.
+type NoiseControl struct {
+	Enabled       bool
+	ReferenceDBFS float64
+	MinVolume     int
+	MaxVolume     int
+}
+
+// DefaultNoiseControl returns the noise settings of a new config.
+func DefaultNoiseControl() NoiseControl {
+	return NoiseControl{
+		ReferenceDBFS: -30,
+		MaxVolume:     100,
+	}
+}
+
+// Ambient is the target nudged by the noise adaptive controller. It is
+// kept in memory only, so each scheduler loop starts from the fixed target.
+type Ambient struct {
+	Active bool
+	Volume int
+	// Level is the last reading, in dBFS.
+	Level float64
+}
+
+func validateNoise(n NoiseControl) error {
+	if err := ValidateVolume(n.MinVolume); err != nil {
+		return fmt.Errorf("noise min volume: %w", err)
+	}
+	if err := ValidateVolume(n.MaxVolume); err != nil {
+		return fmt.Errorf("noise max volume: %w", err)
+	}
+	if n.MinVolume > n.MaxVolume {
+		return fmt.Errorf("noise min volume %d is above max volume %d", n.MinVolume, n.MaxVolume)
+	}
+	if n.ReferenceDBFS > 0 || n.ReferenceDBFS < -120 {
+		return fmt.Errorf("noise reference must be between -120 and 0 dBFS")
+	}
+	return nil
+}
+
+// NudgeForNoise moves the ambient target one step toward keeping level at
+// the configured reference. The first reading starts from the target the
+// curve or config would set.
+func (s *SchedulerService) NudgeForNoise(state ScheduleState, config Config, level float64, now time.Time) ScheduleState {
+	volume := state.Ambient.Volume
+	if !state.Ambient.Active {
+		base := state
+		base.Hold = Hold{}
+		volume = s.ResolveTarget(base, config, now)
+	}
+
+	switch {
+	case level > config.Noise.ReferenceDBFS+NoiseDeadband:
+		volume -= NoiseStep
+	case level < config.Noise.ReferenceDBFS-NoiseDeadband:
+		volume += NoiseStep
+	}
+	volume = max(config.Noise.MinVolume, min(volume, config.Noise.MaxVolume))
+	volume = config.NearestAllowed(volume)
+
+	state.Ambient = Ambient{Active: true, Volume: volume, Level: level}
+	return state
+}
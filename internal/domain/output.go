@@ -0,0 +1,44 @@
+package domain
+
+import "fmt"
+
+// VolumeKind selects which system volume a controller sets.
+type VolumeKind int
+
+const (
+	// VolumeInput is the microphone input volume, the one TargetVolume locks.
+	VolumeInput VolumeKind = iota
+	// VolumeOutput is the speaker output volume.
+	VolumeOutput
+)
+
+func (k VolumeKind) String() string {
+	switch k {
+	case VolumeOutput:
+		return "output"
+	default:
+		return "input"
+	}
+}
+
+// OutputLock locks the output volume independently of the input: while
+// Enabled, every scheduled tick sets it to Volume, whether or not the
+// input is enforced.
+type OutputLock struct {
+	Enabled bool
+	Volume  int
+}
+
+func (o OutputLock) String() string {
+	if !o.Enabled {
+		return "off"
+	}
+	return fmt.Sprintf("locked at %d", o.Volume)
+}
+
+func validateOutput(o OutputLock) error {
+	if err := ValidateVolume(o.Volume); err != nil {
+		return fmt.Errorf("output volume: %w", err)
+	}
+	return nil
+}
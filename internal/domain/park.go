@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Parking fade tuning: a fade moves at most ParkFadeStep per step and
+// takes ParkFadeDuration however far it goes.
+const (
+	ParkFadeDuration = 2 * time.Second
+	ParkFadeStep     = 5
+)
+
+// ShouldPark reports whether going from old to config hands the volume
+// back to manual control at config.ParkVolume.
+func (s *SchedulerService) ShouldPark(old, config Config) bool {
+	return old.Enabled && !config.Enabled && config.ParkVolume != nil
+}
+
+// ParkSteps returns the volumes to set, in order, to park at target. A
+// fade starts from the current volume; without one, or when the current
+// volume is unknown (-1), it sets the target at once.
+func (s *SchedulerService) ParkSteps(current, target int, fade bool) []int {
+	if !fade || current < 0 || current == target {
+		return []int{target}
+	}
+	var steps []int
+	for v := current; v != target; {
+		switch {
+		case v < target:
+			v = min(v+ParkFadeStep, target)
+		default:
+			v = max(v-ParkFadeStep, target)
+		}
+		steps = append(steps, v)
+	}
+	return steps
+}
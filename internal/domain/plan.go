@@ -0,0 +1,32 @@
+package domain
+
+// ApplyPlan describes what a manual apply would do, computed without
+// touching the volume, the config or the history.
+type ApplyPlan struct {
+	// Requested is the volume asked for, or -1 for the resolved target.
+	Requested int
+	// Source is where the volume comes from: "requested" or a TargetSource.
+	Source string
+	// Volume is what will be set, after the minTargetVolume floor, which
+	// Raised reports.
+	Volume int
+	Raised bool
+	// Controller describes the volume controller that will set it, and
+	// Device the input device it targets, empty for the default input.
+	Controller string
+	Device     string
+	// Verify reports that the volume is read back after setting it and the
+	// apply fails when it is off by more than Tolerance.
+	Verify    bool
+	Tolerance int
+	// Ramp is how the controller steps to Volume, off when it sets it at
+	// once.
+	Ramp Ramp
+	// PreApplyCmd and PostApplyCmd are run around the apply.
+	PreApplyCmd            string
+	PostApplyCmd           string
+	AbortOnPreApplyFailure bool
+	// Enabled reports whether the scheduler is enabled, which an apply
+	// that respects it requires.
+	Enabled bool
+}
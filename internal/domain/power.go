@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultPowerPollInterval is how often the power source is read when
+// Config.PowerPollInterval is zero.
+const DefaultPowerPollInterval = 10 * time.Second
+
+// PowerPoll returns how often the power source is read for
+// ReapplyOnPowerChange.
+func (c Config) PowerPoll() time.Duration {
+	if c.PowerPollInterval <= 0 {
+		return DefaultPowerPollInterval
+	}
+	return c.PowerPollInterval
+}
+
+func validatePowerPoll(interval time.Duration) error {
+	if interval != 0 && interval < MinInterval {
+		return fmt.Errorf("power poll interval must be zero or at least %s", MinInterval)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Profile is a named set of settings that can be switched to as a whole,
+// including whether the scheduler runs and how often.
+type Profile struct {
+	Name         string
+	TargetVolume int
+	Interval     time.Duration
+	Enabled      bool
+	Curve        []CurvePoint
+}
+
+// ProfileFromConfig captures the current settings as a named profile.
+func ProfileFromConfig(name string, c Config) Profile {
+	return Profile{
+		Name:         name,
+		TargetVolume: c.TargetVolume,
+		Interval:     c.Interval,
+		Enabled:      c.Enabled,
+		Curve:        c.Curve,
+	}
+}
+
+// Profile looks up a profile by name.
+func (c Config) Profile(name string) (Profile, bool) {
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// WithProfile returns a copy of the config with the profile's settings
+// applied and the profile marked active.
+func (c Config) WithProfile(p Profile) Config {
+	c.TargetVolume = p.TargetVolume
+	c.Interval = p.Interval
+	c.Enabled = p.Enabled
+	c.Curve = p.Curve
+	c.ActiveProfile = p.Name
+	return c
+}
+
+// WithSavedProfile returns a copy of the config with the profile added,
+// replacing any existing profile of the same name.
+func (c Config) WithSavedProfile(p Profile) Config {
+	profiles := make([]Profile, 0, len(c.Profiles)+1)
+	replaced := false
+	for _, existing := range c.Profiles {
+		if existing.Name == p.Name {
+			profiles = append(profiles, p)
+			replaced = true
+			continue
+		}
+		profiles = append(profiles, existing)
+	}
+	if !replaced {
+		profiles = append(profiles, p)
+	}
+	c.Profiles = profiles
+	return c
+}
+
+// WithoutProfile returns a copy of the config with the named profile removed.
+func (c Config) WithoutProfile(name string) (Config, error) {
+	profiles := make([]Profile, 0, len(c.Profiles))
+	for _, p := range c.Profiles {
+		if p.Name != name {
+			profiles = append(profiles, p)
+		}
+	}
+	if len(profiles) == len(c.Profiles) {
+		return c, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	c.Profiles = profiles
+	if c.ActiveProfile == name {
+		c.ActiveProfile = ""
+	}
+	return c, nil
+}
+
+// validateProfiles checks every profile's settings and that names are unique.
+func validateProfiles(profiles []Profile, active string) error {
+	seen := make(map[string]bool, len(profiles))
+	for _, p := range profiles {
+		if p.Name == "" {
+			return fmt.Errorf("profile name must not be empty")
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate profile %q", p.Name)
+		}
+		seen[p.Name] = true
+
+		settings := Config{TargetVolume: p.TargetVolume, Interval: p.Interval, Curve: p.Curve}
+		if err := settings.validate(); err != nil {
+			return fmt.Errorf("profile %q: %w", p.Name, err)
+		}
+	}
+	if active != "" && !seen[active] {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, active)
+	}
+	return nil
+}
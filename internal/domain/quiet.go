@@ -0,0 +1,258 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a daily span of local wall-clock time from Start up to,
+// but excluding, End, both minutes of the day. A window whose End is
+// before its Start crosses midnight. Weekdays, when non-empty, limits the
+// window to the days it starts on.
+type TimeWindow struct {
+	Start    int
+	End      int
+	Weekdays []time.Weekday
+}
+
+// weekdayNames are the labels accepted for weekdays, indexed by time.Weekday.
+var weekdayNames = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// On reports whether the window applies to windows starting on day.
+func (w TimeWindow) On(day time.Weekday) bool {
+	return len(w.Weekdays) == 0 || slices.Contains(w.Weekdays, day)
+}
+
+// String renders the window in the format accepted by ParseTimeWindow.
+func (w TimeWindow) String() string {
+	span := FormatClock(w.Start) + "-" + FormatClock(w.End)
+	if len(w.Weekdays) == 0 {
+		return span
+	}
+	return formatWeekdays(w.Weekdays) + "@" + span
+}
+
+// ParseTimeWindow parses "HH:MM-HH:MM", optionally prefixed with the
+// weekdays it applies to, as in "mon-fri@22:00-07:00" or
+// "sat+sun@09:00-12:00".
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	var w TimeWindow
+	span := strings.TrimSpace(s)
+	if days, rest, ok := strings.Cut(span, "@"); ok {
+		weekdays, err := parseWeekdays(days)
+		if err != nil {
+			return TimeWindow{}, err
+		}
+		w.Weekdays, span = weekdays, rest
+	}
+	start, end, ok := strings.Cut(span, "-")
+	if !ok {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: expected HH:MM-HH:MM", s)
+	}
+	var err error
+	if w.Start, err = ParseClock(start); err != nil {
+		return TimeWindow{}, err
+	}
+	if w.End, err = ParseClock(end); err != nil {
+		return TimeWindow{}, err
+	}
+	return w, nil
+}
+
+// ParseQuietHours parses a comma separated list of time windows.
+func ParseQuietHours(s string) ([]TimeWindow, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var windows []TimeWindow
+	for _, part := range strings.Split(s, ",") {
+		w, err := ParseTimeWindow(part)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// FormatQuietHours renders windows in the format accepted by ParseQuietHours.
+func FormatQuietHours(windows []TimeWindow) string {
+	parts := make([]string, 0, len(windows))
+	for _, w := range windows {
+		parts = append(parts, w.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseWeekdays parses "+" separated weekdays or ranges of weekdays, e.g.
+// "mon-fri" or "sat+sun". Ranges may wrap around the week ("fri-mon").
+func parseWeekdays(s string) ([]time.Weekday, error) {
+	var set [7]bool
+	for _, part := range strings.Split(s, "+") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := ParseWeekday(first)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = ParseWeekday(last); err != nil {
+				return nil, err
+			}
+		}
+		for day := from; ; day = (day + 1) % 7 {
+			set[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+	var days []time.Weekday
+	for day, ok := range set {
+		if ok {
+			days = append(days, time.Weekday(day))
+		}
+	}
+	return days, nil
+}
+
+// ParseWeekday converts a label such as "mon" into a weekday.
+func ParseWeekday(s string) (time.Weekday, error) {
+	i := slices.Index(weekdayNames[:], strings.ToLower(strings.TrimSpace(s)))
+	if i < 0 {
+		return 0, fmt.Errorf("invalid weekday %q: expected one of %s", s, strings.Join(weekdayNames[:], ", "))
+	}
+	return time.Weekday(i), nil
+}
+
+// WeekdayLabel is the label ParseWeekday accepts for day, e.g. "mon".
+func WeekdayLabel(day time.Weekday) string {
+	return weekdayNames[day]
+}
+
+// formatWeekdays renders weekdays Monday first, collapsing runs of three or
+// more days, including runs across the weekend such as "sat-mon", into a
+// range.
+func formatWeekdays(days []time.Weekday) string {
+	var set [7]bool
+	for _, day := range days {
+		set[day] = true
+	}
+	// Start after a day that is not set, so no run is split at the start
+	first := time.Monday
+	for i := range 7 {
+		day := (time.Monday + time.Weekday(i)) % 7
+		if !set[(day+6)%7] {
+			first = day
+			break
+		}
+	}
+	var parts []string
+	for i := 0; i < 7; {
+		from := (first + time.Weekday(i)) % 7
+		if !set[from] {
+			i++
+			continue
+		}
+		j := i
+		for j+1 < 7 && set[(first+time.Weekday(j+1))%7] {
+			j++
+		}
+		to := (first + time.Weekday(j)) % 7
+		switch j - i {
+		case 0:
+			parts = append(parts, weekdayNames[from])
+		case 1:
+			parts = append(parts, weekdayNames[from], weekdayNames[to])
+		default:
+			parts = append(parts, weekdayNames[from]+"-"+weekdayNames[to])
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, "+")
+}
+
+// validateQuietHours checks that every window is well formed and that no
+// two windows cover the same minute of the week.
+func validateQuietHours(windows []TimeWindow) error {
+	const week = 7 * minutesPerDay
+	owner := make([]int, week)
+	for i, w := range windows {
+		if w.Start < 0 || w.Start >= minutesPerDay || w.End < 0 || w.End >= minutesPerDay {
+			return fmt.Errorf("%w: window times must be within a day", ErrInvalidQuietHours)
+		}
+		if w.Start == w.End {
+			return fmt.Errorf("%w: window %s is empty", ErrInvalidQuietHours, w)
+		}
+		for j, day := range w.Weekdays {
+			if day < time.Sunday || day > time.Saturday {
+				return fmt.Errorf("%w: invalid weekday %d", ErrInvalidQuietHours, day)
+			}
+			if slices.Contains(w.Weekdays[:j], day) {
+				return fmt.Errorf("%w: window %s lists %s twice", ErrInvalidQuietHours, w, weekdayNames[day])
+			}
+		}
+
+		length := (w.End - w.Start + minutesPerDay) % minutesPerDay
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if !w.On(day) {
+				continue
+			}
+			start := int(day)*minutesPerDay + w.Start
+			for m := start; m < start+length; m++ {
+				if other := owner[m%week]; other != 0 {
+					return fmt.Errorf("%w: %s overlaps %s", ErrInvalidQuietHours, w, windows[other-1])
+				}
+				owner[m%week] = i + 1
+			}
+		}
+	}
+	return nil
+}
+
+// QuietUntil returns when the quiet hours active at t end, carrying on
+// through windows that begin as the previous one ends, or the zero time
+// when t is outside every window.
+func (c Config) QuietUntil(t time.Time) time.Time {
+	var until time.Time
+	// Back-to-back windows chain at most once per window and weekday
+	for range 7*len(c.QuietHours) + 1 {
+		end, ok := c.quietWindowEnd(t)
+		if !ok || !end.After(t) {
+			break
+		}
+		until, t = end, end
+	}
+	return until
+}
+
+// quietWindowEnd returns the end of the window containing t, read on the
+// wall clock in config's zone.
+func (c Config) quietWindowEnd(t time.Time) (time.Time, bool) {
+	loc := c.Location()
+	local := t.In(loc)
+	y, m, d := local.Date()
+	minute := local.Hour()*60 + local.Minute()
+	today := local.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, w := range c.QuietHours {
+		if w.Start < w.End {
+			if minute >= w.Start && minute < w.End && w.On(today) {
+				return time.Date(y, m, d, 0, w.End, 0, 0, loc), true
+			}
+			continue
+		}
+		// Crossing midnight: the evening part started today, the morning
+		// part yesterday
+		if minute >= w.Start && w.On(today) {
+			return time.Date(y, m, d+1, 0, w.End, 0, 0, loc), true
+		}
+		if minute < w.End && w.On(yesterday) {
+			return time.Date(y, m, d, 0, w.End, 0, 0, loc), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultRampSteps is how many volume sets a ramp takes when
+	// Config.RampSteps is zero.
+	DefaultRampSteps = 10
+	// MaxRampDuration and MaxRampSteps bound a ramp, which holds up every
+	// other apply while it runs.
+	MaxRampDuration = 10 * time.Second
+	MaxRampSteps    = 100
+	// MaxStagger bounds Config.StaggerBetweenDevices, which like a ramp
+	// holds up every other apply while it runs.
+	MaxStagger = 10 * time.Second
+)
+
+// Ramp steps the volume from its current reading to a new target over
+// Duration instead of setting the target at once. A zero Duration
+// disables it.
+type Ramp struct {
+	Duration time.Duration
+	Steps    int
+}
+
+// Ramp returns the ramp configured by RampDuration and RampSteps.
+func (c Config) Ramp() Ramp {
+	steps := c.RampSteps
+	if steps <= 0 {
+		steps = DefaultRampSteps
+	}
+	return Ramp{Duration: c.RampDuration, Steps: steps}
+}
+
+func (r Ramp) String() string {
+	if r.Duration <= 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%s in up to %d steps", r.Duration, r.Steps)
+}
+
+// Volumes returns the volumes to set, in order, to go from current to
+// target, spread evenly and never more steps than the volume moves. It
+// sets the target at once when the ramp is off or current is unknown (-1).
+func (r Ramp) Volumes(current, target int) []int {
+	distance := target - current
+	if r.Duration <= 0 || current < 0 || distance == 0 {
+		return []int{target}
+	}
+	n := min(max(r.Steps, 1), max(distance, -distance))
+	volumes := make([]int, 0, n)
+	for i := 1; i <= n; i++ {
+		volumes = append(volumes, current+distance*i/n)
+	}
+	return volumes
+}
+
+// Pause returns the wait between two of n volume sets, so that the last
+// one lands Duration after the first.
+func (r Ramp) Pause(n int) time.Duration {
+	if n <= 1 {
+		return 0
+	}
+	return r.Duration / time.Duration(n-1)
+}
+
+func validateRamp(duration time.Duration, steps int) error {
+	if duration < 0 || duration > MaxRampDuration {
+		return fmt.Errorf("%w: ramp duration must be between 0 and %s", ErrInvalidRamp, MaxRampDuration)
+	}
+	if steps < 0 || steps > MaxRampSteps {
+		return fmt.Errorf("%w: ramp steps must be between 0 and %d", ErrInvalidRamp, MaxRampSteps)
+	}
+	return nil
+}
@@ -1,5 +1,10 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 // ConfigRepository is a secondary port that defines how to persist configuration.
 // This interface is defined in the domain layer and implemented by adapters.
 type ConfigRepository interface {
@@ -7,8 +12,107 @@ type ConfigRepository interface {
 	Save(config Config, state ScheduleState) error
 }
 
+// ConfigWatcher is implemented by ConfigRepositories that can tell when the
+// stored config is changed from outside, e.g. edited by hand.
+type ConfigWatcher interface {
+	// Watch sends on the returned channel after each outside change, until
+	// ctx ends. Changes the repository saved itself are not reported.
+	Watch(ctx context.Context) (<-chan struct{}, error)
+}
+
 // VolumeController is a secondary port that defines how to control microphone volume.
 // This interface is defined in the domain layer and implemented by adapters.
 type VolumeController interface {
 	SetVolume(volume int) error
+	// GetVolume reads back the current volume. Controllers that cannot read
+	// the volume return ErrNotSupported.
+	GetVolume() (int, error)
+	// ListInputDevices lists the input devices, for picking a DeviceName.
+	// Controllers that cannot list devices return ErrNotSupported.
+	ListInputDevices() ([]AudioDevice, error)
+}
+
+// VolumeProber is implemented by VolumeControllers that can check that
+// their backend is usable, so that a missing one is reported at startup
+// rather than on the first apply.
+type VolumeProber interface {
+	Probe() error
+}
+
+// DeviceVolumeController is implemented by VolumeControllers that can
+// target a named input device rather than the system default input.
+type DeviceVolumeController interface {
+	SetDeviceVolume(device string, volume int) error
+	GetDeviceVolume(device string) (int, error)
+}
+
+// KindVolumeController is implemented by VolumeControllers that can
+// also set the volumes of other kinds than the input SetVolume targets.
+type KindVolumeController interface {
+	SetKindVolume(kind VolumeKind, volume int) error
+	GetKindVolume(kind VolumeKind) (int, error)
+}
+
+// RampController is implemented by VolumeControllers that ramp to a new
+// volume rather than set it at once; the scheduler passes the configured
+// ramp before setting a volume.
+type RampController interface {
+	SetRamp(ramp Ramp)
+}
+
+// AppVolumeController is a secondary port that defines how to control the
+// input level of individual applications.
+// This interface is defined in the domain layer and implemented by adapters.
+type AppVolumeController interface {
+	// SetAppVolume sets the input level of one application. Apps whose
+	// input gain cannot be scripted return ErrNotSupported.
+	SetAppVolume(app string, volume int) error
+}
+
+// NoiseSensor is a secondary port that defines how to measure the input
+// level driving the noise adaptive target.
+// This interface is defined in the domain layer and implemented by adapters.
+type NoiseSensor interface {
+	// Level returns the current input level in dBFS, 0 being full scale.
+	Level() (float64, error)
+}
+
+// PowerSource is a secondary port that defines how to read the power
+// source for ReapplyOnPowerChange.
+// This interface is defined in the domain layer and implemented by adapters.
+type PowerSource interface {
+	// Source names the current power source, e.g. "AC Power". Platforms
+	// where it cannot be read return ErrNotSupported.
+	Source() (string, error)
+}
+
+// CommandRunner is a secondary port that defines how to run the user's
+// pre- and post-apply commands.
+// This interface is defined in the domain layer and implemented by adapters.
+type CommandRunner interface {
+	// Run executes command with env added to the environment and returns
+	// its combined output. It fails when the command does not finish
+	// within timeout.
+	Run(command string, env map[string]string, timeout time.Duration) ([]byte, error)
+}
+
+// Notifier is a secondary port that defines how to alert the user when
+// applies start failing and when they recover.
+// This interface is defined in the domain layer and implemented by adapters.
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// HistoryRepository is a secondary port that defines how to record apply history.
+// This interface is defined in the domain layer and implemented by adapters.
+type HistoryRepository interface {
+	Append(record ApplyRecord) error
+	// Query returns the page of records selected by q and the total number of matches.
+	Query(q HistoryQuery) ([]ApplyRecord, int, error)
+}
+
+// EffectRecorder is a secondary port that defines how to trace executed side effects.
+// This interface is defined in the domain layer and implemented by adapters.
+type EffectRecorder interface {
+	Record(record EffectRecord) error
 }
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultRetryBackoff is the delay before the first retry of a failed
+	// volume set when Config.RetryBackoff is zero.
+	DefaultRetryBackoff = time.Second
+	// MaxRetries bounds Config.MaxRetries and MaxRetryBackoff the delay
+	// before any one retry, so a failing controller cannot stall applies
+	// for long.
+	MaxRetries      = 10
+	MaxRetryBackoff = 30 * time.Second
+)
+
+// RetryDelay returns how long to wait before the given retry, counted from
+// 1: RetryBackoff doubled for each earlier retry, up to MaxRetryBackoff.
+func (c Config) RetryDelay(retry int) time.Duration {
+	delay := c.RetryBackoff
+	if delay <= 0 {
+		delay = DefaultRetryBackoff
+	}
+	for i := 1; i < retry && delay < MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxRetryBackoff)
+}
+
+func validateRetry(retries int, backoff time.Duration) error {
+	if retries < 0 || retries > MaxRetries {
+		return fmt.Errorf("%w: max retries must be between 0 and %d", ErrInvalidRetry, MaxRetries)
+	}
+	if backoff < 0 || backoff > MaxRetryBackoff {
+		return fmt.Errorf("%w: retry backoff must be between 0 and %s", ErrInvalidRetry, MaxRetryBackoff)
+	}
+	return nil
+}
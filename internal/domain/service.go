@@ -1,70 +1,476 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // SchedulerService provides pure domain logic for the scheduler.
 // This service has no side effects and no dependencies on external concerns.
-type SchedulerService struct{}
+type SchedulerService struct {
+	clock Clock
+}
+
+// ServiceOption configures a SchedulerService.
+type ServiceOption func(*SchedulerService)
+
+// WithClock makes the service read the time from clock where the caller
+// gives none, instead of the system clock.
+func WithClock(clock Clock) ServiceOption {
+	return func(s *SchedulerService) {
+		s.clock = clock
+	}
+}
+
+// AdaptiveStableThreshold is the number of consecutive checks without drift
+// after which the adaptive interval is lengthened.
+const AdaptiveStableThreshold = 3
+
+// MaxFailureBackoff caps how far repeated failures push the next attempt out.
+const MaxFailureBackoff = 10 * time.Minute
+
+// MinInterval is the shortest interval between scheduled applies.
+const MinInterval = time.Second
+
+// DefaultApplyCmdTimeout bounds pre- and post-apply commands when the
+// config leaves ApplyCmdTimeout at zero.
+const DefaultApplyCmdTimeout = 10 * time.Second
 
 // NewSchedulerService creates a new scheduler service.
-func NewSchedulerService() *SchedulerService {
-	return &SchedulerService{}
+func NewSchedulerService(opts ...ServiceOption) *SchedulerService {
+	s := &SchedulerService{clock: SystemClock{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // ShouldApply determines if volume should be applied based on current state and time.
 // This is a pure function with no side effects.
+// An active hold is enforced even while the scheduler is disabled.
+// A tick that arrives while an apply is still in flight is coalesced into it.
+// ExplainApply lists the factors behind the decision.
 func (s *SchedulerService) ShouldApply(state ScheduleState, config Config, now time.Time) bool {
-	if !config.Enabled {
-		return false
+	return s.ExplainApply(state, config, now).Apply
+}
+
+// CalculateNextRun determines the next scheduled run time.
+// In relative mode it is one interval after lastApplied, so a slow apply
+// shifts the schedule. In fixed mode it is the first interval boundary,
+// counted on the wall clock from midnight in config's zone, after
+// lastApplied, so runs stay on the same marks (e.g. :00 and :30) however
+// long applies take and across DST changes. A cron Schedule overrides
+// both and runs at its next match after lastApplied, also after a failure.
+// A run that falls in quiet hours moves to their end.
+func (s *SchedulerService) CalculateNextRun(config Config, lastApplied time.Time, interval time.Duration) time.Time {
+	next := s.nextBoundary(config, lastApplied, interval)
+	if until := config.QuietUntil(next); !until.IsZero() {
+		return until
 	}
+	return next
+}
 
-	// If never run or next run time has passed
-	if state.NextRun.IsZero() || now.After(state.NextRun) {
-		return true
+// nextBoundary is CalculateNextRun without regard to quiet hours.
+func (s *SchedulerService) nextBoundary(config Config, lastApplied time.Time, interval time.Duration) time.Time {
+	if lastApplied.IsZero() {
+		lastApplied = s.clock.Now()
+	}
+	if next := config.NextScheduled(lastApplied); !next.IsZero() {
+		return next
+	}
+	if config.ScheduleMode != ScheduleFixed || interval <= 0 {
+		return lastApplied.Add(interval)
 	}
 
-	return false
+	local := lastApplied.In(config.Location())
+	y, m, d := local.Date()
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+	for boundaries := sinceMidnight/interval + 1; ; boundaries++ {
+		// time.Date normalizes the wall clock, so a boundary that falls in
+		// a DST gap moves out of it; skip any that land at or before
+		// lastApplied. Boundaries restart at the next midnight.
+		wall := min(boundaries*interval, 24*time.Hour)
+		next := time.Date(y, m, d, 0, 0, 0, int(wall), local.Location())
+		if next.After(lastApplied) {
+			return next
+		}
+	}
 }
 
-// CalculateNextRun determines the next scheduled run time.
-func (s *SchedulerService) CalculateNextRun(lastApplied time.Time, interval time.Duration) time.Time {
-	if lastApplied.IsZero() {
-		return time.Now().Add(interval)
+// ProjectedNextRun returns the next run recorded in state, or, for state
+// saved without one, the run one interval after LastApplied. It is zero
+// when nothing was applied yet, in which case a starting loop applies at
+// once.
+func (s *SchedulerService) ProjectedNextRun(state ScheduleState, config Config) time.Time {
+	if !state.NextRun.IsZero() || state.LastApplied.IsZero() {
+		return state.NextRun
 	}
-	return lastApplied.Add(interval)
+	return s.CalculateNextRun(config, state.LastApplied, s.EffectiveInterval(state, config))
 }
 
 // ApplySuccess updates the state after a successful volume application.
 func (s *SchedulerService) ApplySuccess(state ScheduleState, config Config, appliedAt time.Time) ScheduleState {
-	return ScheduleState{
-		LastApplied:     appliedAt,
-		LastApplyStatus: StatusSuccess,
-		LastError:       nil,
-		NextRun:         s.CalculateNextRun(appliedAt, config.Interval),
-		IsRunning:       false,
+	state.LastApplied = appliedAt
+	if state.FirstApplied.IsZero() {
+		state.FirstApplied = appliedAt
 	}
+	state.LastApplyStatus = StatusSuccess
+	state.LastError = nil
+	state.LastWarning = ""
+	state.NextRun = s.CalculateNextRun(config, appliedAt, s.EffectiveInterval(state, config))
+	state.IsRunning = false
+	state.ConsecutiveFailures = 0
+	return state
+}
+
+// KeepSchedule undoes the move of the next run by an apply with trigger
+// that config does not reschedule on, restoring next, the next run from
+// before the apply. Only manual applies can be kept off the schedule.
+func (s *SchedulerService) KeepSchedule(state ScheduleState, config Config, trigger ApplyTrigger, next time.Time) ScheduleState {
+	if trigger != TriggerManual || config.RescheduleOnManualApply || next.IsZero() {
+		return state
+	}
+	state.NextRun = next
+	return state
+}
+
+// WaitForDevice updates the state after an apply found the configured
+// device absent and waited for it. That is neither a success nor a
+// failure: the failure streak ends without its backoff, and the next run is
+// one interval away.
+func (s *SchedulerService) WaitForDevice(state ScheduleState, config Config, at time.Time) ScheduleState {
+	state.LastApplyStatus = StatusWaitingDevice
+	state.LastError = nil
+	state.LastWarning = ""
+	state.ConsecutiveFailures = 0
+	state.NextRun = s.CalculateNextRun(config, at, s.EffectiveInterval(state, config))
+	state.IsRunning = false
+	return state
 }
 
 // ApplyFailure updates the state after a failed volume application.
+// LastApplied is kept so that it still points at the previous success.
+// Each consecutive failure doubles the wait before the next attempt.
 func (s *SchedulerService) ApplyFailure(state ScheduleState, config Config, err error, attemptedAt time.Time) ScheduleState {
-	return ScheduleState{
-		LastApplied:     state.LastApplied, // Keep previous success time
-		LastApplyStatus: StatusError,
-		LastError:       err,
-		NextRun:         s.CalculateNextRun(attemptedAt, config.Interval),
-		IsRunning:       false,
+	state.LastApplyStatus = StatusError
+	state.LastError = err
+	state.LastWarning = ""
+	state.ConsecutiveFailures++
+	state.NextRun = s.CalculateNextRun(config, attemptedAt, s.FailureBackoff(s.EffectiveInterval(state, config), state.ConsecutiveFailures))
+	state.IsRunning = false
+	return state
+}
+
+// FailureBackoff returns the wait after the given number of consecutive
+// failures: the interval doubled per extra failure, up to MaxFailureBackoff.
+// An interval already longer than the cap is never shortened.
+func (s *SchedulerService) FailureBackoff(interval time.Duration, failures int) time.Duration {
+	backoff := interval
+	for i := 1; i < failures && backoff < MaxFailureBackoff; i++ {
+		backoff *= 2
 	}
+	return max(interval, min(backoff, MaxFailureBackoff))
+}
+
+// RecordWarning attaches a non-fatal warning to a successful application.
+func (s *SchedulerService) RecordWarning(state ScheduleState, warning string) ScheduleState {
+	state.LastWarning = warning
+	return state
+}
+
+// SignificantDrift reports whether the read-back volume was further from
+// the target than config.DriftAlertThreshold allows.
+func (s *SchedulerService) SignificantDrift(config Config, observed, target int) bool {
+	return config.DriftAlertThreshold > 0 && abs(observed-target) > config.DriftAlertThreshold
+}
+
+// ObserveVolume records the volume read back before a scheduled apply.
+func (s *SchedulerService) ObserveVolume(state ScheduleState, observed int) ScheduleState {
+	state.LastObservedVolume = &observed
+	return state
+}
+
+// RedactError returns ErrApplyFailed in place of err when config asks for
+// apply failures to be redacted.
+func (s *SchedulerService) RedactError(config Config, err error) error {
+	if err == nil || !config.RedactErrors {
+		return err
+	}
+	return ErrApplyFailed
+}
+
+// RedactState returns state with its last error redacted per config.
+func (s *SchedulerService) RedactState(state ScheduleState, config Config) ScheduleState {
+	state.LastError = s.RedactError(config, state.LastError)
+	return state
+}
+
+// RedactRecord returns record with its error redacted per config.
+func (s *SchedulerService) RedactRecord(record ApplyRecord, config Config) ApplyRecord {
+	if record.Error != "" && config.RedactErrors {
+		record.Error = ErrApplyFailed.Error()
+	}
+	return record
+}
+
+// ClearStatus returns the state to a clean "never applied" status without
+// touching the config or the hold: the last result, error, warning and
+// failure streak are dropped, and the next run no longer waits out a
+// failure backoff. LastApplied still records the previous success.
+func (s *SchedulerService) ClearStatus(state ScheduleState, config Config) ScheduleState {
+	state.LastApplyStatus = StatusNever
+	state.LastError = nil
+	state.LastWarning = ""
+	state.ConsecutiveFailures = 0
+	state.FirstApplied = time.Time{}
+	state.NextRun = time.Time{}
+	if !state.LastApplied.IsZero() {
+		state.NextRun = s.CalculateNextRun(config, state.LastApplied, s.EffectiveInterval(state, config))
+	}
+	return state
+}
+
+// StartLoop begins a new enforcement period when the scheduler loop
+// starts, so that FirstApplied is set again by its first success.
+func (s *SchedulerService) StartLoop(state ScheduleState) ScheduleState {
+	state.FirstApplied = time.Time{}
+	return state
 }
 
 // StartRunning marks the state as currently applying volume.
 func (s *SchedulerService) StartRunning(state ScheduleState) ScheduleState {
-	return ScheduleState{
-		LastApplied:     state.LastApplied,
-		LastApplyStatus: state.LastApplyStatus,
-		LastError:       state.LastError,
-		NextRun:         state.NextRun,
-		IsRunning:       true,
+	state.IsRunning = true
+	return state
+}
+
+// ReportedStatus is the status shown to users: a failure streak shorter
+// than config.ErrorThreshold is reported as degraded rather than error, so
+// one transient failure does not raise an alarm.
+func (s *SchedulerService) ReportedStatus(state ScheduleState, config Config) ApplyStatus {
+	if state.LastApplyStatus == StatusError && state.ConsecutiveFailures < config.ErrorThreshold {
+		return StatusDegraded
+	}
+	return state.LastApplyStatus
+}
+
+// SkipApply updates the state when a tick decided not to apply, waiting a
+// full interval before the next attempt.
+func (s *SchedulerService) SkipApply(state ScheduleState, config Config, now time.Time) ScheduleState {
+	state.NextRun = s.CalculateNextRun(config, now, s.EffectiveInterval(state, config))
+	state.IsRunning = false
+	return state
+}
+
+// CheckEnabled returns ErrNotEnabled when neither the scheduler nor a hold
+// is currently enforcing a volume.
+func (s *SchedulerService) CheckEnabled(state ScheduleState, config Config) error {
+	if !config.Enabled && !state.Hold.Active {
+		return ErrNotEnabled
+	}
+	return nil
+}
+
+// Enforcing reports whether scheduled ticks have anything to enforce: the
+// target or a hold (CheckEnabled), or the output lock.
+func (s *SchedulerService) Enforcing(state ScheduleState, config Config) bool {
+	return s.CheckEnabled(state, config) == nil || config.Output.Enabled
+}
+
+// CheckMutable returns ErrConfigLocked when the config may not be changed.
+func (s *SchedulerService) CheckMutable(config Config) error {
+	if config.Locked {
+		return ErrConfigLocked
+	}
+	return nil
+}
+
+// CheckQuantization returns a QuantizationError when the read-back volume
+// differs from the requested one by more than tolerance.
+func (s *SchedulerService) CheckQuantization(requested, achieved, tolerance int) error {
+	if diff := achieved - requested; diff > tolerance || -diff > tolerance {
+		return &QuantizationError{Requested: requested, Achieved: achieved}
+	}
+	return nil
+}
+
+// ResolveTarget returns the volume that should be enforced at now.
+// An active hold always wins, then the noise adaptive target, then the
+// curve, then the configured target.
+func (s *SchedulerService) ResolveTarget(state ScheduleState, config Config, now time.Time) int {
+	if state.Hold.Active {
+		return state.Hold.Volume
+	}
+	if config.Noise.Enabled && state.Ambient.Active {
+		return state.Ambient.Volume
+	}
+	if len(config.Curve) > 0 {
+		return InterpolateCurve(config.Curve, now.In(config.Location()))
+	}
+	if volume, ok := config.FormatTarget(state.SampleRate); ok {
+		return volume
+	}
+	return config.TargetVolume
+}
+
+// TargetSource names what ResolveTarget takes the volume from: "hold",
+// "noise", "curve", "format" or "config".
+func (s *SchedulerService) TargetSource(state ScheduleState, config Config) string {
+	switch {
+	case state.Hold.Active:
+		return "hold"
+	case config.Noise.Enabled && state.Ambient.Active:
+		return "noise"
+	case len(config.Curve) > 0:
+		return "curve"
+	default:
+		if _, ok := config.FormatTarget(state.SampleRate); ok {
+			return "format"
+		}
+		return "config"
+	}
+}
+
+// EffectiveInterval returns the interval the scheduler actually ticks at.
+// An adaptive interval may stretch the configured one while the volume is
+// stable, and a curve needs a fine cadence to be tracked, so it is capped.
+func (s *SchedulerService) EffectiveInterval(state ScheduleState, config Config) time.Duration {
+	interval := config.Interval
+	if config.AdaptiveInterval && state.AdaptedInterval > interval {
+		interval = min(state.AdaptedInterval, config.MaxInterval)
+	}
+	if len(config.Curve) > 0 && interval > CurveMaxInterval {
+		return CurveMaxInterval
+	}
+	return interval
+}
+
+// AdaptInterval updates the adaptive interval after a read-back check.
+// Every AdaptiveStableThreshold consecutive stable checks double the
+// interval up to MaxInterval; any drift snaps it back to the base interval.
+func (s *SchedulerService) AdaptInterval(state ScheduleState, config Config, stable bool) ScheduleState {
+	if !config.AdaptiveInterval || !stable {
+		state.StableCount = 0
+		state.AdaptedInterval = 0
+		return state
+	}
+	state.StableCount++
+	if state.StableCount%AdaptiveStableThreshold == 0 {
+		current := max(state.AdaptedInterval, config.Interval)
+		state.AdaptedInterval = min(current*2, config.MaxInterval)
+	}
+	return state
+}
+
+// PreviewTargets returns the configured target at each step over the horizon.
+func (s *SchedulerService) PreviewTargets(config Config, from time.Time, horizon, step time.Duration) []TargetPoint {
+	var points []TargetPoint
+	// Points carry the config's zone, so they read like the curve
+	from = from.In(config.Location())
+	for at := from; !at.After(from.Add(horizon)); at = at.Add(step) {
+		volume, _ := s.ApplyFloor(config, s.ResolveTarget(ScheduleState{}, config, at))
+		points = append(points, TargetPoint{
+			At:     at,
+			Volume: volume,
+		})
+	}
+	return points
+}
+
+// ApplyFloor raises volume to config.Floor and reports whether the floor
+// changed it.
+func (s *SchedulerService) ApplyFloor(config Config, volume int) (int, bool) {
+	if floor := config.Floor(); volume < floor {
+		return floor, true
+	}
+	return volume, false
+}
+
+// StartHold marks the state as holding the given volume.
+func (s *SchedulerService) StartHold(state ScheduleState, volume int, now time.Time) (ScheduleState, error) {
+	if err := ValidateVolume(volume); err != nil {
+		return state, err
+	}
+	state.Hold = Hold{Active: true, Volume: volume, Since: now}
+	return state, nil
+}
+
+// ReleaseHold clears any active hold.
+func (s *SchedulerService) ReleaseHold(state ScheduleState) ScheduleState {
+	state.Hold = Hold{}
+	return state
+}
+
+// RestartRequired lists the settings in config that the running scheduler
+// loop has not picked up. It is empty when no loop is running.
+func (s *SchedulerService) RestartRequired(state ScheduleState, config Config) []string {
+	if state.Running == nil {
+		return nil
+	}
+	return ChangedFields(*state.Running, config)
+}
+
+// ReplayHistory folds apply records, oldest first, through the same
+// transitions the scheduler uses and returns the state they imply.
+func (s *SchedulerService) ReplayHistory(config Config, records []ApplyRecord) ScheduleState {
+	state := ScheduleState{LastApplyStatus: StatusNever}
+	for _, r := range records {
+		switch r.Status {
+		case StatusError:
+			state = s.ApplyFailure(state, config, errors.New(r.Error), r.Timestamp)
+			continue
+		case StatusWaitingDevice:
+			state = s.WaitForDevice(state, config, r.Timestamp)
+			continue
+		}
+		state = s.ApplySuccess(state, config, r.Timestamp)
+		if r.Warning != "" {
+			state = s.RecordWarning(state, r.Warning)
+		}
+	}
+	return state
+}
+
+// StateDivergence describes every apply outcome field where actual differs
+// from the state expected from the history.
+func (s *SchedulerService) StateDivergence(expected, actual ScheduleState) []string {
+	var diffs []string
+	if !expected.LastApplied.Equal(actual.LastApplied) {
+		diffs = append(diffs, fmt.Sprintf("lastApplied: history %s, persisted %s",
+			formatStateTime(expected.LastApplied), formatStateTime(actual.LastApplied)))
+	}
+	if expected.LastApplyStatus != actual.LastApplyStatus {
+		diffs = append(diffs, fmt.Sprintf("lastApplyStatus: history %s, persisted %s",
+			expected.LastApplyStatus, actual.LastApplyStatus))
+	}
+	if errorText(expected.LastError) != errorText(actual.LastError) {
+		diffs = append(diffs, fmt.Sprintf("lastError: history %q, persisted %q",
+			errorText(expected.LastError), errorText(actual.LastError)))
+	}
+	if expected.ConsecutiveFailures != actual.ConsecutiveFailures {
+		diffs = append(diffs, fmt.Sprintf("consecutiveFailures: history %d, persisted %d",
+			expected.ConsecutiveFailures, actual.ConsecutiveFailures))
+	}
+	if expected.LastWarning != actual.LastWarning {
+		diffs = append(diffs, fmt.Sprintf("lastWarning: history %q, persisted %q",
+			expected.LastWarning, actual.LastWarning))
+	}
+	return diffs
+}
+
+func formatStateTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
 	}
+	return err.Error()
 }
 
 // ValidateAndNormalize validates a config and returns a normalized version.
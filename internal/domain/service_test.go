@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculateNextRun(t *testing.T) {
+	at := func(day, hour, minute, second int) time.Time {
+		return time.Date(2026, 1, day, hour, minute, second, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		mode     ScheduleMode
+		interval time.Duration
+		last     time.Time
+		want     time.Time
+	}{
+		{"relative", ScheduleRelative, 30 * time.Minute, at(5, 9, 7, 30), at(5, 9, 37, 30)},
+		{"fixed", ScheduleFixed, 30 * time.Minute, at(5, 9, 7, 30), at(5, 9, 30, 0)},
+		{"fixed on a mark", ScheduleFixed, 30 * time.Minute, at(5, 9, 30, 0), at(5, 10, 0, 0)},
+		{"fixed late apply", ScheduleFixed, 30 * time.Minute, at(5, 9, 31, 0), at(5, 10, 0, 0)},
+		{"fixed restarts at midnight", ScheduleFixed, 7 * time.Hour, at(5, 22, 0, 0), at(6, 0, 0, 0)},
+	}
+	service := NewSchedulerService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{ScheduleMode: tt.mode, Timezone: "UTC"}
+			if got := service.CalculateNextRun(config, tt.last, tt.interval); !got.Equal(tt.want) {
+				t.Errorf("CalculateNextRun(%v) = %v, want %v", tt.last, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ConfigSimulation is the projected outcome of saving a config, computed
+// without saving or applying anything.
+type ConfigSimulation struct {
+	Snapshot Snapshot
+	// Target is the volume the next apply would set.
+	Target   int
+	Warnings []string
+}
+
+// ConfigChanged returns the state after config has been saved at now: the
+// adaptive interval starts over and the next run is counted from now.
+func (s *SchedulerService) ConfigChanged(state ScheduleState, config Config, now time.Time) ScheduleState {
+	state = s.AdaptInterval(state, config, false)
+	state.NextRun = s.CalculateNextRun(config, now, s.EffectiveInterval(state, config))
+	return state
+}
+
+// SimulateUpdate projects saving an already validated config over state at
+// now, and lists the settings that will not behave as written.
+func (s *SchedulerService) SimulateUpdate(state ScheduleState, config Config, now time.Time) ConfigSimulation {
+	state = s.ConfigChanged(state, config, now)
+	sim := ConfigSimulation{
+		Snapshot: Snapshot{Config: config, ScheduleState: state},
+	}
+
+	resolved := s.ResolveTarget(state, config, now)
+	target, raised := s.ApplyFloor(config, resolved)
+	sim.Target = target
+	if raised {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("target %d is below minTargetVolume; %d will be applied", resolved, target))
+	}
+	if interval := s.EffectiveInterval(state, config); interval < config.Interval {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("interval is capped at %s while a curve is configured", interval))
+	}
+	if config.Schedule != "" && (config.ScheduleMode == ScheduleFixed || config.AdaptiveInterval) {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("schedule %q decides the runs; scheduleMode and adaptiveInterval have no effect", config.Schedule))
+	}
+	if state.Hold.Active {
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("volume is held at %d; the new settings take effect after unlock", state.Hold.Volume))
+	}
+	if fields := s.RestartRequired(state, config); len(fields) > 0 {
+		sim.Warnings = append(sim.Warnings, "the running scheduler loop will not pick up: "+strings.Join(fields, ", "))
+	}
+	return sim
+}
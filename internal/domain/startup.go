@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// StartupCheck is the volume read back when the scheduler loop starts,
+// before its first apply, compared with the target at that time.
+type StartupCheck struct {
+	At     time.Time
+	Volume int
+	Target int
+	// Error is set when the controller could not read the volume, in
+	// which case Volume is meaningless.
+	Error string
+}
+
+// Failed reports whether the controller could not read the volume.
+func (c StartupCheck) Failed() bool {
+	return c.Error != ""
+}
+
+// Drift is how far the volume was from the target, negative when below.
+func (c StartupCheck) Drift() int {
+	return c.Volume - c.Target
+}
+
+// String summarizes the check, e.g. "volume 30, 10 below target 40".
+func (c StartupCheck) String() string {
+	switch drift := c.Drift(); {
+	case c.Failed():
+		return "controller problem: " + c.Error
+	case drift == 0:
+		return fmt.Sprintf("volume %d matches target", c.Volume)
+	case drift < 0:
+		return fmt.Sprintf("volume %d, %d below target %d", c.Volume, -drift, c.Target)
+	default:
+		return fmt.Sprintf("volume %d, %d above target %d", c.Volume, drift, c.Target)
+	}
+}
+
+// CheckStartup compares the volume read at startup, or the error reading
+// it, with the target the first apply will use.
+func (s *SchedulerService) CheckStartup(state ScheduleState, config Config, volume int, err error, now time.Time) StartupCheck {
+	target, _ := s.ApplyFloor(config, s.ResolveTarget(state, config, now))
+	check := StartupCheck{At: now, Volume: volume, Target: target}
+	if err != nil {
+		check.Volume = 0
+		check.Error = s.RedactError(config, err).Error()
+	}
+	return check
+}
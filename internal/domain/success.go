@@ -0,0 +1,54 @@
+package domain
+
+import "fmt"
+
+// SuccessRateWindow is how many recent apply attempts the success rate
+// covers.
+const SuccessRateWindow = 50
+
+// SuccessRate is the share of successful applies among recent attempts.
+type SuccessRate struct {
+	Successes int
+	Attempts  int
+}
+
+// Percent returns the rate rounded down to a whole percent, so that a
+// single failure never shows as 100%.
+func (r SuccessRate) Percent() int {
+	if r.Attempts == 0 {
+		return 0
+	}
+	return r.Successes * 100 / r.Attempts
+}
+
+// String renders the rate with its window, e.g. "98% over last 50".
+func (r SuccessRate) String() string {
+	if r.Attempts == 0 {
+		return "no applies yet"
+	}
+	return fmt.Sprintf("%d%% over last %d", r.Percent(), r.Attempts)
+}
+
+// RecordResult adds the outcome of an apply to the recent results behind
+// the success rate, keeping only the last SuccessRateWindow. The slice is
+// copied, so snapshots taken earlier are unaffected.
+func (s *SchedulerService) RecordResult(state ScheduleState, ok bool) ScheduleState {
+	recent := append([]bool(nil), state.RecentResults...)
+	recent = append(recent, ok)
+	if len(recent) > SuccessRateWindow {
+		recent = recent[len(recent)-SuccessRateWindow:]
+	}
+	state.RecentResults = recent
+	return state
+}
+
+// SuccessRate summarizes the recent results in state.
+func (s *SchedulerService) SuccessRate(state ScheduleState) SuccessRate {
+	rate := SuccessRate{Attempts: len(state.RecentResults)}
+	for _, ok := range state.RecentResults {
+		if ok {
+			rate.Successes++
+		}
+	}
+	return rate
+}
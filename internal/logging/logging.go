@@ -1,9 +1,13 @@
 package logging
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Level represents logging severity.
@@ -17,9 +21,25 @@ const (
 	LevelTrace
 )
 
+// Format selects how log lines are written.
+type Format int
+
+const (
+	// FormatText writes "[LEVEL] message key=value ..." through the
+	// standard logger.
+	FormatText Format = iota
+	// FormatJSON writes one {"ts","level","msg",...fields} object per line,
+	// for log aggregators.
+	FormatJSON
+)
+
 var (
 	currentLevel     = LevelWarn
 	currentVerbosity = 0
+	currentFormat    = FormatText
+
+	// writeMu serializes JSON lines, which bypass the standard logger.
+	writeMu sync.Mutex
 )
 
 func init() {
@@ -93,35 +113,153 @@ func ParseLevel(s string) (Level, int, error) {
 	}
 }
 
+// SetFormat selects the log format: "text" (the default) or "json".
+func SetFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "text":
+		currentFormat = FormatText
+	case "json":
+		currentFormat = FormatJSON
+	default:
+		return fmt.Errorf("unknown log format %s", format)
+	}
+	return nil
+}
+
 func shouldLog(l Level) bool {
 	return l <= currentLevel
 }
 
-func logf(l Level, prefix, format string, args ...any) {
-	if !shouldLog(l) {
+// Logger logs with a fixed set of key/value fields. The zero Logger has
+// none; the package-level functions log through it.
+type Logger struct {
+	fields []any
+}
+
+// With returns a logger that attaches the given alternating keys and
+// values to every line, e.g. With("volume", 40, "trigger", trigger).
+func With(kv ...any) Logger {
+	return Logger{}.With(kv...)
+}
+
+// With returns a logger with kv added to l's fields.
+func (l Logger) With(kv ...any) Logger {
+	if len(kv)%2 != 0 {
+		kv = append(kv[:len(kv)-1:len(kv)-1], "!BADKEY", kv[len(kv)-1])
+	}
+	return Logger{fields: append(l.fields[:len(l.fields):len(l.fields)], kv...)}
+}
+
+// Errorf always prints.
+func (l Logger) Errorf(format string, args ...any) {
+	l.logf(LevelError, "err", format, args...)
+}
+
+func (l Logger) Warnf(format string, args ...any) {
+	l.logf(LevelWarn, "warn", format, args...)
+}
+
+func (l Logger) Infof(format string, args ...any) {
+	l.logf(LevelInfo, "info", format, args...)
+}
+
+func (l Logger) Debugf(format string, args ...any) {
+	l.logf(LevelDebug, "dbg", format, args...)
+}
+
+func (l Logger) Tracef(format string, args ...any) {
+	l.logf(LevelTrace, "trc", format, args...)
+}
+
+func (l Logger) logf(level Level, prefix, format string, args ...any) {
+	if !shouldLog(level) {
 		return
 	}
 	msg := fmt.Sprintf(format, args...)
-	log.Printf("[%s] %s", strings.ToUpper(prefix), msg)
+	if currentFormat == FormatJSON {
+		l.writeJSON(level, msg)
+		return
+	}
+	var b strings.Builder
+	for i := 0; i < len(l.fields); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", l.fields[i], textValue(l.fields[i+1]))
+	}
+	log.Printf("[%s] %s%s", strings.ToUpper(prefix), msg, b.String())
+}
+
+// writeJSON writes one line with ts, level and msg first, then the fields
+// in the order given.
+func (l Logger) writeJSON(level Level, msg string) {
+	var b bytes.Buffer
+	b.WriteString(`{"ts":`)
+	writeJSONValue(&b, time.Now().Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSONValue(&b, LevelToString(level))
+	b.WriteString(`,"msg":`)
+	writeJSONValue(&b, msg)
+	for i := 0; i < len(l.fields); i += 2 {
+		b.WriteByte(',')
+		writeJSONValue(&b, fmt.Sprint(l.fields[i]))
+		b.WriteByte(':')
+		writeJSONValue(&b, jsonValue(l.fields[i+1]))
+	}
+	b.WriteString("}\n")
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	_, _ = log.Writer().Write(b.Bytes())
+}
+
+func writeJSONValue(b *bytes.Buffer, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(data)
+}
+
+// jsonValue turns errors and Stringers (durations, enums) into their text,
+// which would otherwise marshal as {} or a bare number.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// textValue quotes values that would otherwise be hard to tell apart from
+// the next field.
+func textValue(v any) string {
+	s := fmt.Sprint(jsonValue(v))
+	if s == "" || strings.ContainsAny(s, " =\"") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
 }
 
 // Errorf always prints.
 func Errorf(format string, args ...any) {
-	logf(LevelError, "err", format, args...)
+	Logger{}.logf(LevelError, "err", format, args...)
 }
 
 func Warnf(format string, args ...any) {
-	logf(LevelWarn, "warn", format, args...)
+	Logger{}.logf(LevelWarn, "warn", format, args...)
 }
 
 func Infof(format string, args ...any) {
-	logf(LevelInfo, "info", format, args...)
+	Logger{}.logf(LevelInfo, "info", format, args...)
 }
 
 func Debugf(format string, args ...any) {
-	logf(LevelDebug, "dbg", format, args...)
+	Logger{}.logf(LevelDebug, "dbg", format, args...)
 }
 
 func Tracef(format string, args ...any) {
-	logf(LevelTrace, "trc", format, args...)
+	Logger{}.logf(LevelTrace, "trc", format, args...)
 }
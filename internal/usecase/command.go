@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+const effectRunCommand = "RunCommand"
+
+// WithCommandRunner runs the config's pre- and post-apply commands through
+// the given runner. Without one they are not run.
+func WithCommandRunner(runner domain.CommandRunner) Option {
+	return func(s *schedulerInteractor) {
+		s.commands = runner
+	}
+}
+
+// applyVolume sets the system level and the given app levels between the
+// pre- and post-apply commands, spaced by pace.
+func (s *schedulerInteractor) applyVolume(config domain.Config, volume int, apps []domain.AppVolume, trigger domain.ApplyTrigger, pace *stagger) (string, error) {
+	warning, err := s.preApply(config, volume, trigger)
+	if err == nil {
+		// The input is the first set, which the stagger never delays
+		pace.next()
+		var setWarning string
+		setWarning, err = s.setVolume(volume)
+		s.setAppVolumes(apps, pace)
+		warning = joinWarnings(warning, setWarning)
+	}
+	return joinWarnings(warning, s.postApply(config, volume, trigger, err)), err
+}
+
+// preApply runs the pre-apply command. Its failure is returned as an error
+// when it should abort the apply, and as a warning otherwise.
+func (s *schedulerInteractor) preApply(config domain.Config, volume int, trigger domain.ApplyTrigger) (string, error) {
+	err := s.runApplyCmd("pre-apply", config.PreApplyCmd, config, map[string]string{
+		"MICGAIN_VOLUME":  strconv.Itoa(volume),
+		"MICGAIN_TRIGGER": trigger.String(),
+	})
+	switch {
+	case err == nil:
+		return "", nil
+	case config.AbortOnPreApplyFailure:
+		return "", err
+	default:
+		logging.Warnf("%v; applying anyway", err)
+		return err.Error(), nil
+	}
+}
+
+// postApply runs the post-apply command with the outcome of the apply and
+// returns its failure as a warning.
+func (s *schedulerInteractor) postApply(config domain.Config, volume int, trigger domain.ApplyTrigger, applyErr error) string {
+	env := map[string]string{
+		"MICGAIN_VOLUME":  strconv.Itoa(volume),
+		"MICGAIN_TRIGGER": trigger.String(),
+		"MICGAIN_STATUS":  domain.StatusSuccess.String(),
+	}
+	if applyErr != nil {
+		env["MICGAIN_STATUS"] = domain.StatusError.String()
+		env["MICGAIN_ERROR"] = applyErr.Error()
+	}
+	if err := s.runApplyCmd("post-apply", config.PostApplyCmd, config, env); err != nil {
+		logging.Warnf("%v", err)
+		return err.Error()
+	}
+	return ""
+}
+
+// runApplyCmd runs one configured command, logging its output at debug level.
+func (s *schedulerInteractor) runApplyCmd(stage, command string, config domain.Config, env map[string]string) error {
+	if s.commands == nil || command == "" {
+		return nil
+	}
+	if s.isDryRun(config) {
+		logging.Infof("dry run: would run %s command: %s", stage, command)
+		return nil
+	}
+	timeout := config.ApplyCmdTimeout
+	if timeout <= 0 {
+		timeout = domain.DefaultApplyCmdTimeout
+	}
+
+	var output []byte
+	err := s.execEffect(effectRunCommand, map[string]any{"stage": stage, "command": command}, func() error {
+		var err error
+		output, err = s.commands.Run(command, env, timeout)
+		return err
+	})
+	if out := strings.TrimSpace(string(output)); out != "" {
+		logging.Debugf("%s command output: %s", stage, out)
+	}
+	if err != nil {
+		return fmt.Errorf("%s command failed: %w", stage, err)
+	}
+	return nil
+}
+
+// joinWarnings combines the warnings of one apply into a single message.
+func joinWarnings(warnings ...string) string {
+	var parts []string
+	for _, w := range warnings {
+		if w != "" {
+			parts = append(parts, w)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"time"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// WithDryRunController sets the controller volumes go to while dry run is
+// on, in place of the real one. It must not touch the OS. Without it dry
+// run still skips app volumes and apply commands, but sets the volume
+// through the real controller.
+func WithDryRunController(controller domain.VolumeController) Option {
+	return func(s *schedulerInteractor) {
+		s.dryRunController = controller
+	}
+}
+
+// WithDryRun simulates every apply of this process, as if the config set
+// DryRun, without saving it.
+func WithDryRun() Option {
+	return func(s *schedulerInteractor) {
+		s.dryRunForced = true
+	}
+}
+
+// isDryRun reports whether applies under config are simulated.
+func (s *schedulerInteractor) isDryRun(config domain.Config) bool {
+	return s.dryRunForced || config.DryRun
+}
+
+// volumes returns the controller that reads and sets the volume: the dry
+// run one while dry run is on. The caller must hold s.applyMu or s.mu,
+// which keep s.config from changing.
+func (s *schedulerInteractor) volumes() domain.VolumeController {
+	if s.dryRunController != nil && s.isDryRun(s.config) {
+		return s.dryRunController
+	}
+	return s.controller
+}
+
+// logDryRun logs what a simulated apply would have done, once its outcome
+// is recorded. The caller must hold s.mu.
+func (s *schedulerInteractor) logDryRun(volume int, trigger domain.ApplyTrigger) {
+	next := "none"
+	if !s.state.NextRun.IsZero() {
+		next = s.state.NextRun.Format(time.RFC3339)
+	}
+	logging.Infof("dry run: would set volume %d (trigger %s); next run %s", volume, trigger, next)
+}
@@ -0,0 +1,481 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// Effect types executed by the scheduler.
+const (
+	effectSetVolume     = "SetVolume"
+	effectGetVolume     = "GetVolume"
+	effectListDevices   = "ListInputDevices"
+	effectSetAppVolume  = "SetAppVolume"
+	effectReadNoise     = "ReadNoise"
+	effectReadPower     = "ReadPower"
+	effectSaveConfig    = "SaveConfig"
+	effectAppendHistory = "AppendHistory"
+)
+
+// WithEffectLog records every executed side effect to the given recorder.
+func WithEffectLog(recorder domain.EffectRecorder) Option {
+	return func(s *schedulerInteractor) {
+		s.effects = recorder
+	}
+}
+
+// execEffect runs a side effect, tracing it at debug level and to the
+// effect log when one is configured.
+func (s *schedulerInteractor) execEffect(kind string, params map[string]any, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	elapsed := time.Since(start)
+
+	if err != nil {
+		logging.Debugf("effect %s %v failed in %s: %v", kind, params, elapsed, err)
+	} else {
+		logging.Debugf("effect %s %v ok in %s", kind, params, elapsed)
+	}
+
+	if s.effects != nil {
+		record := domain.EffectRecord{
+			Timestamp: start,
+			Type:      kind,
+			Params:    params,
+			Duration:  elapsed,
+			Err:       err,
+		}
+		if recErr := s.effects.Record(record); recErr != nil {
+			logging.Warnf("record effect: %v", recErr)
+		}
+	}
+	return err
+}
+
+// WithStrictVolume reads the volume back after every apply and fails the
+// apply when it differs from the requested one by more than tolerance.
+func WithStrictVolume(tolerance int) Option {
+	return func(s *schedulerInteractor) {
+		s.strictVolume = true
+		s.volumeTolerance = tolerance
+	}
+}
+
+// setVolume applies the volume through the controller port, retrying a
+// failure up to config.MaxRetries times with a growing delay.
+// A domain.ApplyWarning from the controller is split off and returned as
+// a warning message so that the apply still counts as a success.
+// In strict volume mode the result is read back and checked.
+func (s *schedulerInteractor) setVolume(volume int) (string, error) {
+	device, err := s.targetDevice()
+	if err != nil {
+		return "", err
+	}
+	s.configureRamp()
+	params := map[string]any{"volume": volume}
+	if device != "" {
+		params["device"] = device
+	}
+	var warning string
+	if device != s.config.DeviceName {
+		warning = fmt.Sprintf("input device %q is not connected; applied to the default input", s.config.DeviceName)
+	}
+	set := func() error {
+		var err error
+		if device == "" {
+			err = s.volumes().SetVolume(volume)
+		} else if devices, ok := s.volumes().(domain.DeviceVolumeController); ok {
+			err = devices.SetDeviceVolume(device, volume)
+		} else {
+			err = s.noDeviceSupport(device)
+		}
+		var applyWarning *domain.ApplyWarning
+		if errors.As(err, &applyWarning) {
+			warning = joinWarnings(warning, applyWarning.Message)
+			return nil
+		}
+		return err
+	}
+	err = s.execEffect(effectSetVolume, params, set)
+	for retry := 1; err != nil && retry <= s.config.MaxRetries && !errors.Is(err, domain.ErrNotSupported); retry++ {
+		delay := s.config.RetryDelay(retry)
+		logging.Warnf("set volume %d failed, retry %d/%d in %s: %v", volume, retry, s.config.MaxRetries, delay, err)
+		if !sleepContext(s.ctx, delay) {
+			break
+		}
+		err = s.execEffect(effectSetVolume, params, set)
+	}
+	if warning != "" {
+		logging.Warnf("volume %d applied with warning: %s", volume, warning)
+	}
+	if err == nil && s.strictVolume {
+		err = s.verifyVolume(volume)
+	}
+	return warning, err
+}
+
+// targetDevice resolves the device setVolume and getVolume target: the
+// configured one unless Config.OnDeviceAbsent has a policy for when it is
+// missing from the controller's device list, in which case a missing device
+// resolves to the system default input ("") or ErrDeviceAbsent. Controllers
+// that cannot list devices always get the configured one. The caller must
+// hold s.applyMu.
+func (s *schedulerInteractor) targetDevice() (string, error) {
+	device := s.config.DeviceName
+	if device == "" || s.config.OnDeviceAbsent == domain.DeviceAbsentError {
+		return device, nil
+	}
+	devices, err := s.ListInputDevices()
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotSupported) {
+			logging.Debugf("list input devices: %v", err)
+		}
+		return device, nil
+	}
+	if domain.HasDevice(devices, device) {
+		return device, nil
+	}
+	if s.config.OnDeviceAbsent == domain.DeviceAbsentFallback {
+		return "", nil
+	}
+	return "", fmt.Errorf("%w: %q", domain.ErrDeviceAbsent, device)
+}
+
+// configureRamp passes the configured ramp to a controller that ramps
+// volume sets. The caller must hold s.applyMu, which keeps s.config from
+// changing.
+func (s *schedulerInteractor) configureRamp() {
+	if ramping, ok := s.volumes().(domain.RampController); ok {
+		ramping.SetRamp(s.config.Ramp())
+	}
+}
+
+// sleepContext waits for d and reports false when ctx ended first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stagger spaces the volume sets of one apply Config.StaggerBetweenDevices
+// apart on the scheduler's clock. The zero stagger never waits.
+type stagger struct {
+	s     *schedulerInteractor
+	every time.Duration
+	began bool
+}
+
+// newStagger starts the stagger of an apply. The caller must hold
+// s.applyMu, which guards s.ctx.
+func (s *schedulerInteractor) newStagger(config domain.Config) *stagger {
+	return &stagger{s: s, every: config.StaggerBetweenDevices}
+}
+
+// next waits before every set but the first and reports false when the
+// loop shuts down meanwhile, in which case the set is skipped.
+func (p *stagger) next() bool {
+	if !p.began || p.every <= 0 {
+		p.began = true
+		return true
+	}
+	ticker := p.s.clock.NewTicker(p.every)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C():
+		return true
+	case <-p.s.ctx.Done():
+		return false
+	}
+}
+
+// noDeviceSupport is the error for a device name the controller cannot
+// target.
+func (s *schedulerInteractor) noDeviceSupport(device string) error {
+	return fmt.Errorf("%w: %s cannot select input device %q", domain.ErrNotSupported, describeController(s.volumes()), device)
+}
+
+// describeController names the volume controller for the apply plan.
+// Controllers describe themselves through fmt.Stringer.
+func describeController(controller domain.VolumeController) string {
+	if named, ok := controller.(fmt.Stringer); ok {
+		return named.String()
+	}
+	return fmt.Sprintf("%T", controller)
+}
+
+// verifyVolume checks the read-back volume for strict volume mode.
+func (s *schedulerInteractor) verifyVolume(volume int) error {
+	achieved, err := s.getVolume()
+	if err != nil {
+		return fmt.Errorf("strict volume: read back: %w", err)
+	}
+	return s.service.CheckQuantization(volume, achieved, s.volumeTolerance)
+}
+
+// WithAppVolumes enforces the config's per-application input levels on
+// each scheduled apply through the given controller.
+func WithAppVolumes(controller domain.AppVolumeController) Option {
+	return func(s *schedulerInteractor) {
+		s.apps = controller
+		s.unsupportedApps = make(map[string]bool)
+	}
+}
+
+// setAppVolumes applies every app rule. Failures only warn, since the
+// system level is what the apply status reports; an app that cannot
+// script its input gain is warned about once and then skipped.
+// The caller must hold s.applyMu, which guards unsupportedApps.
+func (s *schedulerInteractor) setAppVolumes(rules []domain.AppVolume, pace *stagger) {
+	if s.apps == nil {
+		return
+	}
+	if s.isDryRun(s.config) {
+		for _, rule := range rules {
+			logging.Infof("dry run: would set app volume %s=%d", rule.App, rule.Volume)
+		}
+		return
+	}
+	for _, rule := range rules {
+		if s.unsupportedApps[rule.App] {
+			continue
+		}
+		if !pace.next() {
+			return
+		}
+		err := s.execEffect(effectSetAppVolume, map[string]any{"app": rule.App, "volume": rule.Volume}, func() error {
+			return s.apps.SetAppVolume(rule.App, rule.Volume)
+		})
+		switch {
+		case errors.Is(err, domain.ErrNotSupported):
+			s.unsupportedApps[rule.App] = true
+			logging.Warnf("%v; ignoring its app volume rule", err)
+		case err != nil:
+			logging.Warnf("set app volume for %s: %v", rule.App, err)
+		}
+	}
+}
+
+// setOutputVolume enforces the config's output lock through a controller
+// that can set the output volume. A failure only warns, since the input is
+// what the apply status reports; a controller without output support is
+// warned about once and then skipped.
+// The caller must hold s.applyMu, which guards outputUnsupported.
+func (s *schedulerInteractor) setOutputVolume(lock domain.OutputLock, pace *stagger) string {
+	if !lock.Enabled || s.outputUnsupported {
+		return ""
+	}
+	kinds, ok := s.volumes().(domain.KindVolumeController)
+	if !ok {
+		s.outputUnsupported = true
+		logging.Warnf("%v: %s cannot set the output volume; ignoring the output lock", domain.ErrNotSupported, describeController(s.volumes()))
+		return ""
+	}
+	if !pace.next() {
+		return ""
+	}
+
+	s.configureRamp()
+	params := map[string]any{"kind": domain.VolumeOutput.String()}
+	var observed int
+	err := s.execEffect(effectGetVolume, params, func() error {
+		var err error
+		observed, err = kinds.GetKindVolume(domain.VolumeOutput)
+		return err
+	})
+	if err == nil && observed != lock.Volume {
+		logging.Infof("output volume had drifted to %d, target %d", observed, lock.Volume)
+	}
+
+	params = map[string]any{"kind": domain.VolumeOutput.String(), "volume": lock.Volume}
+	err = s.execEffect(effectSetVolume, params, func() error {
+		return kinds.SetKindVolume(domain.VolumeOutput, lock.Volume)
+	})
+	var applyWarning *domain.ApplyWarning
+	switch {
+	case err == nil:
+		if s.isDryRun(s.config) {
+			logging.Infof("dry run: would set output volume %d", lock.Volume)
+		}
+		return ""
+	case errors.As(err, &applyWarning):
+		logging.Warnf("output volume %d applied with warning: %s", lock.Volume, applyWarning.Message)
+		return "output volume: " + applyWarning.Message
+	case errors.Is(err, domain.ErrNotSupported):
+		s.outputUnsupported = true
+		logging.Warnf("%v; ignoring the output lock", err)
+		return ""
+	default:
+		logging.Warnf("set output volume %d: %v", lock.Volume, err)
+		return fmt.Sprintf("set output volume %d: %v", lock.Volume, err)
+	}
+}
+
+// WithNoiseSensor feeds the noise adaptive target, when the config enables
+// it, with readings from the given sensor. Without a sensor the target
+// stays fixed.
+func WithNoiseSensor(sensor domain.NoiseSensor) Option {
+	return func(s *schedulerInteractor) {
+		s.noise = sensor
+	}
+}
+
+// readNoise takes a reading for the noise adaptive target. It reports
+// false when the config does not use one or the sensor failed, in which
+// case the target falls back to the fixed one.
+func (s *schedulerInteractor) readNoise(config domain.Config) (float64, bool) {
+	if !config.Noise.Enabled || s.noise == nil {
+		return 0, false
+	}
+	var level float64
+	err := s.execEffect(effectReadNoise, nil, func() error {
+		var err error
+		level, err = s.noise.Level()
+		return err
+	})
+	if err != nil {
+		logging.Warnf("read noise level: %v; using the fixed target", err)
+		return 0, false
+	}
+	return level, true
+}
+
+// readSampleRate reads the sample rate of the input device applies target,
+// the configured one or else the system default input, when config has
+// format volumes to pick by it. It returns zero when the rate is unknown.
+func (s *schedulerInteractor) readSampleRate(config domain.Config) int {
+	if len(config.FormatVolumes) == 0 {
+		return 0
+	}
+	devices, err := s.ListInputDevices()
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotSupported) {
+			logging.Warnf("list input devices for the sample rate: %v", err)
+		}
+		return 0
+	}
+	for _, d := range devices {
+		if d.Name == config.DeviceName || config.DeviceName == "" && d.Default {
+			return d.SampleRate
+		}
+	}
+	return 0
+}
+
+// getVolume reads the current volume back through the controller port.
+// Like setVolume it targets the configured device; both run under
+// s.applyMu, which keeps s.config from changing.
+func (s *schedulerInteractor) getVolume() (int, error) {
+	device, err := s.targetDevice()
+	if err != nil {
+		return 0, err
+	}
+	var params map[string]any
+	if device != "" {
+		params = map[string]any{"device": device}
+	}
+	var volume int
+	err = s.execEffect(effectGetVolume, params, func() error {
+		var err error
+		if device == "" {
+			volume, err = s.volumes().GetVolume()
+		} else if devices, ok := s.volumes().(domain.DeviceVolumeController); ok {
+			volume, err = devices.GetDeviceVolume(device)
+		} else {
+			err = s.noDeviceSupport(device)
+		}
+		return err
+	})
+	return volume, err
+}
+
+// ListInputDevices lists the input devices the controller can see.
+func (s *schedulerInteractor) ListInputDevices() ([]domain.AudioDevice, error) {
+	var devices []domain.AudioDevice
+	err := s.execEffect(effectListDevices, nil, func() error {
+		var err error
+		devices, err = s.controller.ListInputDevices()
+		return err
+	})
+	return devices, err
+}
+
+// save persists config and state through the repository port, and
+// publishes them to subscribers, since every change goes through here.
+func (s *schedulerInteractor) save(config domain.Config, state domain.ScheduleState) error {
+	params := map[string]any{
+		"targetVolume": config.TargetVolume,
+		"interval":     config.Interval.String(),
+		"enabled":      config.Enabled,
+		"status":       state.LastApplyStatus.String(),
+	}
+	state = s.service.RedactState(state, config)
+	defer s.publish(domain.Snapshot{Config: config, ScheduleState: state, DryRun: s.isDryRun(config)})
+	return s.execEffect(effectSaveConfig, params, func() error {
+		return s.repo.Save(config, state)
+	})
+}
+
+// checkStartup reads the volume back once before the first apply and logs
+// how far it is from the target, so a broken controller shows up at
+// startup instead of at the first tick. It returns nil for controllers
+// that cannot read the volume.
+func (s *schedulerInteractor) checkStartup() *domain.StartupCheck {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	volume, err := s.getVolume()
+	if errors.Is(err, domain.ErrNotSupported) {
+		logging.Infof("startup check skipped: controller cannot read the volume")
+		return nil
+	}
+
+	s.mu.RLock()
+	check := s.service.CheckStartup(s.state, s.config, volume, err, s.clock.Now())
+	s.mu.RUnlock()
+	switch {
+	case check.Failed():
+		logging.Errorf("startup check: could not read the volume, the controller may not work: %s", check.Error)
+	case check.Drift() == 0:
+		logging.Infof("startup check: volume %d matches the target, no enforcement needed", check.Volume)
+	default:
+		logging.Infof("startup check: volume %d, target %d; enforcement needed (%+d)", check.Volume, check.Target, -check.Drift())
+	}
+	return &check
+}
+
+// checkLatency times one volume read and set, writing back the volume just
+// read, and warns when the interval is not comfortably longer than that.
+// It is advisory only; controllers that cannot read the volume skip it.
+func (s *schedulerInteractor) checkLatency() {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	start := time.Now()
+	volume, err := s.getVolume()
+	if err == nil {
+		_, err = s.setVolume(volume)
+	}
+	if err != nil {
+		logging.Debugf("latency check skipped: %v", err)
+		return
+	}
+	latency := time.Since(start)
+
+	s.mu.RLock()
+	interval := s.service.EffectiveInterval(s.state, s.config)
+	s.mu.RUnlock()
+	if safe := s.service.SafeMinInterval(latency); interval < safe {
+		logging.Warnf("interval %s is under %dx the %s one volume read and set took; applies may pile up, use at least %s",
+			interval, domain.IntervalLatencyFactor, latency.Round(time.Millisecond), safe)
+	}
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/clock"
+	"micgain-manager/internal/domain"
+)
+
+// setLog records every volume set as "what@offset", the offset being the
+// fake clock's time since testStart.
+type setLog struct {
+	mu    sync.Mutex
+	clock *clock.Fake
+	sets  []string
+}
+
+func (l *setLog) add(what string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sets = append(l.sets, fmt.Sprintf("%s@%s", what, l.clock.Now().Sub(testStart)))
+}
+
+// waitFor waits until n sets are recorded. The stagger stops its ticker
+// before each set, so the next running ticker is the next wait.
+func (l *setLog) waitFor(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(l.get()) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d sets, have %v", n, l.get())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (l *setLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.sets)
+}
+
+// loggingController sets the input and the output into a setLog.
+type loggingController struct {
+	fakeController
+	log *setLog
+}
+
+func (c *loggingController) SetVolume(volume int) error {
+	c.log.add(fmt.Sprintf("input=%d", volume))
+	return c.fakeController.SetVolume(volume)
+}
+
+func (c *loggingController) SetKindVolume(kind domain.VolumeKind, volume int) error {
+	c.log.add(fmt.Sprintf("%s=%d", kind, volume))
+	return nil
+}
+
+func (c *loggingController) GetKindVolume(domain.VolumeKind) (int, error) {
+	return 0, nil
+}
+
+// loggingApps sets app volumes into a setLog.
+type loggingApps struct {
+	log *setLog
+}
+
+func (a *loggingApps) SetAppVolume(app string, volume int) error {
+	a.log.add(fmt.Sprintf("%s=%d", app, volume))
+	return nil
+}
+
+func newStaggerScheduler(t *testing.T) (*schedulerInteractor, *clock.Fake, *setLog) {
+	t.Helper()
+	config := testConfig()
+	config.StaggerBetweenDevices = 2 * time.Second
+	config.AppVolumes = []domain.AppVolume{{App: "zoom.us", Volume: 70}, {App: "Discord", Volume: 60}}
+	config.Output = domain.OutputLock{Enabled: true, Volume: 30}
+	log := &setLog{}
+	controller := &loggingController{log: log}
+	s, _, fake := newTestScheduler(t, config, domain.ScheduleState{}, controller, WithAppVolumes(&loggingApps{log: log}))
+	log.clock = fake
+	return s, fake, log
+}
+
+func TestStaggerBetweenDevices(t *testing.T) {
+	s, fake, log := newStaggerScheduler(t)
+
+	done := make(chan bool)
+	go func() { done <- s.tick(fake.Now()) }()
+	// Each wait is the only ticker running, as no loop runs
+	for i := 1; i <= 3; i++ {
+		log.waitFor(t, i)
+		fake.BlockUntil(1)
+		fake.Advance(2 * time.Second)
+	}
+	select {
+	case applied := <-done:
+		if !applied {
+			t.Fatal("tick did not apply")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tick did not finish")
+	}
+
+	want := []string{"input=50@0s", "zoom.us=70@2s", "Discord=60@4s", "output=30@6s"}
+	if got := log.get(); !slices.Equal(got, want) {
+		t.Errorf("sets = %v, want %v", got, want)
+	}
+}
+
+func TestStaggerEndsOnShutdown(t *testing.T) {
+	s, fake, log := newStaggerScheduler(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+
+	done := make(chan struct{})
+	go func() {
+		s.tick(fake.Now())
+		close(done)
+	}()
+	log.waitFor(t, 1)
+	fake.BlockUntil(1)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stagger did not end on shutdown")
+	}
+
+	want := []string{"input=50@0s"}
+	if got := log.get(); !slices.Equal(got, want) {
+		t.Errorf("sets = %v, want only the input %v", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"sync"
+
+	"micgain-manager/internal/domain"
+)
+
+// Subscribe returns a channel that receives a snapshot whenever the state
+// is saved, and a function that ends the subscription. A subscriber that
+// falls behind only gets the latest snapshot, so publishing never blocks
+// the scheduler.
+func (s *schedulerInteractor) Subscribe() (<-chan domain.Snapshot, func()) {
+	ch := make(chan domain.Snapshot, 1)
+	s.subMu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[chan domain.Snapshot]struct{})
+	}
+	s.subs[ch] = struct{}{}
+	s.subMu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.subMu.Lock()
+			delete(s.subs, ch)
+			close(ch)
+			s.subMu.Unlock()
+		})
+	}
+}
+
+// publish hands snap to every subscriber, replacing any snapshot still
+// waiting in its buffer, which counts as dropped.
+func (s *schedulerInteractor) publish(snap domain.Snapshot) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for ch := range s.subs {
+		select {
+		case <-ch:
+			s.dropped.Add(1)
+		default:
+		}
+		ch <- snap
+	}
+}
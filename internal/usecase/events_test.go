@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"micgain-manager/internal/domain"
+)
+
+func TestPublishDropsForStalledSubscriber(t *testing.T) {
+	controller := &fakeController{}
+	s, _, fake := newTestScheduler(t, testConfig(), domain.ScheduleState{}, controller)
+	stalled, cancel := s.Subscribe()
+	defer cancel()
+
+	// The subscriber never reads while the scheduler keeps applying on
+	// schedule
+	const ticks = 5
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < ticks; i++ {
+			if !s.tick(fake.Now()) {
+				t.Errorf("tick %d did not apply", i+1)
+				return
+			}
+			fake.Advance(91 * time.Second)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("applies blocked on a subscriber that does not read")
+	}
+
+	if got := controller.setCount(); got != ticks {
+		t.Errorf("controller called %d times, want %d", got, ticks)
+	}
+	snap := s.GetSnapshot()
+	if snap.DroppedEvents == 0 {
+		t.Error("no dropped events counted")
+	}
+	// What is left in the buffer is the latest state
+	latest := <-stalled
+	if !latest.ScheduleState.LastApplied.Equal(snap.ScheduleState.LastApplied) {
+		t.Errorf("subscriber holds the apply of %v, want the latest at %v",
+			latest.ScheduleState.LastApplied, snap.ScheduleState.LastApplied)
+	}
+}
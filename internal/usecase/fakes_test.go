@@ -0,0 +1,262 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/clock"
+	"micgain-manager/internal/domain"
+)
+
+// testStart is where the fake clock of every test starts, a Monday.
+var testStart = time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+// testConfig is the default config, read in UTC so that tests do not
+// depend on the zone they run in.
+func testConfig() domain.Config {
+	config := domain.DefaultConfig()
+	config.Timezone = "UTC"
+	return config
+}
+
+// memRepo is a ConfigRepository that keeps the config and state in memory.
+type memRepo struct {
+	mu     sync.Mutex
+	config domain.Config
+	state  domain.ScheduleState
+	saves  int
+}
+
+func (r *memRepo) Load() (domain.Config, domain.ScheduleState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config, r.state, nil
+}
+
+func (r *memRepo) Save(config domain.Config, state domain.ScheduleState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config, r.state = config, state
+	r.saves++
+	return nil
+}
+
+func (r *memRepo) saved() (domain.Config, domain.ScheduleState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config, r.state
+}
+
+// fakeController is a VolumeController that records the volumes set. While
+// block is set, each SetVolume reports itself on entered and then waits for
+// block to be closed.
+type fakeController struct {
+	mu      sync.Mutex
+	volume  int
+	sets    []int
+	devices []domain.AudioDevice
+	block   chan struct{}
+	entered chan int
+}
+
+func (c *fakeController) SetVolume(volume int) error {
+	c.mu.Lock()
+	block, entered := c.block, c.entered
+	c.mu.Unlock()
+	if block != nil {
+		entered <- volume
+		<-block
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.volume = volume
+	c.sets = append(c.sets, volume)
+	return nil
+}
+
+func (c *fakeController) GetVolume() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.volume, nil
+}
+
+func (c *fakeController) ListInputDevices() ([]domain.AudioDevice, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.devices == nil {
+		return nil, domain.ErrNotSupported
+	}
+	return c.devices, nil
+}
+
+// blockSets makes every following SetVolume wait until the returned
+// function is called.
+func (c *fakeController) blockSets() (entered <-chan int, release func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.block = make(chan struct{})
+	c.entered = make(chan int, 16)
+	block := c.block
+	var once sync.Once
+	return c.entered, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			c.block = nil
+			c.mu.Unlock()
+			close(block)
+		})
+	}
+}
+
+func (c *fakeController) setCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sets)
+}
+
+// memHistory is a HistoryRepository that keeps the records in memory and
+// reports each one on appended.
+type memHistory struct {
+	mu       sync.Mutex
+	records  []domain.ApplyRecord
+	appended chan domain.ApplyRecord
+}
+
+func newMemHistory() *memHistory {
+	return &memHistory{appended: make(chan domain.ApplyRecord, 64)}
+}
+
+func (h *memHistory) Append(record domain.ApplyRecord) error {
+	h.mu.Lock()
+	h.records = append(h.records, record)
+	h.mu.Unlock()
+	select {
+	case h.appended <- record:
+	default:
+	}
+	return nil
+}
+
+func (h *memHistory) Query(q domain.HistoryQuery) ([]domain.ApplyRecord, int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var matched []domain.ApplyRecord
+	for i := len(h.records) - 1; i >= 0; i-- {
+		if q.Matches(h.records[i]) {
+			matched = append(matched, h.records[i])
+		}
+	}
+	return matched, len(matched), nil
+}
+
+// count returns how many records have trigger.
+func (h *memHistory) count(trigger domain.ApplyTrigger) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, r := range h.records {
+		if r.Trigger == trigger {
+			n++
+		}
+	}
+	return n
+}
+
+// next waits for the next appended record.
+func (h *memHistory) next(t *testing.T) domain.ApplyRecord {
+	t.Helper()
+	select {
+	case record := <-h.appended:
+		return record
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an apply to be recorded")
+		return domain.ApplyRecord{}
+	}
+}
+
+// newTestScheduler returns a scheduler on a fake clock over config, with
+// the given state loaded.
+func newTestScheduler(t *testing.T, config domain.Config, state domain.ScheduleState, controller domain.VolumeController, opts ...Option) (*schedulerInteractor, *memRepo, *clock.Fake) {
+	t.Helper()
+	fake := clock.NewFake(testStart)
+	repo := &memRepo{config: config, state: state}
+	uc, err := NewSchedulerUseCase(repo, controller, append([]Option{WithClock(fake)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewSchedulerUseCase: %v", err)
+	}
+	return uc.(*schedulerInteractor), repo, fake
+}
+
+// deviceController is a fakeController that can also target the listed
+// devices, recording each device set as "name=volume". Other devices fail
+// as not found.
+type deviceController struct {
+	fakeController
+	deviceSets []string
+}
+
+func (c *deviceController) SetDeviceVolume(device string, volume int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !domain.HasDevice(c.devices, device) {
+		return fmt.Errorf("input device %q not found", device)
+	}
+	c.deviceSets = append(c.deviceSets, fmt.Sprintf("%s=%d", device, volume))
+	return nil
+}
+
+func (c *deviceController) GetDeviceVolume(string) (int, error) {
+	return c.GetVolume()
+}
+
+// startLoop starts the scheduler loop and waits until it runs its ticker
+// and sleep check. The loop stops when the test ends.
+func startLoop(t *testing.T, s *schedulerInteractor, fake *clock.Fake) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s.Start(ctx)
+	fake.BlockUntil(2)
+}
+
+// advance moves the fake clock forward by d one sleep check period at a
+// time, giving the loop a moment after each, so that it never takes the
+// jump for a system sleep.
+func advance(fake *clock.Fake, d time.Duration) {
+	for d > 0 {
+		step := min(d, sleepCheckPeriod)
+		fake.Advance(step)
+		d -= step
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// nextApply advances the fake clock step by step until the loop records an
+// apply, and fails after limit.
+func nextApply(t *testing.T, fake *clock.Fake, history *memHistory, step, limit time.Duration) domain.ApplyRecord {
+	t.Helper()
+	for moved := time.Duration(0); moved < limit; moved += step {
+		advance(fake, step)
+		select {
+		case record := <-history.appended:
+			return record
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	t.Fatalf("no apply within %s", limit)
+	return domain.ApplyRecord{}
+}
+
+// noApply fails if the loop records an apply shortly.
+func noApply(t *testing.T, history *memHistory) {
+	t.Helper()
+	select {
+	case record := <-history.appended:
+		t.Fatalf("unexpected apply at %s", record.Timestamp.Sub(testStart))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"time"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// DefaultTickHookTimeout bounds how long a tick waits for its hook.
+const DefaultTickHookTimeout = 5 * time.Second
+
+// TickHook runs before each scheduled apply with the state as of that tick.
+// Returning false skips the apply until the next interval.
+//
+// It is an extension point for programs that embed the scheduler, e.g. to
+// forward every tick to another system or to veto applies while a meeting
+// app is recording. Manual applies, holds and config changes do not run it.
+type TickHook func(snapshot domain.Snapshot) (proceed bool)
+
+// WithTickHook runs hook before each scheduled apply. A hook that panics or
+// does not return within timeout (DefaultTickHookTimeout when zero) lets
+// the apply proceed, so a broken hook cannot stop the volume from being
+// enforced.
+func WithTickHook(hook TickHook, timeout time.Duration) Option {
+	if timeout <= 0 {
+		timeout = DefaultTickHookTimeout
+	}
+	return func(s *schedulerInteractor) {
+		s.tickHook = hook
+		s.tickHookTimeout = timeout
+	}
+}
+
+// runTickHook reports whether the scheduled apply should proceed.
+// Must be called without holding s.mu, since the hook may be slow.
+func (s *schedulerInteractor) runTickHook(snapshot domain.Snapshot) bool {
+	if s.tickHook == nil {
+		return true
+	}
+	// A hook still stuck in an earlier tick is not called again, so that
+	// timed out calls cannot pile up
+	if !s.tickHookBusy.CompareAndSwap(false, true) {
+		logging.Warnf("tick hook is still running from an earlier tick; applying without it")
+		return true
+	}
+
+	result := make(chan bool, 1)
+	go func() {
+		defer s.tickHookBusy.Store(false)
+		defer func() {
+			if r := recover(); r != nil {
+				logging.Warnf("tick hook panicked: %v; applying anyway", r)
+				result <- true
+			}
+		}()
+		result <- s.tickHook(snapshot)
+	}()
+
+	timer := time.NewTimer(s.tickHookTimeout)
+	defer timer.Stop()
+	select {
+	case proceed := <-result:
+		if !proceed {
+			logging.Infof("tick hook skipped the scheduled apply")
+		}
+		return proceed
+	case <-timer.C:
+		logging.Warnf("tick hook did not return within %s; applying anyway", s.tickHookTimeout)
+		return true
+	}
+}
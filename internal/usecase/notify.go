@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// DefaultNotifyDebounce is the least time between two notifications.
+const DefaultNotifyDebounce = 5 * time.Minute
+
+// notifyTitle titles every notification.
+const notifyTitle = "micgain-manager"
+
+// WithNotifier alerts through notifier when the reported apply status turns
+// to error and when applies succeed again. Notifications come at most once
+// per debounce (DefaultNotifyDebounce when zero); a change within it is
+// announced by the first apply after it if it still holds, so an apply
+// that keeps flapping does not send one notification per flip.
+func WithNotifier(notifier domain.Notifier, debounce time.Duration) Option {
+	if debounce <= 0 {
+		debounce = DefaultNotifyDebounce
+	}
+	return func(s *schedulerInteractor) {
+		s.notifier = notifier
+		s.notifyDebounce = debounce
+	}
+}
+
+// notifyStatus notifies of a failure or a recovery that the last apply
+// brought and that was not announced yet. The caller must hold s.mu.
+func (s *schedulerInteractor) notifyStatus(volume int, config domain.Config) {
+	if s.notifier == nil {
+		return
+	}
+	failing := s.service.ReportedStatus(s.state, config) == domain.StatusError
+	var message string
+	switch {
+	case failing && !s.notifiedFailing:
+		err := s.service.RedactError(config, s.state.LastError)
+		message = fmt.Sprintf("音量 %d の適用に失敗しています (連続 %d 回): %s",
+			volume, s.state.ConsecutiveFailures, strings.TrimSpace(fmt.Sprint(err)))
+	case s.state.LastApplyStatus == domain.StatusSuccess && s.notifiedFailing:
+		message = fmt.Sprintf("音量 %d の適用が回復しました", volume)
+	default:
+		return
+	}
+
+	now := s.clock.Now()
+	if !s.notifiedAt.IsZero() && now.Sub(s.notifiedAt) < s.notifyDebounce {
+		logging.Debugf("notification held back until %s: %s", s.notifiedAt.Add(s.notifyDebounce).Format(time.RFC3339), message)
+		return
+	}
+	s.notifiedFailing = failing
+	s.notifiedAt = now
+
+	// Posting may be slow, and must not hold up the scheduler
+	go func() {
+		if err := s.notifier.Notify(notifyTitle, message); err != nil {
+			logging.Warnf("notify: %v", err)
+		}
+	}()
+}
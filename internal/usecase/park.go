@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"time"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// park leaves the volume at config.ParkVolume after the scheduler was
+// disabled, fading there when config.FadeOnPark is set. Parking is a
+// handoff rather than an apply, so it is logged but not recorded in the
+// state or the history. Failures only warn.
+func (s *schedulerInteractor) park(config domain.Config) {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	target := *config.ParkVolume
+	current := -1
+	if config.FadeOnPark {
+		if v, err := s.getVolume(); err != nil {
+			logging.Warnf("park: read volume, setting %d at once: %v", target, err)
+		} else {
+			current = v
+		}
+	}
+
+	steps := s.service.ParkSteps(current, target, config.FadeOnPark)
+	pause := domain.ParkFadeDuration / time.Duration(max(len(steps)-1, 1))
+	for i, volume := range steps {
+		if i > 0 {
+			time.Sleep(pause)
+		}
+		if _, err := s.setVolume(volume); err != nil {
+			logging.Warnf("park at %d: %v", target, err)
+			return
+		}
+	}
+	logging.Infof("scheduler disabled; volume parked at %d", target)
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// WithPowerSource lets the scheduler loop reapply the target when the power
+// source changes, if the config enables ReapplyOnPowerChange.
+func WithPowerSource(source domain.PowerSource) Option {
+	return func(s *schedulerInteractor) {
+		s.power = source
+	}
+}
+
+// watchPower polls the power source alongside the scheduler loop and
+// reapplies on every change. It gives up for good where the source cannot
+// be read, so the setting is a no-op there.
+func (s *schedulerInteractor) watchPower(ctx context.Context) {
+	last := ""
+	for {
+		s.mu.RLock()
+		config := s.config
+		s.mu.RUnlock()
+
+		if !config.ReapplyOnPowerChange {
+			// Start afresh when re-enabled rather than compare to a stale reading
+			last = ""
+		} else {
+			source, err := s.readPower()
+			switch {
+			case errors.Is(err, domain.ErrNotSupported):
+				logging.Infof("power source cannot be read on this system; reapplyOnPowerChange has no effect")
+				return
+			case err != nil:
+				logging.Warnf("read power source: %v", err)
+			case last != "" && source != last:
+				logging.Infof("power source changed from %s to %s; reapplying", last, source)
+				s.reapply(domain.TriggerPower)
+				last = source
+			default:
+				last = source
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(config.PowerPoll()):
+		}
+	}
+}
+
+func (s *schedulerInteractor) readPower() (string, error) {
+	var source string
+	err := s.execEffect(effectReadPower, nil, func() error {
+		var err error
+		source, err = s.power.Source()
+		return err
+	})
+	return source, err
+}
+
+// reapply applies the target, or the held volume, for an event outside
+// the schedule. Nothing is applied while neither the scheduler nor a hold
+// is enforcing a volume.
+func (s *schedulerInteractor) reapply(trigger domain.ApplyTrigger) {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.service.CheckEnabled(s.state, s.config); err != nil {
+		logging.Infof("%s reapply skipped: %v", trigger, err)
+		return
+	}
+	if until := s.config.QuietUntil(s.clock.Now()); !until.IsZero() {
+		logging.Infof("%s reapply skipped: quiet hours until %s", trigger, until.Format("15:04"))
+		return
+	}
+	volume, err := s.manualVolume(-1)
+	if err == nil {
+		err = s.applyLocked(volume, trigger)
+	}
+	if err != nil {
+		logging.Warnf("%s reapply failed: %v", trigger, err)
+	}
+}
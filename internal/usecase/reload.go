@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+// WithConfigReload makes the scheduler loop reload the config whenever it
+// is changed outside this process, e.g. edited by hand, where the
+// repository can tell (domain.ConfigWatcher).
+func WithConfigReload() Option {
+	return func(s *schedulerInteractor) {
+		s.reloadConfig = true
+	}
+}
+
+// watchConfig reloads the config on every change the repository reports,
+// until ctx ends.
+func (s *schedulerInteractor) watchConfig(ctx context.Context) {
+	watcher, ok := s.repo.(domain.ConfigWatcher)
+	if !ok {
+		logging.Warnf("the config store cannot be watched; outside changes need a restart")
+		return
+	}
+	changes, err := watcher.Watch(ctx)
+	if err != nil {
+		logging.Warnf("%v; outside changes need a restart", err)
+		return
+	}
+	for range changes {
+		s.reload()
+	}
+}
+
+// reload loads the config and takes it through UpdateConfig, like a change
+// made through this process, unless it is what is already running. A
+// config that does not load or validate is reported and left unused.
+// The lock is taken from the layers too, so a system layer that sets or
+// lifts it takes effect; the lock guards changes made through this
+// process, not edits to the files.
+func (s *schedulerInteractor) reload() {
+	config, _, err := s.repo.Load()
+	if err != nil {
+		logging.Warnf("config file changed but cannot be loaded, keeping the current config: %v", err)
+		return
+	}
+	normalized, err := s.service.ValidateAndNormalize(config)
+	if err != nil {
+		logging.Warnf("config file changed but is invalid, keeping the current config: %v", err)
+		return
+	}
+
+	s.mu.RLock()
+	current := s.config
+	s.mu.RUnlock()
+	normalized.Locked = normalized.Locked || s.lockForced
+	if reflect.DeepEqual(normalized, current) {
+		logging.Debugf("config file changed without changing the config")
+		return
+	}
+
+	if err := s.updateConfig(normalized, false, true); err != nil {
+		logging.Warnf("config file changed but cannot be reloaded: %v", err)
+		return
+	}
+	if normalized.Locked != current.Locked {
+		if normalized.Locked {
+			logging.Infof("config locked by the system config")
+		} else {
+			logging.Infof("config unlocked by the system config")
+		}
+	}
+	changed := s.service.RestartRequired(domain.ScheduleState{Running: &current}, normalized)
+	if len(changed) == 0 {
+		logging.Infof("config reloaded from file")
+		return
+	}
+	logging.Infof("config reloaded from file: %s changed", strings.Join(changed, ", "))
+}
@@ -2,10 +2,14 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
 )
 
 // SchedulerUseCase is the primary port for scheduler operations.
@@ -14,7 +18,47 @@ type SchedulerUseCase interface {
 	Start(ctx context.Context)
 	GetSnapshot() domain.Snapshot
 	ApplyNow(volume int) error
+	ApplyIfEnabled(volume int) error
+	PlanApply(volume int) (domain.ApplyPlan, error)
 	UpdateConfig(config domain.Config, applyNow bool) error
+	SimulateConfig(config domain.Config) (domain.ConfigSimulation, error)
+	UseProfile(name string, applyNow bool) error
+	Hold(volume int) error
+	Release() error
+	ResetState() error
+	RestartRequired() ([]string, error)
+	QueryHistory(q domain.HistoryQuery) ([]domain.ApplyRecord, int, error)
+	PreviewTargets(horizon, step time.Duration) []domain.TargetPoint
+	ExplainApply() domain.ApplyDecision
+	ListInputDevices() ([]domain.AudioDevice, error)
+	Subscribe() (<-chan domain.Snapshot, func())
+}
+
+// Option configures optional dependencies of the scheduler use case.
+type Option func(*schedulerInteractor)
+
+// WithHistory records every apply attempt to the given history repository.
+func WithHistory(history domain.HistoryRepository) Option {
+	return func(s *schedulerInteractor) {
+		s.history = history
+	}
+}
+
+// WithClock runs the scheduler on clock instead of the system clock, so
+// that tests can drive its timing.
+func WithClock(clock domain.Clock) Option {
+	return func(s *schedulerInteractor) {
+		s.clock = clock
+	}
+}
+
+// WithConfigLocked locks the config for this process, as if the system
+// config layer had set "locked", whatever a reload reads from the layers.
+func WithConfigLocked() Option {
+	return func(s *schedulerInteractor) {
+		s.config.Locked = true
+		s.lockForced = true
+	}
 }
 
 // schedulerInteractor implements SchedulerUseCase.
@@ -22,11 +66,61 @@ type SchedulerUseCase interface {
 type schedulerInteractor struct {
 	repo       domain.ConfigRepository
 	controller domain.VolumeController
+	apps       domain.AppVolumeController
+	commands   domain.CommandRunner
+	noise      domain.NoiseSensor
+	power      domain.PowerSource
+	history    domain.HistoryRepository
+	effects    domain.EffectRecorder
 	service    *domain.SchedulerService
+	clock      domain.Clock
+
+	strictVolume    bool
+	volumeTolerance int
+
+	tickHook        TickHook
+	tickHookTimeout time.Duration
+	tickHookBusy    atomic.Bool
+
+	unsupportedApps   map[string]bool
+	outputUnsupported bool
+
+	dryRunController domain.VolumeController
+	dryRunForced     bool
+
+	notifier       domain.Notifier
+	notifyDebounce time.Duration
+	// notifiedFailing and notifiedAt record the last notification sent,
+	// guarded by mu.
+	notifiedFailing bool
+	notifiedAt      time.Time
 
-	mu     sync.RWMutex
-	config domain.Config
-	state  domain.ScheduleState
+	reloadConfig bool
+	// lockForced keeps the config locked through reloads, for
+	// WithConfigLocked.
+	lockForced bool
+	// wake tells the loop that the config or the hold changed, so a new
+	// interval or schedule takes effect without waiting out the old one
+	// and the ticker stops or restarts as enforcement ends or begins.
+	wake chan struct{}
+
+	// subs are the Subscribe channels, guarded by subMu alone so that
+	// publishing works under either of the locks below.
+	subMu sync.Mutex
+	subs  map[chan domain.Snapshot]struct{}
+	// dropped counts the snapshots publish replaced unread.
+	dropped atomic.Uint64
+
+	// applyMu serializes applies with everything that changes what they
+	// would apply. Lock it before mu.
+	applyMu sync.Mutex
+	// ctx is the scheduler loop's context once started, so that waits
+	// within an apply end on shutdown. Guarded by applyMu.
+	ctx     context.Context
+	mu      sync.RWMutex
+	config  domain.Config
+	state   domain.ScheduleState
+	running bool
 }
 
 // NewSchedulerUseCase creates a new scheduler use case.
@@ -34,6 +128,7 @@ type schedulerInteractor struct {
 func NewSchedulerUseCase(
 	repo domain.ConfigRepository,
 	controller domain.VolumeController,
+	opts ...Option,
 ) (SchedulerUseCase, error) {
 	service := domain.NewSchedulerService()
 
@@ -43,72 +138,313 @@ func NewSchedulerUseCase(
 		return nil, err
 	}
 
+	// An allowlist added by another layer must not make the saved target
+	// unloadable, or there would be no way left to fix it
+	if err := config.CheckAllowed(config.TargetVolume); err != nil {
+		nearest := config.NearestAllowed(config.TargetVolume)
+		logging.Warnf("%v; using %d", err, nearest)
+		config.TargetVolume = nearest
+	}
+
 	// Validate and normalize
 	config, err = service.ValidateAndNormalize(config)
 	if err != nil {
 		return nil, err
 	}
 
-	return &schedulerInteractor{
+	s := &schedulerInteractor{
 		repo:       repo,
 		controller: controller,
 		service:    service,
 		config:     config,
 		state:      state,
-	}, nil
+		ctx:        context.Background(),
+		clock:      domain.SystemClock{},
+
+		wake: make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.service = domain.NewSchedulerService(domain.WithClock(s.clock))
+	s.seedResults()
+	return s, nil
+}
+
+// seedResults loads the recent apply outcomes from the history, so the
+// success rate survives restarts.
+func (s *schedulerInteractor) seedResults() {
+	if s.history == nil {
+		return
+	}
+	records, _, err := s.history.Query(domain.HistoryQuery{Limit: domain.SuccessRateWindow})
+	if err != nil {
+		logging.Warnf("read history for the success rate: %v", err)
+		return
+	}
+	// Records come newest first
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Status != domain.StatusWaitingDevice {
+			s.state = s.service.RecordResult(s.state, records[i].Status == domain.StatusSuccess)
+		}
+	}
 }
 
 // Start begins the scheduler loop.
+// The running config is persisted so that other processes can tell when
+// their saved changes have not reached this loop, together with the
+// volume read back before the first apply.
 func (s *schedulerInteractor) Start(ctx context.Context) {
+	s.applyMu.Lock()
+	s.ctx = ctx
+	s.applyMu.Unlock()
+	check := s.checkStartup()
+
+	s.mu.Lock()
+	s.running = true
+	s.state = s.service.StartLoop(s.state)
+	s.state.Running = runningConfig(s.config)
+	s.state.StartupCheck = check
+	_ = s.save(s.config, s.state)
+	s.mu.Unlock()
+
 	go s.loop(ctx)
+	if s.power != nil {
+		go s.watchPower(ctx)
+	}
+	if s.reloadConfig {
+		go s.watchConfig(ctx)
+	}
 }
 
-func (s *schedulerInteractor) loop(ctx context.Context) {
-	s.mu.RLock()
-	interval := s.config.Interval
-	s.mu.RUnlock()
+// stop clears the persisted running config when the loop exits.
+func (s *schedulerInteractor) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.state.Running = nil
+	s.state.StartupCheck = nil
+	_ = s.save(s.config, s.state)
+}
 
-	ticker := time.NewTicker(interval)
+func runningConfig(config domain.Config) *domain.Config {
+	return &config
+}
+
+func (s *schedulerInteractor) loop(ctx context.Context) {
+	s.checkLatency()
+	interval, period := s.tickPeriod()
+	ticker := s.clock.NewTicker(period)
 	defer ticker.Stop()
+	defer s.stop()
+
+	sleepCheck := s.clock.NewTicker(sleepCheckPeriod)
+	defer sleepCheck.Stop()
+	lastCheck := s.clock.Now()
+
+	// The tickers stop while nothing is enforced, e.g. with the scheduler
+	// disabled and no hold, and restart when a change enforces something
+	paused := false
+	pauseIfIdle := func() {
+		if !paused && !s.enforcing() {
+			ticker.Stop()
+			sleepCheck.Stop()
+			paused = true
+			logging.Infof("nothing to enforce; scheduler paused")
+		}
+	}
+	pauseIfIdle()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			s.mu.Lock()
-			now := time.Now()
-
-			if s.service.ShouldApply(s.state, s.config, now) {
-				// Mark as running
-				s.state = s.service.StartRunning(s.state)
-				volume := s.config.TargetVolume
-				config := s.config
-				s.mu.Unlock()
-
-				// Execute side effect through secondary port
-				err := s.controller.SetVolume(volume)
-
-				s.mu.Lock()
-				if err != nil {
-					s.state = s.service.ApplyFailure(s.state, config, err, now)
-				} else {
-					s.state = s.service.ApplySuccess(s.state, config, now)
+		case <-ticker.C():
+			now := s.clock.Now()
+			// After a wake this tick may come before the sleep check; the
+			// reapply moves the next run, so the tick below does not repeat it
+			if s.wokeFromSleep(lastCheck, now) {
+				lastCheck = now
+			}
+			// The ticker keeps one tick buffered while we apply, so a
+			// slow apply would otherwise be followed by another at once
+			if s.tick(now) {
+				if elapsed := s.clock.Now().Sub(now); elapsed >= interval {
+					select {
+					case <-ticker.C():
+					default:
+					}
+					logging.Warnf("apply took %s, longer than the %s interval; skipping the missed tick (interval may be too short)",
+						elapsed.Round(time.Millisecond), interval)
 				}
-				// Persist state
-				_ = s.repo.Save(s.config, s.state)
+			}
 
-				// Update ticker if interval changed
-				if s.config.Interval != interval {
-					interval = s.config.Interval
-					ticker.Reset(interval)
-				}
-				s.mu.Unlock()
-			} else {
-				s.mu.Unlock()
+			// Update ticker if interval changed
+			var next time.Duration
+			interval, next = s.tickPeriod()
+			if next != period {
+				period = next
+				ticker.Reset(period)
 			}
+			pauseIfIdle()
+		case <-sleepCheck.C():
+			now := s.clock.Now()
+			if s.wokeFromSleep(lastCheck, now) {
+				interval, period = s.tickPeriod()
+				ticker.Reset(period)
+				pauseIfIdle()
+			}
+			lastCheck = now
+		case <-s.wake:
+			interval, period = s.tickPeriod()
+			if !s.enforcing() {
+				pauseIfIdle()
+				continue
+			}
+			if paused {
+				paused = false
+				sleepCheck.Reset(sleepCheckPeriod)
+				lastCheck = s.clock.Now()
+				logging.Infof("scheduler resumed")
+			}
+			ticker.Reset(period)
+		}
+	}
+}
+
+// wakeLoop tells the loop to pick up a change; it never blocks.
+func (s *schedulerInteractor) wakeLoop() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enforcing reports whether scheduled ticks have anything to enforce.
+func (s *schedulerInteractor) enforcing() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.service.Enforcing(s.state, s.config)
+}
+
+// tickPeriod returns the effective interval and how long the ticker should
+// wait. In fixed schedule mode or with a cron schedule the ticker is aimed
+// just past a next run within the interval instead, so runs land on it
+// rather than up to an interval late.
+func (s *schedulerInteractor) tickPeriod() (interval, period time.Duration) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	interval = s.service.EffectiveInterval(s.state, s.config)
+	wallClock := s.config.ScheduleMode == domain.ScheduleFixed || s.config.Schedule != ""
+	if wallClock && !s.state.NextRun.IsZero() {
+		if wait := s.state.NextRun.Sub(s.clock.Now()); wait > 0 && wait <= interval {
+			// ShouldApply wants now strictly after NextRun
+			return interval, wait + 10*time.Millisecond
 		}
 	}
+	return interval, interval
+}
+
+// tick runs one scheduled apply when one is due and reports whether it did.
+// The volume is set outside s.mu so snapshots stay readable meanwhile, but
+// under s.applyMu, so no config update, hold or manual apply can interleave
+// and the result is always recorded against the state that produced it.
+func (s *schedulerInteractor) tick(now time.Time) bool {
+	s.mu.RLock()
+	due := s.service.ShouldApply(s.state, s.config, now)
+	snapshot := domain.Snapshot{Config: s.config, ScheduleState: s.state, DryRun: s.isDryRun(s.config)}
+	s.mu.RUnlock()
+	if !due {
+		return false
+	}
+
+	if !s.runTickHook(snapshot) {
+		s.mu.Lock()
+		s.state = s.service.SkipApply(s.state, s.config, now)
+		_ = s.save(s.config, s.state)
+		s.mu.Unlock()
+		return false
+	}
+
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	// Read the sensor and the sample rate outside s.mu; s.applyMu keeps
+	// the config current
+	s.mu.RLock()
+	current := s.config
+	s.mu.RUnlock()
+	level, sensed := s.readNoise(current)
+	rate := s.readSampleRate(current)
+
+	s.mu.Lock()
+	// A config update or hold may have landed while the hook ran
+	if !s.service.ShouldApply(s.state, s.config, now) {
+		s.mu.Unlock()
+		return false
+	}
+	// Only the output lock is enforced: set the output and wait for the
+	// next run, leaving the input apply status alone
+	if s.service.CheckEnabled(s.state, s.config) != nil {
+		config := s.config
+		s.mu.Unlock()
+		s.setOutputVolume(config.Output, s.newStagger(config))
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.state = s.service.SkipApply(s.state, config, now)
+		_ = s.save(s.config, s.state)
+		return true
+	}
+	// Mark as running
+	s.state = s.service.StartRunning(s.state)
+	s.state.SampleRate = rate
+	config := s.config
+	if sensed {
+		s.state = s.service.NudgeForNoise(s.state, config, level, now)
+	} else {
+		s.state.Ambient = domain.Ambient{}
+	}
+	volume := s.floorVolume(config, s.service.ResolveTarget(s.state, config, now))
+	s.mu.Unlock()
+
+	// Read back first to tell whether anything changed the volume since
+	// the last tick, for the adaptive interval and the drift alert
+	stable := false
+	observed := -1
+	if v, err := s.getVolume(); err == nil {
+		observed = v
+		stable = v == volume
+	} else if !errors.Is(err, domain.ErrNotSupported) {
+		logging.Debugf("read back before apply: %v", err)
+	}
+
+	// Execute side effect through secondary port
+	// The stagger spaces the input, app and output sets
+	pace := s.newStagger(config)
+	warning, err := s.applyVolume(config, volume, config.AppVolumes, domain.TriggerScheduled, pace)
+	warning = joinWarnings(warning, s.setOutputVolume(config.Output, pace))
+
+	drift := false
+	if err == nil && observed >= 0 && s.service.SignificantDrift(config, observed, volume) {
+		drift = true
+		msg := fmt.Sprintf("significant drift corrected: observed %d, target %d", observed, volume)
+		logging.Warnf("%s", msg)
+		warning = joinWarnings(msg, warning)
+	} else if observed >= 0 && observed != volume {
+		logging.Infof("volume had drifted to %d, target %d", observed, volume)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if config.AdaptiveInterval {
+		s.state = s.service.AdaptInterval(s.state, config, stable)
+	}
+	if observed >= 0 {
+		s.state = s.service.ObserveVolume(s.state, observed)
+	}
+	s.finishApply(volume, config, warning, err, now, domain.TriggerScheduled, observed, drift)
+	return true
 }
 
 // GetSnapshot returns the current system state.
@@ -117,64 +453,422 @@ func (s *schedulerInteractor) GetSnapshot() domain.Snapshot {
 	defer s.mu.RUnlock()
 	return domain.Snapshot{
 		Config:        s.config,
-		ScheduleState: s.state,
+		ScheduleState: s.service.RedactState(s.state, s.config),
+		DryRun:        s.isDryRun(s.config),
+		DroppedEvents: s.dropped.Load(),
 	}
 }
 
 // ApplyNow immediately applies the specified volume.
+// While a hold is active only the held volume may be applied.
 func (s *schedulerInteractor) ApplyNow(volume int) error {
+	return s.applyNow(volume, domain.TriggerManual)
+}
+
+func (s *schedulerInteractor) applyNow(volume int, trigger domain.ApplyTrigger) error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	volume, err := s.manualVolume(volume)
+	if err != nil {
+		return err
+	}
+	return s.applyLocked(volume, trigger)
+}
+
+// manualVolume resolves the volume a manual apply of volume sets before the
+// floor, where -1 means the current target, and fails where the apply must
+// not happen. The caller must hold s.mu.
+func (s *schedulerInteractor) manualVolume(volume int) (int, error) {
+	// Re-applying the configured target is fine, overriding it is a change
+	if volume >= 0 {
+		if err := s.service.CheckMutable(s.config); err != nil {
+			return 0, err
+		}
+	}
+
+	if s.state.Hold.Active {
+		if volume >= 0 && volume != s.state.Hold.Volume {
+			return 0, domain.ErrVolumeHeld
+		}
+		volume = s.state.Hold.Volume
+	}
+
 	// Use current config volume if negative
 	if volume < 0 {
-		volume = s.config.TargetVolume
+		volume = s.service.ResolveTarget(s.state, s.config, s.clock.Now())
 	}
 
 	// Validate volume
-	if volume < 0 || volume > 100 {
-		return domain.ErrInvalidVolume
+	if err := domain.ValidateVolume(volume); err != nil {
+		return 0, err
+	}
+	if err := s.config.CheckAllowed(volume); err != nil {
+		return 0, err
 	}
+	return volume, nil
+}
 
-	now := time.Now()
+// PlanApply returns what ApplyNow(volume) would do, without doing any of
+// it. It fails where ApplyNow would fail before touching the volume.
+func (s *schedulerInteractor) PlanApply(volume int) (domain.ApplyPlan, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resolved, err := s.manualVolume(volume)
+	if err != nil {
+		return domain.ApplyPlan{}, err
+	}
+	source := "requested"
+	if volume < 0 || s.state.Hold.Active {
+		source = s.service.TargetSource(s.state, s.config)
+	}
+	target, raised := s.service.ApplyFloor(s.config, resolved)
+
+	plan := domain.ApplyPlan{
+		Requested:  volume,
+		Source:     source,
+		Volume:     target,
+		Raised:     raised,
+		Controller: describeController(s.volumes()),
+		Device:     s.config.DeviceName,
+		Verify:     s.strictVolume,
+		Enabled:    s.service.CheckEnabled(s.state, s.config) == nil,
+	}
+	if plan.Verify {
+		plan.Tolerance = s.volumeTolerance
+	}
+	if _, ok := s.volumes().(domain.RampController); ok {
+		plan.Ramp = s.config.Ramp()
+	}
+	if s.commands != nil {
+		plan.PreApplyCmd = s.config.PreApplyCmd
+		plan.PostApplyCmd = s.config.PostApplyCmd
+		plan.AbortOnPreApplyFailure = s.config.AbortOnPreApplyFailure && s.config.PreApplyCmd != ""
+	}
+	return plan, nil
+}
+
+// ApplyIfEnabled behaves like ApplyNow but returns domain.ErrNotEnabled
+// instead of applying while the scheduler is disabled.
+func (s *schedulerInteractor) ApplyIfEnabled(volume int) error {
+	s.mu.RLock()
+	err := s.service.CheckEnabled(s.state, s.config)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return s.ApplyNow(volume)
+}
+
+// applyLocked executes the volume change and records the outcome.
+// The caller must hold s.applyMu and s.mu. Like tick it releases s.mu
+// while the volume is set, which with retries, a ramp and the apply
+// commands can take a while, so snapshots stay readable meanwhile;
+// s.applyMu keeps the config and the hold from changing.
+func (s *schedulerInteractor) applyLocked(volume int, trigger domain.ApplyTrigger) error {
+	now := s.clock.Now()
+	config := s.config
+	volume = s.floorVolume(config, volume)
 	s.state = s.service.StartRunning(s.state)
+	s.mu.Unlock()
 
 	// Execute side effect
-	err := s.controller.SetVolume(volume)
+	warning, err := s.applyVolume(config, volume, nil, trigger, s.newStagger(config))
 
-	if err != nil {
-		s.state = s.service.ApplyFailure(s.state, s.config, err, now)
-	} else {
-		s.state = s.service.ApplySuccess(s.state, s.config, now)
+	s.mu.Lock()
+	s.finishApply(volume, config, warning, err, now, trigger, -1, false)
+	return s.service.RedactError(config, err)
+}
+
+// floorVolume enforces the configured minimum volume on every apply path.
+func (s *schedulerInteractor) floorVolume(config domain.Config, volume int) int {
+	floored, raised := s.service.ApplyFloor(config, volume)
+	if raised {
+		logging.Infof("volume %d is below the floor; applying %d instead", volume, floored)
 	}
+	return floored
+}
 
-	// Persist state
-	_ = s.repo.Save(s.config, s.state)
+// finishApply records the outcome of an apply in the state, on disk and in
+// the history. observed is the volume read back before the apply, or -1,
+// and drift marks a significant drift corrected from it. The caller must
+// hold s.mu.
+func (s *schedulerInteractor) finishApply(volume int, config domain.Config, warning string, err error, at time.Time, trigger domain.ApplyTrigger, observed int, drift bool) {
+	elapsed := s.clock.Now().Sub(at)
+	log := logging.With("volume", volume, "trigger", trigger)
+	next := s.state.NextRun
+	switch {
+	case errors.Is(err, domain.ErrDeviceAbsent):
+		// Neither a success nor a failure, for the success rate as well
+		log.Infof("%v; waiting for it", err)
+		s.state = s.service.WaitForDevice(s.state, config, at)
+	case err != nil:
+		s.state = s.service.RecordResult(s.state, false)
+		if config.RedactErrors {
+			// The detail stays in the in-memory state and this log only;
+			// save, GetSnapshot and recordHistory redact it
+			log.Warnf("apply failed: %v", err)
+		} else {
+			// The caller, the state and the history report it already
+			log.Infof("apply failed: %v", err)
+		}
+		s.state = s.service.ApplyFailure(s.state, config, err, at)
+	default:
+		s.state = s.service.RecordResult(s.state, true)
+		log.With("elapsed", elapsed.Round(time.Millisecond)).Infof("volume applied")
+		s.state = s.service.ApplySuccess(s.state, config, at)
+		if warning != "" {
+			s.state = s.service.RecordWarning(s.state, warning)
+		}
+	}
+	s.state = s.service.KeepSchedule(s.state, config, trigger, next)
+
+	s.state.LastApplyDryRun = s.isDryRun(config)
+	if trigger == domain.TriggerWake {
+		s.state.LastWakeApply = at
+	}
 
-	return err
+	// Persist state
+	_ = s.save(s.config, s.state)
+	s.recordHistory(volume, warning, err, at, elapsed, trigger, observed, drift)
+	s.notifyStatus(volume, config)
+	if s.state.LastApplyDryRun {
+		s.logDryRun(volume, trigger)
+	}
 }
 
 // UpdateConfig updates the configuration and optionally applies immediately.
+// While a hold is active the new config is saved but not applied until Release.
 func (s *schedulerInteractor) UpdateConfig(config domain.Config, applyNow bool) error {
+	return s.updateConfig(config, applyNow, false)
+}
+
+// updateConfig is UpdateConfig. A reloaded config comes from the
+// repository's layers, which decide Locked and may have set or lifted it;
+// any other update keeps the current lock and is refused while it is set.
+func (s *schedulerInteractor) updateConfig(config domain.Config, applyNow, reloaded bool) error {
 	// Validate through domain service
 	config, err := s.service.ValidateAndNormalize(config)
 	if err != nil {
 		return err
 	}
 
+	// Wait for an in-flight scheduled apply, so its result is recorded
+	// against the config that produced it
+	s.applyMu.Lock()
 	s.mu.Lock()
+	if reloaded {
+		config.Locked = config.Locked || s.lockForced
+	} else {
+		if err := s.service.CheckMutable(s.config); err != nil {
+			s.mu.Unlock()
+			s.applyMu.Unlock()
+			return err
+		}
+		config.Locked = s.config.Locked
+	}
+	park := s.service.ShouldPark(s.config, config)
 	s.config = config
-	s.state.NextRun = s.service.CalculateNextRun(time.Now(), config.Interval)
-	s.mu.Unlock()
+	if s.running {
+		s.state.Running = runningConfig(config)
+	}
+	s.state = s.service.ConfigChanged(s.state, config, s.clock.Now())
+	held := s.state.Hold.Active
 
 	// Persist
-	if err := s.repo.Save(config, s.state); err != nil {
+	err = s.save(config, s.state)
+	s.mu.Unlock()
+	s.applyMu.Unlock()
+	if err != nil {
 		return err
 	}
+	s.wakeLoop()
+	logging.With("volume", config.TargetVolume, "interval", config.Interval, "enabled", config.Enabled).Infof("config updated")
+
+	if held {
+		logging.Infof("config saved; volume is held so changes take effect after unlock")
+		return nil
+	}
 
 	if applyNow {
-		return s.ApplyNow(config.TargetVolume)
+		if err := s.applyNow(-1, domain.TriggerConfig); err != nil {
+			return err
+		}
+	}
+	if park {
+		s.park(config)
 	}
 
 	return nil
 }
+
+// SimulateConfig returns what UpdateConfig would save for config, without
+// saving or applying it. It fails where UpdateConfig would.
+func (s *schedulerInteractor) SimulateConfig(config domain.Config) (domain.ConfigSimulation, error) {
+	config, err := s.service.ValidateAndNormalize(config)
+	if err != nil {
+		return domain.ConfigSimulation{}, err
+	}
+
+	s.mu.RLock()
+	current, state, running := s.config, s.state, s.running
+	s.mu.RUnlock()
+	if err := s.service.CheckMutable(current); err != nil {
+		return domain.ConfigSimulation{}, err
+	}
+	config.Locked = current.Locked
+	if running {
+		state.Running = runningConfig(config)
+	}
+
+	sim := s.service.SimulateUpdate(state, config, s.clock.Now())
+	sim.Snapshot.ScheduleState = s.service.RedactState(sim.Snapshot.ScheduleState, config)
+	return sim, nil
+}
+
+// UseProfile switches to the named profile through the normal update path,
+// so the profile's scheduler settings and next run take effect immediately.
+func (s *schedulerInteractor) UseProfile(name string, applyNow bool) error {
+	s.mu.RLock()
+	config := s.config
+	s.mu.RUnlock()
+
+	profile, ok := config.Profile(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrProfileNotFound, name)
+	}
+	logging.Infof("switching to profile %q", name)
+	return s.UpdateConfig(config.WithProfile(profile), applyNow)
+}
+
+// Hold applies the volume and keeps enforcing it until Release is called.
+func (s *schedulerInteractor) Hold(volume int) error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.service.CheckMutable(s.config); err != nil {
+		return err
+	}
+	if err := s.config.CheckAllowed(volume); err != nil {
+		return err
+	}
+	state, err := s.service.StartHold(s.state, volume, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	s.state = state
+	logging.Infof("holding volume at %d", volume)
+	s.wakeLoop()
+
+	return s.applyLocked(volume, domain.TriggerLock)
+}
+
+// Release clears the hold and re-applies the configured target when enabled.
+func (s *schedulerInteractor) Release() error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.service.CheckMutable(s.config); err != nil {
+		return err
+	}
+	if !s.state.Hold.Active {
+		return domain.ErrNotHeld
+	}
+	s.state = s.service.ReleaseHold(s.state)
+	logging.Infof("volume hold released")
+	s.wakeLoop()
+
+	if !s.config.Enabled {
+		return s.save(s.config, s.state)
+	}
+	return s.applyLocked(s.service.ResolveTarget(s.state, s.config, s.clock.Now()), domain.TriggerUnlock)
+}
+
+// ResetState clears a lingering error or warning and the failure backoff,
+// leaving the config untouched.
+func (s *schedulerInteractor) ResetState() error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = s.service.ClearStatus(s.state, s.config)
+	logging.Infof("apply status cleared")
+	return s.save(s.config, s.state)
+}
+
+// PreviewTargets returns the resolved target volume over the coming horizon.
+func (s *schedulerInteractor) PreviewTargets(horizon, step time.Duration) []domain.TargetPoint {
+	s.mu.RLock()
+	config := s.config
+	s.mu.RUnlock()
+	return s.service.PreviewTargets(config, s.clock.Now(), horizon, step)
+}
+
+// ExplainApply traces whether a tick arriving now would apply, and why.
+func (s *schedulerInteractor) ExplainApply() domain.ApplyDecision {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.service.ExplainApply(s.state, s.config, s.clock.Now())
+}
+
+// RestartRequired lists the saved settings that the running scheduler loop,
+// possibly in another process, has not picked up.
+func (s *schedulerInteractor) RestartRequired() ([]string, error) {
+	config, state, err := s.repo.Load()
+	if err != nil {
+		return nil, err
+	}
+	return s.service.RestartRequired(state, config), nil
+}
+
+// QueryHistory returns a page of the apply history.
+func (s *schedulerInteractor) QueryHistory(q domain.HistoryQuery) ([]domain.ApplyRecord, int, error) {
+	if s.history == nil {
+		return nil, 0, nil
+	}
+	return s.history.Query(q)
+}
+
+// recordHistory appends an apply attempt to the history, if configured.
+// observed is the volume read back before the apply, or -1.
+func (s *schedulerInteractor) recordHistory(volume int, warning string, err error, at time.Time, elapsed time.Duration, trigger domain.ApplyTrigger, observed int, drift bool) {
+	if s.history == nil {
+		return
+	}
+	record := domain.ApplyRecord{
+		Timestamp: at,
+		Volume:    volume,
+		Status:    domain.StatusSuccess,
+		Warning:   warning,
+		Trigger:   trigger,
+		Duration:  elapsed,
+	}
+	if err != nil {
+		record.Status = domain.StatusError
+		if errors.Is(err, domain.ErrDeviceAbsent) {
+			record.Status = domain.StatusWaitingDevice
+		}
+		record.Error = err.Error()
+	}
+	if observed >= 0 {
+		record.Observed = &observed
+	}
+	record.SignificantDrift = drift
+	record.DryRun = s.state.LastApplyDryRun
+	params := map[string]any{"volume": volume, "status": record.Status.String(), "trigger": trigger.String()}
+	// Like the state, the record is redacted only where it leaves the process
+	err = s.execEffect(effectAppendHistory, params, func() error {
+		return s.history.Append(s.service.RedactRecord(record, s.config))
+	})
+	if err != nil {
+		logging.Warnf("record history: %v", err)
+	}
+}
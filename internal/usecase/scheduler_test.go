@@ -0,0 +1,450 @@
+package usecase
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"micgain-manager/internal/clock"
+	"micgain-manager/internal/domain"
+)
+
+func TestManualApplyReschedule(t *testing.T) {
+	tests := []struct {
+		name       string
+		reschedule bool
+		wantNext   time.Time
+		wantTick   bool
+	}{
+		{"reschedules by default", true, testStart.Add(150 * time.Second), false},
+		{"keeps the schedule", false, testStart.Add(90 * time.Second), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.RescheduleOnManualApply = tt.reschedule
+			controller := &fakeController{}
+			s, _, fake := newTestScheduler(t, config, domain.ScheduleState{}, controller)
+
+			if !s.tick(fake.Now()) {
+				t.Fatal("first tick did not apply")
+			}
+			fake.Advance(60 * time.Second)
+			if err := s.ApplyNow(-1); err != nil {
+				t.Fatalf("ApplyNow: %v", err)
+			}
+			if got := s.GetSnapshot().ScheduleState.NextRun; !got.Equal(tt.wantNext) {
+				t.Errorf("NextRun after manual apply = %v, want %v", got, tt.wantNext)
+			}
+
+			fake.Advance(31 * time.Second)
+			if got := s.tick(fake.Now()); got != tt.wantTick {
+				t.Errorf("tick at T+91s applied = %v, want %v", got, tt.wantTick)
+			}
+			if want := 2 + btoi(tt.wantTick); controller.setCount() != want {
+				t.Errorf("controller called %d times, want %d", controller.setCount(), want)
+			}
+		})
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestDeviceAbsent(t *testing.T) {
+	tests := []struct {
+		policy       domain.DeviceAbsentPolicy
+		wantStatus   domain.ApplyStatus
+		wantFailures int
+		wantSets     int
+		// wantWait is how long after the second tick the next run is
+		wantWait time.Duration
+	}{
+		{domain.DeviceAbsentError, domain.StatusError, 2, 0, 180 * time.Second},
+		{domain.DeviceAbsentSkip, domain.StatusWaitingDevice, 0, 0, 90 * time.Second},
+		{domain.DeviceAbsentFallback, domain.StatusSuccess, 0, 2, 90 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			config := testConfig()
+			config.DeviceName = "USB"
+			config.OnDeviceAbsent = tt.policy
+			controller := &deviceController{}
+			controller.devices = []domain.AudioDevice{{Name: "Built-in", Default: true}}
+			history := newMemHistory()
+			s, _, fake := newTestScheduler(t, config, domain.ScheduleState{}, controller, WithHistory(history))
+
+			// Two ticks, so a failure backoff would show after the second
+			for i := 1; i <= 2; i++ {
+				if next := s.GetSnapshot().ScheduleState.NextRun; !next.IsZero() {
+					fake.Advance(next.Sub(fake.Now()) + time.Second)
+				}
+				if !s.tick(fake.Now()) {
+					t.Fatalf("tick %d did not run", i)
+				}
+			}
+
+			state := s.GetSnapshot().ScheduleState
+			if state.LastApplyStatus != tt.wantStatus {
+				t.Errorf("status = %s, want %s", state.LastApplyStatus, tt.wantStatus)
+			}
+			if state.ConsecutiveFailures != tt.wantFailures {
+				t.Errorf("consecutive failures = %d, want %d", state.ConsecutiveFailures, tt.wantFailures)
+			}
+			if got := state.NextRun.Sub(fake.Now()); got != tt.wantWait {
+				t.Errorf("next run in %s, want %s", got, tt.wantWait)
+			}
+			if got := controller.setCount(); got != tt.wantSets {
+				t.Errorf("default input set %d times, want %d", got, tt.wantSets)
+			}
+			if len(controller.deviceSets) != 0 {
+				t.Errorf("absent device set: %v", controller.deviceSets)
+			}
+			if got := history.count(domain.TriggerScheduled); got != 2 {
+				t.Errorf("%d scheduled applies recorded, want 2", got)
+			}
+			if r := history.records[1]; r.Status != tt.wantStatus {
+				t.Errorf("history status = %s, want %s", r.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDevicePresent(t *testing.T) {
+	config := testConfig()
+	config.DeviceName = "USB"
+	config.OnDeviceAbsent = domain.DeviceAbsentSkip
+	controller := &deviceController{}
+	controller.devices = []domain.AudioDevice{{Name: "Built-in", Default: true}, {Name: "USB"}}
+	s, _, fake := newTestScheduler(t, config, domain.ScheduleState{}, controller)
+
+	if !s.tick(fake.Now()) {
+		t.Fatal("tick did not run")
+	}
+	if got := s.GetSnapshot().ScheduleState.LastApplyStatus; got != domain.StatusSuccess {
+		t.Errorf("status = %s, want ok", got)
+	}
+	if len(controller.deviceSets) != 1 || controller.deviceSets[0] != "USB=50" {
+		t.Errorf("device sets = %v, want [USB=50]", controller.deviceSets)
+	}
+}
+
+func TestFormatVolumes(t *testing.T) {
+	tests := []struct {
+		name string
+		rate int
+		want int
+	}{
+		{"matching rate", 48000, 55},
+		{"other rate", 44100, 50},
+		{"unknown rate", 0, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.FormatVolumes = []domain.FormatVolume{{SampleRate: 48000, Volume: 55}}
+			controller := &fakeController{}
+			controller.devices = []domain.AudioDevice{
+				{Name: "USB", SampleRate: 48000},
+				{Name: "Built-in", Default: true, SampleRate: tt.rate},
+			}
+			s, _, fake := newTestScheduler(t, config, domain.ScheduleState{}, controller)
+
+			if !s.tick(fake.Now()) {
+				t.Fatal("tick did not apply")
+			}
+			if got, _ := controller.GetVolume(); got != tt.want {
+				t.Errorf("applied %d, want %d", got, tt.want)
+			}
+			if got := s.GetSnapshot().ScheduleState.SampleRate; got != tt.rate {
+				t.Errorf("sample rate = %d, want %d", got, tt.rate)
+			}
+		})
+	}
+}
+
+func TestSlowApplyDoesNotStall(t *testing.T) {
+	controller := &fakeController{}
+	s, _, fake := newTestScheduler(t, testConfig(), domain.ScheduleState{}, controller)
+	entered, release := controller.blockSets()
+	defer release()
+
+	applied := make(chan bool)
+	go func() { applied <- s.tick(fake.Now()) }()
+	<-entered
+
+	// The apply holds neither lock, so snapshots and further ticks go on
+	snapshot := make(chan domain.Snapshot)
+	go func() { snapshot <- s.GetSnapshot() }()
+	select {
+	case snap := <-snapshot:
+		if !snap.ScheduleState.IsRunning {
+			t.Error("snapshot during the apply is not running")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetSnapshot stalled behind the apply")
+	}
+	fake.Advance(time.Hour)
+	coalesced := make(chan bool)
+	go func() { coalesced <- s.tick(fake.Now()) }()
+	select {
+	case got := <-coalesced:
+		if got {
+			t.Error("tick during the apply applied again")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tick stalled behind the apply")
+	}
+
+	release()
+	if !<-applied {
+		t.Error("slow tick did not apply")
+	}
+	if got := controller.setCount(); got != 1 {
+		t.Errorf("controller called %d times, want 1", got)
+	}
+}
+
+// slowController takes delay on the fake clock for the next set once slow
+// is called.
+type slowController struct {
+	fakeController
+	clock *clock.Fake
+	delay time.Duration
+	armed atomic.Bool
+}
+
+func (c *slowController) slow(delay time.Duration) {
+	c.delay = delay
+	c.armed.Store(true)
+}
+
+func (c *slowController) SetVolume(volume int) error {
+	if c.armed.CompareAndSwap(true, false) {
+		c.clock.Advance(c.delay)
+	}
+	return c.fakeController.SetVolume(volume)
+}
+
+func TestSlowApplySkipsMissedTick(t *testing.T) {
+	config := testConfig()
+	config.Interval = 10 * time.Second
+	controller := &slowController{}
+	history := newMemHistory()
+	s, _, fake := newTestScheduler(t, config, domain.ScheduleState{}, controller, WithHistory(history))
+	controller.clock = fake
+	startLoop(t, s, fake)
+	// After the latency check, so only the first scheduled apply is slow
+	controller.slow(25 * time.Second)
+
+	first := nextApply(t, fake, history, 10*time.Second, 30*time.Second)
+	if got := first.Timestamp.Sub(testStart); got != 10*time.Second {
+		t.Fatalf("first apply at %s, want 10s", got)
+	}
+	// The tick buffered during the apply would apply again at once
+	noApply(t, history)
+
+	second := nextApply(t, fake, history, 10*time.Second, 30*time.Second)
+	if got := second.Timestamp.Sub(testStart); got <= 35*time.Second {
+		t.Errorf("second apply at %s, want after the slow one ended at 35s", got)
+	}
+}
+
+func TestOverdueRunAppliesOnce(t *testing.T) {
+	state := domain.ScheduleState{
+		LastApplied:     testStart.Add(-time.Hour),
+		LastApplyStatus: domain.StatusSuccess,
+		NextRun:         testStart.Add(-time.Hour + 90*time.Second),
+	}
+	history := newMemHistory()
+	s, _, fake := newTestScheduler(t, testConfig(), state, &fakeController{}, WithHistory(history))
+	startLoop(t, s, fake)
+
+	record := nextApply(t, fake, history, 10*time.Second, 90*time.Second)
+	if got := record.Timestamp.Sub(testStart); got != 90*time.Second {
+		t.Errorf("overdue run applied at %s, want on the first tick at 90s", got)
+	}
+	// The missed runs are not caught up one by one
+	noApply(t, history)
+	if got := s.GetSnapshot().ScheduleState.NextRun; !got.Equal(record.Timestamp.Add(90 * time.Second)) {
+		t.Errorf("next run at %s, want one interval after the apply", got.Sub(testStart))
+	}
+	if got := history.count(domain.TriggerScheduled); got != 1 {
+		t.Errorf("%d scheduled applies, want 1", got)
+	}
+}
+
+func TestIntervalChangeResetsTicker(t *testing.T) {
+	history := newMemHistory()
+	s, _, fake := newTestScheduler(t, testConfig(), domain.ScheduleState{}, &fakeController{}, WithHistory(history))
+	startLoop(t, s, fake)
+
+	config := s.GetSnapshot().Config
+	config.Interval = 5 * time.Second
+	if err := s.UpdateConfig(config, false); err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	// Let the loop take the wake before the clock moves
+	for len(s.wake) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The update moves the next run to 5s, and a run applies on the first
+	// tick strictly after it, at 10s, rather than on the old 90s tick
+	record := nextApply(t, fake, history, 5*time.Second, 90*time.Second)
+	if got := record.Timestamp.Sub(testStart); got > 10*time.Second {
+		t.Errorf("first apply after the change at %s, want by 10s", got)
+	}
+}
+
+func TestConcurrentApplyUpdateAndSnapshot(t *testing.T) {
+	controller := &fakeController{}
+	s, repo, fake := newTestScheduler(t, testConfig(), domain.ScheduleState{}, controller)
+
+	const n = 20
+	var (
+		wg      sync.WaitGroup
+		applies atomic.Int32
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			if err := s.ApplyNow(-1); err != nil {
+				t.Errorf("ApplyNow: %v", err)
+				return
+			}
+			applies.Add(1)
+		}()
+		go func(target int) {
+			defer wg.Done()
+			config := s.GetSnapshot().Config
+			config.TargetVolume = target
+			if err := s.UpdateConfig(config, false); err != nil {
+				t.Errorf("UpdateConfig: %v", err)
+			}
+		}(40 + i)
+		go func() {
+			defer wg.Done()
+			if s.tick(fake.Now().Add(time.Hour)) {
+				applies.Add(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			snap := s.GetSnapshot()
+			if v := snap.Config.TargetVolume; v != 50 && (v < 40 || v >= 40+n) {
+				t.Errorf("snapshot target %d was never set", v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := controller.setCount(), int(applies.Load()); got != want {
+		t.Errorf("controller called %d times for %d applies", got, want)
+	}
+	snap := s.GetSnapshot()
+	if snap.ScheduleState.IsRunning {
+		t.Error("still running after every apply returned")
+	}
+	saved, state := repo.saved()
+	if saved.TargetVolume != snap.Config.TargetVolume || !state.LastApplied.Equal(snap.ScheduleState.LastApplied) {
+		t.Errorf("saved target %d at %v, snapshot has %d at %v",
+			saved.TargetVolume, state.LastApplied, snap.Config.TargetVolume, snap.ScheduleState.LastApplied)
+	}
+}
+
+func TestFirstAppliedSurvivesRestart(t *testing.T) {
+	controller := &fakeController{}
+	s, repo, fake := newTestScheduler(t, testConfig(), domain.ScheduleState{}, controller)
+	if !s.tick(fake.Now()) {
+		t.Fatal("first tick did not apply")
+	}
+	fake.Advance(2 * time.Minute)
+	if !s.tick(fake.Now()) {
+		t.Fatal("second tick did not apply")
+	}
+	if got := s.GetSnapshot().ScheduleState.FirstApplied; !got.Equal(testStart) {
+		t.Fatalf("first applied = %v, want the first apply at %v", got, testStart)
+	}
+
+	// A new process over the saved state, as after a restart
+	uc, err := NewSchedulerUseCase(repo, controller, WithClock(fake))
+	if err != nil {
+		t.Fatalf("NewSchedulerUseCase: %v", err)
+	}
+	restarted := uc.(*schedulerInteractor)
+	if got := restarted.GetSnapshot().ScheduleState.FirstApplied; !got.Equal(testStart) {
+		t.Errorf("first applied after restart = %v, want %v", got, testStart)
+	}
+
+	// Its own loop counts from its own first success
+	startLoop(t, restarted, fake)
+	if got := restarted.GetSnapshot().ScheduleState.FirstApplied; !got.IsZero() {
+		t.Errorf("first applied once the loop started = %v, want unset", got)
+	}
+	later := fake.Now().Add(5 * time.Minute)
+	if !restarted.tick(later) {
+		t.Fatal("tick after restart did not apply")
+	}
+	if got := restarted.GetSnapshot().ScheduleState.FirstApplied; !got.Equal(later) {
+		t.Errorf("first applied = %v, want the loop's first apply at %v", got, later)
+	}
+}
+
+func TestScheduleModesWithSlowApply(t *testing.T) {
+	tests := []struct {
+		mode domain.ScheduleMode
+		// wantNext are the next runs after each of two applies that take
+		// two minutes, the first at 09:07
+		wantNext [2]string
+	}{
+		{domain.ScheduleRelative, [2]string{"09:37", "10:07"}},
+		{domain.ScheduleFixed, [2]string{"09:30", "10:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			config := testConfig()
+			config.Interval = 30 * time.Minute
+			config.ScheduleMode = tt.mode
+			controller := &slowController{}
+			s, _, fake := newTestScheduler(t, config, domain.ScheduleState{}, controller)
+			controller.clock = fake
+			fake.Advance(7 * time.Minute)
+
+			for i, want := range tt.wantNext {
+				controller.slow(2 * time.Minute)
+				if !s.tick(fake.Now()) {
+					t.Fatalf("tick %d at %s did not apply", i+1, fake.Now().Format("15:04"))
+				}
+				next := s.GetSnapshot().ScheduleState.NextRun
+				if got := next.Format("15:04"); got != want {
+					t.Errorf("next run after apply %d = %s, want %s", i+1, got, want)
+				}
+				// The loop ticks just after the next run
+				fake.Advance(next.Sub(fake.Now()) + time.Second)
+			}
+		})
+	}
+}
+
+func TestManualApplyFloorStaysAllowed(t *testing.T) {
+	config := testConfig()
+	config.AllowedVolumes = []int{20, 80}
+	config.MinTargetVolume = 50
+	config.TargetVolume = 80
+	controller := &fakeController{}
+	s, _, _ := newTestScheduler(t, config, domain.ScheduleState{}, controller)
+
+	if err := s.ApplyNow(20); err != nil {
+		t.Fatalf("ApplyNow(20): %v", err)
+	}
+	if got, _ := controller.GetVolume(); got != 80 {
+		t.Errorf("applied %d, want the floor raised to the allowed 80", got)
+	}
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"time"
+
+	"micgain-manager/internal/domain"
+	"micgain-manager/internal/logging"
+)
+
+const (
+	// sleepCheckPeriod is how often the loop compares the wall clock with
+	// the time its ticks say has passed.
+	sleepCheckPeriod = 10 * time.Second
+	// sleepGapThreshold is how far the wall clock may run ahead of the
+	// sleep check before the system is taken to have slept.
+	sleepGapThreshold = 30 * time.Second
+)
+
+// wokeFromSleep reports whether the system slept since the sleep check
+// at last, and if so reapplies at once instead of leaving the volume the
+// wake may have reset until the next run. Tickers run on the monotonic
+// clock, which stands still while the system sleeps, so a sleep shows as
+// a wall clock gap beyond the sleep check period. The caller must not
+// hold s.applyMu or s.mu.
+func (s *schedulerInteractor) wokeFromSleep(last, now time.Time) bool {
+	// Round(0) drops the monotonic reading, which would hide the gap
+	gap := now.Round(0).Sub(last.Round(0)) - sleepCheckPeriod
+	if gap <= sleepGapThreshold {
+		return false
+	}
+	logging.With("asleep", gap.Round(time.Second)).Infof("system woke from sleep; reapplying")
+	s.reapply(domain.TriggerWake)
+	return true
+}